@@ -35,22 +35,15 @@ func init() {
 	flag.StringVar(&flagconf, "conf", "../../configs", "config path, eg: -conf config.yaml")
 }
 
-func newApp(logger log.Logger, gs *grpc.Server, hs *http.Server, rs *server.RedisServer, seckillServers []transport.Server) *kratos.App {
+func newApp(logger log.Logger, gs *grpc.Server, hs *http.Server, rs *server.RedisServer, seckill *server.SeckillSupervisor, scheduler *server.SeckillSchedulerServer, outbox *server.OutboxRelayServer, events *server.InstanceEventServer, expiry *server.OrderExpiryServer) *kratos.App {
 	var servers []transport.Server
-	servers = append(servers, gs, hs)
+	servers = append(servers, gs, hs, seckill, scheduler, outbox, events, expiry)
 
 	// 如果 RedisServer 初始化成功，则添加到服务列表
 	if rs != nil {
 		servers = append(servers, rs)
 	}
 
-	// 添加秒杀 Stream 服务器
-	for _, ss := range seckillServers {
-		if ss != nil {
-			servers = append(servers, ss)
-		}
-	}
-
 	return kratos.New(
 		kratos.ID(id),
 		kratos.Name(Name),
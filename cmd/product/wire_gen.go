@@ -32,25 +32,54 @@ func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*
 	productUsecase := biz.NewProductUsecase(productRepo, logger)
 	orderRepo := data.NewOrderRepoImpl(dataData, logger)
 	instanceRepo := data.NewInstanceRepo(dataData, logger)
-	mqPublisher, cleanup2, err := data.NewMQPublisher(confData, logger)
+	outboxRepo := data.NewOutboxRepo(dataData, logger)
+	transaction := data.NewTransaction(dataData)
+	orderIDGenerator, cleanup2, err := data.NewOrderIDGenerator(confData, dataData, logger)
 	if err != nil {
 		cleanup()
 		return nil, nil, err
 	}
-	orderIDGenerator := data.NewOrderIDGenerator(logger)
-	instanceIDGenerator := data.NewInstanceIDGenerator(logger)
-	orderUsecase := biz.NewOrderUsecase(orderRepo, productRepo, instanceRepo, mqPublisher, orderIDGenerator, instanceIDGenerator, logger)
-	productService := service.NewProductService(productUsecase, orderUsecase, logger)
+	instanceIDGenerator := data.NewInstanceIDGenerator(dataData, logger)
+	inventoryRepo := data.NewInventoryRepo(dataData, logger)
+	paymentGateway, cleanup3, err := data.NewPaymentGateway(confData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	orderUsecase := biz.NewOrderUsecase(orderRepo, productRepo, instanceRepo, outboxRepo, inventoryRepo, paymentGateway, transaction, orderIDGenerator, instanceIDGenerator, logger)
+	inventoryUsecase := biz.NewInventoryUsecase(inventoryRepo, productRepo, logger)
+	productService := service.NewProductService(productUsecase, orderUsecase, inventoryUsecase, logger)
 	seckillProductRepo := data.NewSeckillProductRepo(dataData, logger)
-	seckillUsecase := biz.NewSeckillUsecase(seckillProductRepo, logger)
-	seckillService := service.NewSeckillService(seckillUsecase, logger)
+	seckillCampaignRepo := data.NewSeckillCampaignRepo(dataData, logger)
+	seckillCompensationRepo := data.NewSeckillCompensationRepo(dataData, logger)
+	seckillUsecase := biz.NewSeckillUsecase(seckillProductRepo, seckillCampaignRepo, seckillCompensationRepo, orderRepo, transaction, logger)
+	seckillService := service.NewSeckillService(seckillUsecase, orderUsecase, logger)
 	orderService := service.NewOrderService(orderUsecase, logger)
-	grpcServer := server.NewGRPCServer(confServer, logger, productService, seckillService, orderService)
-	httpServer := server.NewHTTPServer(confServer, logger, productService, orderService)
+	instanceUsecase := biz.NewInstanceUsecase(instanceRepo, productRepo, outboxRepo, transaction, logger)
+	instanceService := service.NewInstanceService(instanceUsecase, logger)
+	grpcServer := server.NewGRPCServer(confServer, logger, productService, seckillService, orderService, instanceService)
+	httpServer := server.NewHTTPServer(confServer, logger, productService, orderService, instanceService)
 	redisServer := server.NewRedisServer(confData, logger)
-	v := server.NewSeckillStreamServers(confServer, redisServer, seckillUsecase, orderUsecase, logger)
-	app := newApp(logger, grpcServer, httpServer, redisServer, v)
+	seckillSupervisor := server.NewSeckillSupervisor(confServer, redisServer, seckillUsecase, orderUsecase, logger)
+	seckillSchedulerServer := server.NewSeckillSchedulerServer(confServer, seckillUsecase, logger)
+	mqPublisher, cleanup4, err := data.NewMQPublisher(confData, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outboxUsecase := biz.NewOutboxUsecase(outboxRepo, mqPublisher, logger)
+	outboxRelayServer := server.NewOutboxRelayServer(confServer, outboxUsecase, logger)
+	instanceEventRepo := data.NewInstanceEventRepo(dataData, logger)
+	instanceEventUsecase := biz.NewInstanceEventUsecase(instanceEventRepo, instanceRepo, orderRepo, orderUsecase, transaction, logger)
+	instanceEventServer := server.NewInstanceEventServer(confData, instanceEventUsecase, logger)
+	orderExpiryServer := server.NewOrderExpiryServer(confServer, orderUsecase, logger)
+	app := newApp(logger, grpcServer, httpServer, redisServer, seckillSupervisor, seckillSchedulerServer, outboxRelayServer, instanceEventServer, orderExpiryServer)
 	return app, func() {
+		cleanup4()
+		cleanup3()
 		cleanup2()
 		cleanup()
 	}, nil
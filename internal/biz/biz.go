@@ -1,6 +1,34 @@
 package biz
 
-import "github.com/google/wire"
+import (
+	"context"
+	"errors"
+
+	"github.com/google/wire"
+)
 
 // ProviderSet is biz providers.
-var ProviderSet = wire.NewSet(NewSeckillUsecase, NewProductUsecase, NewOrderUsecase)
+var ProviderSet = wire.NewSet(NewSeckillUsecase, NewProductUsecase, NewOrderUsecase, NewOutboxUsecase, NewInstanceEventUsecase, NewInstanceUsecase, NewInventoryUsecase)
+
+// Transaction 事务管理接口，由 data 层实现
+type Transaction interface {
+	// InTx 在同一个事务中执行 fn，fn 内使用传入的 ctx 调用仓储
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// BizError 业务错误
+type BizError struct {
+	Code      int
+	Message   string
+	Permanent bool // 重试也不会成功（如参数非法、数据不存在），消息消费者据此丢弃而不是重新投递
+}
+
+func (e *BizError) Error() string {
+	return e.Message
+}
+
+// IsPermanentError 是否为标记为 Permanent 的业务错误；其余错误（并发冲突、数据库、网络、网关）视为可重试
+func IsPermanentError(err error) bool {
+	var bizErr *BizError
+	return errors.As(err, &bizErr) && bizErr.Permanent
+}
@@ -0,0 +1,62 @@
+package biz
+
+import "time"
+
+// IDEpochMs 订单 ID 与实例 ID 的时间起点：UTC 2026-01-18 00:00:00
+const IDEpochMs = 1768665600000
+
+// ErrInvalidID ID 必须为正数
+var ErrInvalidID = &BizError{Code: 400, Message: "invalid id: must be greater than 0", Permanent: true}
+
+// 订单 ID 结构（Snowflake）：[0(1位)][timestamp(41位)][worker(10位)][sequence(12位)]
+const (
+	OrderIDWorkerBits   = 10
+	OrderIDSequenceBits = 12
+	OrderIDMaxWorker    = 1<<OrderIDWorkerBits - 1
+	OrderIDMaxSequence  = 1<<OrderIDSequenceBits - 1
+	OrderIDMaxTimestamp = 1<<41 - 1
+)
+
+// OrderID 订单 ID
+type OrderID int64
+
+// NewOrderID 由相对 IDEpochMs 的毫秒数、worker ID 和毫秒内序号组成订单 ID
+func NewOrderID(ms, workerID, sequence int64) OrderID {
+	return OrderID(ms<<(OrderIDWorkerBits+OrderIDSequenceBits) | workerID<<OrderIDSequenceBits | sequence)
+}
+
+// Decode 解析生成时间、worker ID 和毫秒内序号
+func (id OrderID) Decode() (time.Time, int64, int64) {
+	v := int64(id)
+	return time.UnixMilli(v>>(OrderIDWorkerBits+OrderIDSequenceBits) + IDEpochMs),
+		v >> OrderIDSequenceBits & OrderIDMaxWorker,
+		v & OrderIDMaxSequence
+}
+
+// 实例 ID 结构：[0(1位)][user_hash(15位)][timestamp(45位)][sequence(3位)]
+// 用户哈希放在高位，同一用户的实例 ID 按时间聚集
+const (
+	InstanceIDSequenceBits = 3
+	InstanceIDTimeBits     = 45
+	InstanceIDMaxSequence  = 1<<InstanceIDSequenceBits - 1
+	InstanceIDMaxTimestamp = 1<<InstanceIDTimeBits - 1
+	InstanceIDUserHashMask = 1<<15 - 1
+)
+
+// InstanceID 实例 ID
+type InstanceID int64
+
+// NewInstanceID 由用户哈希（低 15 位）、相对 IDEpochMs 的毫秒数和毫秒内序号组成实例 ID
+func NewInstanceID(userHash uint64, ms, sequence int64) InstanceID {
+	return InstanceID(int64(userHash&InstanceIDUserHashMask)<<(InstanceIDTimeBits+InstanceIDSequenceBits) |
+		(ms&InstanceIDMaxTimestamp)<<InstanceIDSequenceBits |
+		sequence&InstanceIDMaxSequence)
+}
+
+// Decode 解析生成时间、用户哈希和毫秒内序号
+func (id InstanceID) Decode() (time.Time, uint16, int64) {
+	v := int64(id)
+	return time.UnixMilli(v>>InstanceIDSequenceBits&InstanceIDMaxTimestamp + IDEpochMs),
+		uint16(v >> (InstanceIDTimeBits + InstanceIDSequenceBits) & InstanceIDUserHashMask),
+		v & InstanceIDMaxSequence
+}
@@ -0,0 +1,36 @@
+package biz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderID_Decode(t *testing.T) {
+	at := time.UnixMilli(IDEpochMs + 123456789)
+	id := NewOrderID(at.UnixMilli()-IDEpochMs, 1023, 4095)
+	if id <= 0 {
+		t.Fatalf("order id = %d, want positive", id)
+	}
+	ts, worker, seq := id.Decode()
+	if !ts.Equal(at) || worker != 1023 || seq != 4095 {
+		t.Errorf("decode = %v %d %d, want %v 1023 4095", ts, worker, seq, at)
+	}
+	if NewOrderID(OrderIDMaxTimestamp, OrderIDMaxWorker, OrderIDMaxSequence) <= 0 {
+		t.Error("max order id overflows the sign bit")
+	}
+}
+
+func TestInstanceID_Decode(t *testing.T) {
+	at := time.UnixMilli(IDEpochMs + 987654321)
+	id := NewInstanceID(0xFFFFABCD, at.UnixMilli()-IDEpochMs, 5)
+	if id <= 0 {
+		t.Fatalf("instance id = %d, want positive", id)
+	}
+	ts, userHash, seq := id.Decode()
+	if !ts.Equal(at) || userHash != 0x2BCD || seq != 5 {
+		t.Errorf("decode = %v %#x %d, want %v 0x2bcd 5", ts, userHash, seq, at)
+	}
+	if NewInstanceID(^uint64(0), InstanceIDMaxTimestamp, InstanceIDMaxSequence) <= 0 {
+		t.Error("max instance id overflows the sign bit")
+	}
+}
@@ -0,0 +1,31 @@
+package biz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// maxIdempotencyKeyLen 幂等键最大长度
+const maxIdempotencyKeyLen = 128
+
+// 幂等键错误
+var (
+	ErrInvalidIdempotencyKey  = &BizError{Code: 400, Message: "invalid idempotency key: must be at most 128 characters"}
+	ErrIdempotencyKeyConflict = &BizError{Code: 409, Message: "idempotency key conflicts with another user's order"}
+)
+
+// IdempotencyReqID 把用户的幂等键确定性地映射为正的 req_id
+// 幂等键按用户隔离（不同用户使用相同的键互不影响），取 SHA-256 的前 63 位，碰撞概率可以忽略；
+// 结果为 0 时取 1，0 保留给"由订单 ID 生成 req_id"
+func IdempotencyReqID(userID, key string) (int64, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return 0, ErrInvalidIdempotencyKey
+	}
+	sum := sha256.Sum256([]byte(userID + "\x00" + key))
+	reqID := int64(binary.BigEndian.Uint64(sum[:8]) & math.MaxInt64)
+	if reqID == 0 {
+		reqID = 1
+	}
+	return reqID, nil
+}
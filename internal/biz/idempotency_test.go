@@ -0,0 +1,71 @@
+package biz
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestIdempotencyReqID(t *testing.T) {
+	a, err := IdempotencyReqID("u1", "key-1")
+	if err != nil || a <= 0 {
+		t.Fatalf("req_id = %d err = %v, want positive", a, err)
+	}
+	if b, _ := IdempotencyReqID("u1", "key-1"); b != a {
+		t.Errorf("req_id is not deterministic: %d != %d", b, a)
+	}
+	if b, _ := IdempotencyReqID("u2", "key-1"); b == a {
+		t.Error("same key of different users maps to the same req_id")
+	}
+	for _, key := range []string{"", strings.Repeat("k", 129)} {
+		if _, err := IdempotencyReqID("u1", key); err != ErrInvalidIdempotencyKey {
+			t.Errorf("key of length %d: err = %v, want %v", len(key), err, ErrInvalidIdempotencyKey)
+		}
+	}
+}
+
+func TestOrderUsecase_PurchaseProduct_Idempotent(t *testing.T) {
+	ctx := context.Background()
+	products := &fakeProductRepo{product: &Product{
+		ID: 1001, Name: "GPU", Status: ProductStatusEnabled, Price: 1000,
+		Spec: &ProductSpec{ID: 1, CPU: 4, Memory: 8192},
+	}}
+	orders := &fakeOrderRepo{}
+	instances := &fakeInstanceRepo{}
+	ids := &fakeIDGenerator{next: 100}
+	uc := NewOrderUsecase(orders, products, instances, &fakeOutboxRepo{}, &fakeInventoryRepo{}, newFakePaymentGateway(), fakeTx{}, ids, ids, log.DefaultLogger)
+
+	first, firstInstance, replayed, err := uc.PurchaseProduct(ctx, "u1", 1001, "retry-1")
+	if err != nil || replayed {
+		t.Fatalf("first purchase: replayed = %t err = %v", replayed, err)
+	}
+	again, againInstance, replayed, err := uc.PurchaseProduct(ctx, "u1", 1001, "retry-1")
+	if err != nil || !replayed {
+		t.Fatalf("retry: replayed = %t err = %v, want replayed", replayed, err)
+	}
+	if again.ID != first.ID || againInstance != firstInstance {
+		t.Errorf("retry returned order %d instance %d, want %d %d", again.ID, againInstance, first.ID, firstInstance)
+	}
+	if len(orders.orders) != 1 || first.PaymentID == "" {
+		t.Errorf("orders = %d paymentID = %q, want one order with a payment intent", len(orders.orders), first.PaymentID)
+	}
+
+	// 其他用户使用相同的幂等键、或不带幂等键时都创建新订单
+	other, _, replayed, err := uc.PurchaseProduct(ctx, "u2", 1001, "retry-1")
+	if err != nil || replayed || other.ID == first.ID {
+		t.Errorf("other user: order %d replayed = %t err = %v, want a new order", other.ID, replayed, err)
+	}
+	plain, _, _, err := uc.PurchaseProduct(ctx, "u1", 1001, "")
+	if err != nil || plain.ReqID != plain.ID {
+		t.Errorf("purchase without key: req_id = %d order = %d err = %v, want req_id = order id", plain.ReqID, plain.ID, err)
+	}
+
+	// 幂等键映射的 req_id 被其他用户的订单占用
+	reqID, _ := IdempotencyReqID("u3", "taken")
+	orders.orders = append(orders.orders, &Order{ID: 999, ProductID: 1001, UserID: "u4", ReqID: reqID})
+	if _, _, _, err := uc.PurchaseProduct(ctx, "u3", 1001, "taken"); err != ErrIdempotencyKeyConflict {
+		t.Errorf("conflicting req_id: err = %v, want %v", err, ErrIdempotencyKeyConflict)
+	}
+}
@@ -0,0 +1,225 @@
+package biz
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 实例待处理操作（已下发给资源域，等待资源域回传状态）
+const (
+	InstanceActionStart  = "START"
+	InstanceActionStop   = "STOP"
+	InstanceActionDelete = "DELETE"
+	InstanceActionResize = "RESIZE"
+)
+
+// InstancePendingTimeout 待处理操作的确认超时，超时未收到资源域回传的操作不再阻塞实例的后续操作
+const InstancePendingTimeout = 15 * time.Minute
+
+// 实例操作错误
+var (
+	ErrInstanceForbidden   = &BizError{Code: 403, Message: "instance does not belong to user", Permanent: true}
+	ErrInstanceBusy        = &BizError{Code: 409, Message: "instance has a pending operation"}
+	ErrInstanceStateDenied = &BizError{Code: 409, Message: "operation not allowed in current instance status"}
+)
+
+// instanceActionAllowed 各操作允许的实例状态
+var instanceActionAllowed = map[string][]string{
+	InstanceActionStart:  {InstanceStatusStopped, InstanceStatusFailed},
+	InstanceActionStop:   {InstanceStatusRunning},
+	InstanceActionDelete: {InstanceStatusCreating, InstanceStatusRunning, InstanceStatusStopped, InstanceStatusFailed},
+	InstanceActionResize: {InstanceStatusRunning, InstanceStatusStopped},
+}
+
+// instanceActionEvents 各操作下发的 MQ 事件
+var instanceActionEvents = map[string]string{
+	InstanceActionStart:  EventInstanceStarted,
+	InstanceActionStop:   EventInstanceStopped,
+	InstanceActionDelete: EventInstanceDeleted,
+	InstanceActionResize: EventInstanceSpecChanged,
+}
+
+// CanApplyInstanceAction 判断实例在 status 状态下能否执行 action
+func CanApplyInstanceAction(action, status string) bool {
+	for _, s := range instanceActionAllowed[action] {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceActionSettled 判断资源域回传的状态是否结束了待处理操作
+// 失败状态结束任何操作；其余按操作的目标状态判断
+func InstanceActionSettled(action, status string) bool {
+	if status == InstanceStatusFailed {
+		return true
+	}
+	switch action {
+	case InstanceActionStart, InstanceActionResize:
+		return status == InstanceStatusRunning
+	case InstanceActionStop:
+		return status == InstanceStatusStopped
+	case InstanceActionDelete:
+		return status == InstanceStatusDeleted
+	}
+	return false
+}
+
+// PendingExpired 待处理操作是否已超时未确认
+func (i *InstanceInfo) PendingExpired(now time.Time) bool {
+	return i.PendingAction != "" && i.PendingAt != nil && now.Sub(*i.PendingAt) > InstancePendingTimeout
+}
+
+// clearPending 清空待处理操作及变更规格的目标规格
+func (i *InstanceInfo) clearPending() {
+	i.PendingAction = ""
+	i.PendingAt = nil
+	i.PendingProductID = 0
+	i.PendingProductName = ""
+	i.PendingSpec = nil
+}
+
+// InstanceUsecase 实例生命周期业务逻辑
+// 操作只负责校验并下发命令，实例状态以资源域回传为准
+type InstanceUsecase struct {
+	instanceRepo InstanceRepo
+	productRepo  ProductRepo
+	outboxRepo   OutboxRepo
+	tx           Transaction
+	log          *log.Helper
+}
+
+// NewInstanceUsecase 创建实例用例
+func NewInstanceUsecase(instanceRepo InstanceRepo, productRepo ProductRepo, outboxRepo OutboxRepo, tx Transaction, logger log.Logger) *InstanceUsecase {
+	return &InstanceUsecase{
+		instanceRepo: instanceRepo,
+		productRepo:  productRepo,
+		outboxRepo:   outboxRepo,
+		tx:           tx,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// GetInstance 获取实例（校验归属），客户端轮询待处理操作的进度
+func (uc *InstanceUsecase) GetInstance(ctx context.Context, userID string, instanceID int64) (*InstanceInfo, error) {
+	return uc.getOwned(ctx, userID, instanceID)
+}
+
+// StartInstance 启动实例
+func (uc *InstanceUsecase) StartInstance(ctx context.Context, userID string, instanceID int64) (*InstanceInfo, error) {
+	return uc.apply(ctx, userID, instanceID, InstanceActionStart, nil)
+}
+
+// StopInstance 停止实例
+func (uc *InstanceUsecase) StopInstance(ctx context.Context, userID string, instanceID int64) (*InstanceInfo, error) {
+	return uc.apply(ctx, userID, instanceID, InstanceActionStop, nil)
+}
+
+// DeleteInstance 删除实例
+func (uc *InstanceUsecase) DeleteInstance(ctx context.Context, userID string, instanceID int64) (*InstanceInfo, error) {
+	return uc.apply(ctx, userID, instanceID, InstanceActionDelete, nil)
+}
+
+// ResizeInstance 把实例变更为另一个商品的规格
+func (uc *InstanceUsecase) ResizeInstance(ctx context.Context, userID string, instanceID, productID int64) (*InstanceInfo, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+
+	product, err := uc.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		uc.log.Errorf("get product failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+	if product.Status != ProductStatusEnabled {
+		return nil, ErrProductDisabled
+	}
+	if product.Spec == nil {
+		return nil, ErrInvalidProductSpec
+	}
+
+	return uc.apply(ctx, userID, instanceID, InstanceActionResize, product)
+}
+
+// apply 校验归属与状态，记录待处理操作并通过发件箱下发事件
+// 变更规格时目标规格记录为待确认，资源域回传 RUNNING 后才写入实例
+func (uc *InstanceUsecase) apply(ctx context.Context, userID string, instanceID int64, action string, target *Product) (*InstanceInfo, error) {
+	instance, err := uc.getOwned(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.PendingAction != "" {
+		return nil, ErrInstanceBusy
+	}
+	if !CanApplyInstanceAction(action, instance.Status) {
+		uc.log.Warnf("instance action denied: instanceID=%d action=%s status=%s", instanceID, action, instance.Status)
+		return nil, ErrInstanceStateDenied
+	}
+
+	now := time.Now()
+	instance.clearPending()
+	instance.PendingAction = action
+	instance.PendingAt = &now
+	instance.UpdatedAt = now
+
+	name, targetSpec := instance.ProductName, instance.Spec
+	if target != nil {
+		instance.PendingProductID = target.ID
+		instance.PendingProductName = target.Name
+		instance.PendingSpec = target.Spec
+		name, targetSpec = target.Name, target.Spec
+	}
+
+	spec := InstanceSpec{
+		InstanceID: instance.InstanceID,
+		UserID:     instance.UserID,
+		Name:       name,
+	}
+	if targetSpec != nil {
+		spec.CPU = targetSpec.CPU
+		spec.Memory = targetSpec.Memory
+		spec.GPU = targetSpec.GPU
+		spec.Image = targetSpec.Image
+		spec.ConfigJSON = targetSpec.ConfigJSON
+	}
+
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := uc.instanceRepo.MarkPending(ctx, instance); err != nil {
+			return err
+		}
+		return uc.outboxRepo.EnqueueInstanceEvent(ctx, instanceActionEvents[action], spec)
+	})
+	if err != nil {
+		uc.log.Errorf("apply instance action failed: instanceID=%d action=%s err=%v", instanceID, action, err)
+		return nil, err
+	}
+	uc.log.Infof("instance action requested: instanceID=%d action=%s", instanceID, action)
+
+	return instance, nil
+}
+
+// getOwned 查询实例并校验归属，超时未确认的待处理操作视为已失效
+func (uc *InstanceUsecase) getOwned(ctx context.Context, userID string, instanceID int64) (*InstanceInfo, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	instance, err := uc.instanceRepo.GetInstanceByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.UserID != userID {
+		uc.log.Warnf("instance ownership mismatch: instanceID=%d userID=%s", instanceID, userID)
+		return nil, ErrInstanceForbidden
+	}
+	if instance.PendingExpired(time.Now()) {
+		uc.log.Warnf("instance pending action expired: instanceID=%d action=%s pendingAt=%s",
+			instanceID, instance.PendingAction, instance.PendingAt.Format(time.RFC3339))
+		instance.clearPending()
+	}
+	return instance, nil
+}
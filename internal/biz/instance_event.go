@@ -0,0 +1,213 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 实例状态（由资源域事件驱动）
+const (
+	InstanceStatusCreating = "CREATING" // 已下单，等待资源域创建
+	InstanceStatusRunning  = "RUNNING"  // 运行中
+	InstanceStatusStopped  = "STOPPED"  // 已停止
+	InstanceStatusFailed   = "FAILED"   // 创建或运行失败
+	InstanceStatusDeleted  = "DELETED"  // 已删除
+)
+
+// InstanceEvent 资源域回传的实例事件
+type InstanceEvent struct {
+	EventID    string    // 事件 ID（幂等键）
+	EventType  string    // 事件类型（如 INSTANCE_STATUS_CHANGED）
+	InstanceID int64     // 实例 ID
+	UserID     string    // 用户 ID
+	Status     string    // 资源域上报的状态（仅 STATUS_CHANGED / K8S_SYNC 携带）
+	Reason     string    // 状态原因（如失败原因）
+	OccurredAt time.Time // 事件发生时间
+}
+
+// 实例事件错误
+var (
+	ErrInstanceNotFound     = &BizError{Code: 404, Message: "instance not found"}
+	ErrInvalidInstanceEvent = &BizError{Code: 400, Message: "invalid instance event: missing event id or instance id", Permanent: true}
+)
+
+// InstanceStatusRecord 资源域回传的实例状态
+type InstanceStatusRecord struct {
+	InstanceID    int64
+	Status        string    // 生命周期状态
+	ResourceState string    // 资源域上报的原始状态
+	Reason        string    // 状态原因
+	ClearPending  bool      // 该状态结束了实例的待处理操作
+	ApplyPending  bool      // 变更规格已确认，目标商品与规格写入实例
+	EventAt       time.Time // 事件发生时间，用于丢弃乱序的旧事件
+}
+
+// InstanceEventRepo 实例事件仓储接口
+type InstanceEventRepo interface {
+	// MarkProcessed 记录已处理的事件，事件已存在时返回 false（需在事务内调用，处理失败时随事务回滚）
+	MarkProcessed(ctx context.Context, eventID, eventType string) (bool, error)
+}
+
+// InstanceEventUsecase 资源域事件处理业务逻辑
+type InstanceEventUsecase struct {
+	repo         InstanceEventRepo
+	instanceRepo InstanceRepo
+	orderRepo    OrderRepo
+	orderUc      *OrderUsecase
+	tx           Transaction
+	log          *log.Helper
+}
+
+// NewInstanceEventUsecase 创建资源域事件处理用例
+func NewInstanceEventUsecase(repo InstanceEventRepo, instanceRepo InstanceRepo, orderRepo OrderRepo, orderUc *OrderUsecase, tx Transaction, logger log.Logger) *InstanceEventUsecase {
+	return &InstanceEventUsecase{
+		repo:         repo,
+		instanceRepo: instanceRepo,
+		orderRepo:    orderRepo,
+		orderUc:      orderUc,
+		tx:           tx,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// HandleEvent 处理资源域事件
+// 1. 按事件 ID 去重，重复投递直接忽略
+// 2. 更新实例状态记录
+// 3. 实例 RUNNING 时完成订单
+// 以上步骤在同一事务内完成，失败时整体回滚，由 MQ 重新投递。
+// 4. 实例在交付前 FAILED/DELETED 时取消订单并退款：退款调用支付网关，在事务提交后进行，
+// 失败时返回错误由 MQ 重新投递，重复投递的事件按已记录的状态补做退款
+func (uc *InstanceEventUsecase) HandleEvent(ctx context.Context, ev *InstanceEvent) error {
+	if ev.EventID == "" || ev.InstanceID <= 0 {
+		return ErrInvalidInstanceEvent
+	}
+
+	status, ok := InstanceStatusOf(ev.EventType, ev.Status)
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		fresh, err := uc.repo.MarkProcessed(ctx, ev.EventID, ev.EventType)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			uc.log.Infof("duplicate event ignored: eventID=%s type=%s", ev.EventID, ev.EventType)
+			return nil
+		}
+
+		if !ok {
+			uc.log.Debugf("event ignored: eventID=%s type=%s status=%s", ev.EventID, ev.EventType, ev.Status)
+			return nil
+		}
+
+		instance, err := uc.instanceRepo.GetInstanceByID(ctx, ev.InstanceID)
+		if errors.Is(err, ErrInstanceNotFound) {
+			uc.log.Warnf("unknown instance, event ignored: eventID=%s instanceID=%d", ev.EventID, ev.InstanceID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 生命周期事件不携带原始状态，以事件类型记录资源域最近一次上报
+		resourceState := ev.Status
+		if resourceState == "" {
+			resourceState = ev.EventType
+		}
+
+		// 待处理操作结束时清空；变更规格只有资源域回传 RUNNING 才生效，失败时丢弃目标规格
+		settled := instance.PendingAction != "" && InstanceActionSettled(instance.PendingAction, status)
+		applied, err := uc.instanceRepo.UpdateStatus(ctx, &InstanceStatusRecord{
+			InstanceID:    ev.InstanceID,
+			Status:        status,
+			ResourceState: resourceState,
+			Reason:        ev.Reason,
+			ClearPending:  settled,
+			ApplyPending:  settled && instance.PendingAction == InstanceActionResize && status == InstanceStatusRunning,
+			EventAt:       ev.OccurredAt,
+		})
+		if err != nil {
+			return err
+		}
+		if !applied {
+			uc.log.Infof("stale event ignored: eventID=%s instanceID=%d at=%s", ev.EventID, ev.InstanceID, ev.OccurredAt)
+			return nil
+		}
+		uc.log.Infof("instance status changed: instanceID=%d orderID=%d status=%s", ev.InstanceID, instance.OrderID, status)
+
+		order, err := uc.orderRepo.GetByID(ctx, instance.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if status != InstanceStatusRunning || order.Status != OrderStatusPaid {
+			return nil
+		}
+		_, err = uc.orderUc.CompleteOrder(ctx, order.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if ok && (status == InstanceStatusFailed || status == InstanceStatusDeleted) {
+		return uc.refundUndelivered(ctx, ev.InstanceID)
+	}
+	return nil
+}
+
+// refundUndelivered 实例未交付即失败或被删除时取消订单并全额退款
+// 按已记录的实例状态和订单状态判断，已退款（或已完成交付）的订单不会重复退款
+func (uc *InstanceEventUsecase) refundUndelivered(ctx context.Context, instanceID int64) error {
+	instance, err := uc.instanceRepo.GetInstanceByID(ctx, instanceID)
+	if errors.Is(err, ErrInstanceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if instance.Status != InstanceStatusFailed && instance.Status != InstanceStatusDeleted {
+		return nil
+	}
+
+	order, err := uc.orderRepo.GetByID(ctx, instance.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != OrderStatusPaid {
+		return nil
+	}
+
+	uc.log.Warnf("instance not delivered, refund order: orderID=%d amount=%d status=%s", order.ID, order.Amount, instance.Status)
+	_, err = uc.orderUc.refundOrder(ctx, order.ID, order.Amount, "instance not delivered: "+instance.Status)
+	return err
+}
+
+// InstanceStatusOf 把资源域事件映射为实例状态，无法映射的事件返回 false
+func InstanceStatusOf(eventType, reported string) (string, bool) {
+	switch eventType {
+	case EventInstanceStarted:
+		return InstanceStatusRunning, true
+	case EventInstanceStopped:
+		return InstanceStatusStopped, true
+	case EventInstanceDeleted:
+		return InstanceStatusDeleted, true
+	case EventInstanceStatusChanged, EventInstanceK8sSync:
+		// 资源域上报 K8s 风格的状态（Running/Failed/Pending...），统一转为大写
+		switch strings.ToUpper(reported) {
+		case "RUNNING":
+			return InstanceStatusRunning, true
+		case "FAILED", "ERROR":
+			return InstanceStatusFailed, true
+		case "PENDING", "CREATING":
+			return InstanceStatusCreating, true
+		case "STOPPED":
+			return InstanceStatusStopped, true
+		case "DELETED", "TERMINATED":
+			return InstanceStatusDeleted, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeInstanceEventRepo struct {
+	processed map[string]bool
+}
+
+func (r *fakeInstanceEventRepo) MarkProcessed(ctx context.Context, eventID, eventType string) (bool, error) {
+	if r.processed[eventID] {
+		return false, nil
+	}
+	r.processed[eventID] = true
+	return true, nil
+}
+
+func TestInstanceStatusOf(t *testing.T) {
+	tests := []struct {
+		eventType, reported string
+		want                string
+		ok                  bool
+	}{
+		{EventInstanceStarted, "", InstanceStatusRunning, true},
+		{EventInstanceStopped, "", InstanceStatusStopped, true},
+		{EventInstanceDeleted, "", InstanceStatusDeleted, true},
+		{EventInstanceStatusChanged, "Running", InstanceStatusRunning, true},
+		{EventInstanceStatusChanged, "Failed", InstanceStatusFailed, true},
+		{EventInstanceK8sSync, "Pending", InstanceStatusCreating, true},
+		{EventInstanceK8sSync, "Unknown", "", false},
+		{EventInstanceCreated, "", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := InstanceStatusOf(tt.eventType, tt.reported)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("InstanceStatusOf(%s, %s) = (%s, %v), want (%s, %v)", tt.eventType, tt.reported, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsPermanentError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{ErrInvalidInstanceEvent, true},
+		{fmt.Errorf("wrapped: %w", ErrOrderNotFound), true},
+		{ErrOrderStatusConflict, false}, // 并发冲突重试可能成功
+		{ErrSeckillCampaignConflict, false},
+		{ErrInstanceBusy, false},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsPermanentError(tt.err); got != tt.want {
+			t.Errorf("IsPermanentError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInstanceEventUsecase_HandleEvent_RefundUndelivered(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+	uc := NewInstanceEventUsecase(&fakeInstanceEventRepo{processed: map[string]bool{}}, f.instances, f.orders, f.uc, fakeTx{}, log.DefaultLogger)
+
+	if err := uc.HandleEvent(ctx, &InstanceEvent{EventType: EventInstanceStarted}); !IsPermanentError(err) {
+		t.Errorf("invalid event: err = %v, want a permanent error", err)
+	}
+
+	order := f.paidOrder(t, "u1")
+	ev := &InstanceEvent{EventID: "ev-1", EventType: EventInstanceStatusChanged, InstanceID: order.InstanceID,
+		Status: "Failed", OccurredAt: time.Now()}
+
+	// 网关退款失败：实例状态已记录，订单保持已支付，返回可重试的错误
+	f.gateway.refundErr = errors.New("gateway timeout")
+	if err := uc.HandleEvent(ctx, ev); err == nil || IsPermanentError(err) {
+		t.Fatalf("refund failure: err = %v, want a retryable error", err)
+	}
+	instance, _ := f.instances.GetInstanceByID(ctx, order.InstanceID)
+	if got, _ := f.orders.GetByID(ctx, order.ID); instance.Status != InstanceStatusFailed || got.Status != OrderStatusPaid {
+		t.Errorf("after refund failure: instance = %s order = %s, want FAILED PAID", instance.Status, got.Status)
+	}
+
+	// 重复投递的事件补做退款
+	f.gateway.refundErr = nil
+	if err := uc.HandleEvent(ctx, ev); err != nil {
+		t.Fatalf("redelivery: err = %v", err)
+	}
+	got, _ := f.orders.GetByID(ctx, order.ID)
+	if got.Status != OrderStatusCancelled || got.Refund == nil || got.Refund.Amount != order.Amount {
+		t.Errorf("redelivery: order = %+v, want cancelled with a full refund", got)
+	}
+	if err := uc.HandleEvent(ctx, ev); err != nil || len(f.gateway.refunds) != 1 {
+		t.Errorf("second redelivery: err = %v refunds = %d, want 1", err, len(f.gateway.refunds))
+	}
+}
@@ -0,0 +1,104 @@
+package biz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestCanApplyInstanceAction(t *testing.T) {
+	tests := []struct {
+		action, status string
+		want           bool
+	}{
+		{InstanceActionStart, InstanceStatusStopped, true},
+		{InstanceActionStart, InstanceStatusRunning, false},
+		{InstanceActionStop, InstanceStatusRunning, true},
+		{InstanceActionStop, InstanceStatusCreating, false},
+		{InstanceActionDelete, InstanceStatusFailed, true},
+		{InstanceActionDelete, InstanceStatusDeleted, false},
+		{InstanceActionResize, InstanceStatusStopped, true},
+		{InstanceActionResize, InstanceStatusCreating, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanApplyInstanceAction(tt.action, tt.status); got != tt.want {
+			t.Errorf("CanApplyInstanceAction(%s, %s) = %v, want %v", tt.action, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestInstanceActionSettled(t *testing.T) {
+	tests := []struct {
+		action, status string
+		want           bool
+	}{
+		{InstanceActionStart, InstanceStatusRunning, true},
+		{InstanceActionStart, InstanceStatusCreating, false},
+		{InstanceActionStop, InstanceStatusStopped, true},
+		{InstanceActionStop, InstanceStatusRunning, false},
+		{InstanceActionDelete, InstanceStatusDeleted, true},
+		{InstanceActionResize, InstanceStatusRunning, true},
+		{InstanceActionResize, InstanceStatusFailed, true},
+	}
+
+	for _, tt := range tests {
+		if got := InstanceActionSettled(tt.action, tt.status); got != tt.want {
+			t.Errorf("InstanceActionSettled(%s, %s) = %v, want %v", tt.action, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestInstanceUsecase_ResizeInstance(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+	order := f.paidOrder(t, "u1")
+	instance, _ := f.instances.GetInstanceByID(ctx, order.InstanceID)
+	instance.Status = InstanceStatusRunning
+
+	products := &fakeProductRepo{product: &Product{
+		ID: 1002, Name: "GPU x2", Status: ProductStatusEnabled, Price: 2000,
+		Spec: &ProductSpec{ID: 2, CPU: 8, Memory: 16384, GPU: 2, Image: "ubuntu:22.04"},
+	}}
+	uc := NewInstanceUsecase(f.instances, products, f.outbox, fakeTx{}, log.DefaultLogger)
+	events := NewInstanceEventUsecase(&fakeInstanceEventRepo{processed: map[string]bool{}}, f.instances, f.orders, f.uc, fakeTx{}, log.DefaultLogger)
+
+	// 下发变更规格：目标规格待确认，实例规格不变
+	resized, err := uc.ResizeInstance(ctx, "u1", instance.InstanceID, 1002)
+	if err != nil {
+		t.Fatalf("resize: err = %v", err)
+	}
+	if resized.ProductID != 1001 || resized.Spec.CPU != 4 || resized.PendingProductID != 1002 || resized.PendingSpec.CPU != 8 {
+		t.Errorf("resized = %+v, want spec unchanged with target 1002 pending", resized)
+	}
+	if last := f.outbox.enqueued[len(f.outbox.enqueued)-1]; last.CPU != 8 || last.Name != "GPU x2" {
+		t.Errorf("event = %+v, want the target spec", last)
+	}
+
+	// 资源域回传 RUNNING 后目标规格生效
+	ev := &InstanceEvent{EventID: "ev-1", EventType: EventInstanceStarted, InstanceID: instance.InstanceID, OccurredAt: time.Now()}
+	if err := events.HandleEvent(ctx, ev); err != nil {
+		t.Fatalf("handle event: err = %v", err)
+	}
+	if instance.ProductID != 1002 || instance.Spec.CPU != 8 || instance.PendingAction != "" || instance.PendingSpec != nil {
+		t.Errorf("instance = %+v, want resized to 1002 with nothing pending", instance)
+	}
+
+	// 超时未确认的待处理操作不再阻塞后续操作
+	if _, err := uc.StopInstance(ctx, "u1", instance.InstanceID); err != nil {
+		t.Fatalf("stop: err = %v", err)
+	}
+	if _, err := uc.StartInstance(ctx, "u1", instance.InstanceID); err != ErrInstanceBusy {
+		t.Errorf("start while stopping: err = %v, want %v", err, ErrInstanceBusy)
+	}
+	stuck := time.Now().Add(-InstancePendingTimeout - time.Minute)
+	instance.PendingAt = &stuck
+	if got, err := uc.GetInstance(ctx, "u1", instance.InstanceID); err != nil || got.PendingAction != "" {
+		t.Errorf("get stuck instance: pending = %q err = %v, want expired", got.PendingAction, err)
+	}
+	if _, err := uc.DeleteInstance(ctx, "u1", instance.InstanceID); err != nil {
+		t.Errorf("delete after pending expired: err = %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package biz
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 库存流水原因
+const (
+	InventoryReasonReserve = "RESERVE" // 下单预占
+	InventoryReasonRelease = "RELEASE" // 订单取消/过期归还
+	InventoryReasonRestock = "RESTOCK" // 补货
+	InventoryReasonAdjust  = "ADJUST"  // 盘点调整
+)
+
+// defaultInventoryLedgerLimit 库存流水默认查询条数
+const defaultInventoryLedgerLimit = 20
+
+// 库存错误
+var (
+	ErrOutOfStock             = &BizError{Code: 409, Message: "product is out of stock"}
+	ErrInventoryNotTracked    = &BizError{Code: 404, Message: "product inventory is not tracked"}
+	ErrInvalidRestockQuantity = &BizError{Code: 400, Message: "invalid restock quantity: must be greater than 0"}
+	ErrInvalidInventoryStock  = &BizError{Code: 400, Message: "invalid stock: must not be negative"}
+)
+
+// Inventory 商品库存（可选：没有库存记录的商品不限量售卖）
+type Inventory struct {
+	ProductID int64
+	Stock     int32
+	UpdatedAt time.Time
+}
+
+// InventoryMovement 库存流水（每次库存变动一条）
+type InventoryMovement struct {
+	ID         int64
+	ProductID  int64
+	Delta      int32  // 变动数量，正数为增加
+	StockAfter int32  // 变动后的库存
+	Reason     string // RESERVE / RELEASE / RESTOCK / ADJUST
+	OrderID    int64  // 关联订单（预占/归还时填写）
+	Note       string
+	CreatedAt  time.Time
+}
+
+// InventoryRepo 库存仓储接口，每次变动与流水在同一事务中写入
+type InventoryRepo interface {
+	// Get 查询商品库存，未启用库存管理时返回 ErrInventoryNotTracked
+	Get(ctx context.Context, productID int64) (*Inventory, error)
+	// Reserve 原子扣减库存（UPDATE ... WHERE stock >= quantity）并记录流水，需在订单事务内调用
+	// 库存不足返回 ErrOutOfStock；未启用库存管理时返回 ErrInventoryNotTracked
+	Reserve(ctx context.Context, productID int64, quantity int32, orderID int64) (*Inventory, error)
+	// Release 按订单的预占流水归还库存并记录流水，需在订单事务内调用；
+	// 订单没有预占库存（未启用库存管理）或已归还时返回 nil, nil，重复调用不会多归还
+	Release(ctx context.Context, productID int64, orderID int64) (*Inventory, error)
+	// Restock 补货，商品没有库存记录时创建（启用库存管理）
+	Restock(ctx context.Context, productID int64, quantity int32, note string) (*Inventory, error)
+	// Adjust 盘点调整：把库存设置为 stock，流水记录差值；商品没有库存记录时创建
+	Adjust(ctx context.Context, productID int64, stock int32, note string) (*Inventory, error)
+	// ListLedger 按时间倒序列出最近的库存流水
+	ListLedger(ctx context.Context, productID int64, limit int) ([]*InventoryMovement, error)
+}
+
+// InventoryUsecase 库存管理（管理员补货、盘点和查询流水）
+type InventoryUsecase struct {
+	repo        InventoryRepo
+	productRepo ProductRepo
+	log         *log.Helper
+}
+
+// NewInventoryUsecase 创建库存用例
+func NewInventoryUsecase(repo InventoryRepo, productRepo ProductRepo, logger log.Logger) *InventoryUsecase {
+	return &InventoryUsecase{
+		repo:        repo,
+		productRepo: productRepo,
+		log:         log.NewHelper(logger),
+	}
+}
+
+// GetInventory 查询商品库存和最近的库存流水
+func (uc *InventoryUsecase) GetInventory(ctx context.Context, productID int64, limit int) (*Inventory, []*InventoryMovement, error) {
+	if productID <= 0 {
+		return nil, nil, ErrInvalidProductID
+	}
+	if limit <= 0 || limit > 100 {
+		limit = defaultInventoryLedgerLimit
+	}
+	inventory, err := uc.repo.Get(ctx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ledger, err := uc.repo.ListLedger(ctx, productID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inventory, ledger, nil
+}
+
+// Restock 补货，商品首次补货时启用库存管理
+func (uc *InventoryUsecase) Restock(ctx context.Context, productID int64, quantity int32, note string) (*Inventory, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if quantity <= 0 {
+		return nil, ErrInvalidRestockQuantity
+	}
+	if _, err := uc.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	inventory, err := uc.repo.Restock(ctx, productID, quantity, note)
+	if err != nil {
+		uc.log.Errorf("restock failed: productID=%d quantity=%d err=%v", productID, quantity, err)
+		return nil, err
+	}
+	uc.log.Infof("product restocked: productID=%d quantity=%d stock=%d", productID, quantity, inventory.Stock)
+	return inventory, nil
+}
+
+// Adjust 盘点调整库存为 stock，商品首次调整时启用库存管理
+func (uc *InventoryUsecase) Adjust(ctx context.Context, productID int64, stock int32, note string) (*Inventory, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if stock < 0 {
+		return nil, ErrInvalidInventoryStock
+	}
+	if _, err := uc.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	inventory, err := uc.repo.Adjust(ctx, productID, stock, note)
+	if err != nil {
+		uc.log.Errorf("adjust inventory failed: productID=%d stock=%d err=%v", productID, stock, err)
+		return nil, err
+	}
+	uc.log.Infof("inventory adjusted: productID=%d stock=%d", productID, inventory.Stock)
+	return inventory, nil
+}
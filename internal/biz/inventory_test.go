@@ -0,0 +1,163 @@
+package biz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// fakeInventoryRepo 内存库存，stock 中没有记录的商品视为未启用库存管理
+type fakeInventoryRepo struct {
+	stock  map[int64]int32
+	ledger []*InventoryMovement
+}
+
+func (r *fakeInventoryRepo) Get(ctx context.Context, productID int64) (*Inventory, error) {
+	stock, ok := r.stock[productID]
+	if !ok {
+		return nil, ErrInventoryNotTracked
+	}
+	return &Inventory{ProductID: productID, Stock: stock}, nil
+}
+
+func (r *fakeInventoryRepo) move(productID int64, delta int32, reason string, orderID int64) *Inventory {
+	r.stock[productID] += delta
+	r.ledger = append(r.ledger, &InventoryMovement{
+		ID: int64(len(r.ledger) + 1), ProductID: productID, Delta: delta,
+		StockAfter: r.stock[productID], Reason: reason, OrderID: orderID,
+	})
+	return &Inventory{ProductID: productID, Stock: r.stock[productID]}
+}
+
+func (r *fakeInventoryRepo) Reserve(ctx context.Context, productID int64, quantity int32, orderID int64) (*Inventory, error) {
+	stock, ok := r.stock[productID]
+	if !ok {
+		return nil, ErrInventoryNotTracked
+	}
+	if stock < quantity {
+		return nil, ErrOutOfStock
+	}
+	return r.move(productID, -quantity, InventoryReasonReserve, orderID), nil
+}
+
+func (r *fakeInventoryRepo) Release(ctx context.Context, productID int64, orderID int64) (*Inventory, error) {
+	var reserved int32
+	for _, movement := range r.ledger {
+		if movement.ProductID == productID && movement.OrderID == orderID &&
+			(movement.Reason == InventoryReasonReserve || movement.Reason == InventoryReasonRelease) {
+			reserved -= movement.Delta
+		}
+	}
+	if reserved <= 0 {
+		return nil, nil
+	}
+	return r.move(productID, reserved, InventoryReasonRelease, orderID), nil
+}
+
+func (r *fakeInventoryRepo) Restock(ctx context.Context, productID int64, quantity int32, note string) (*Inventory, error) {
+	if r.stock == nil {
+		r.stock = map[int64]int32{}
+	}
+	return r.move(productID, quantity, InventoryReasonRestock, 0), nil
+}
+
+func (r *fakeInventoryRepo) Adjust(ctx context.Context, productID int64, stock int32, note string) (*Inventory, error) {
+	if r.stock == nil {
+		r.stock = map[int64]int32{}
+	}
+	return r.move(productID, stock-r.stock[productID], InventoryReasonAdjust, 0), nil
+}
+
+func (r *fakeInventoryRepo) ListLedger(ctx context.Context, productID int64, limit int) ([]*InventoryMovement, error) {
+	var ledger []*InventoryMovement
+	for i := len(r.ledger) - 1; i >= 0 && len(ledger) < limit; i-- {
+		if r.ledger[i].ProductID == productID {
+			ledger = append(ledger, r.ledger[i])
+		}
+	}
+	return ledger, nil
+}
+
+func TestOrderUsecase_PurchaseProduct_Inventory(t *testing.T) {
+	ctx := context.Background()
+	products := &fakeProductRepo{product: &Product{
+		ID: 1001, Name: "GPU", Status: ProductStatusEnabled, Price: 1000,
+		Spec: &ProductSpec{ID: 1, CPU: 4, Memory: 8192},
+	}}
+	orders := &fakeOrderRepo{}
+	inventory := &fakeInventoryRepo{}
+	ids := &fakeIDGenerator{next: 100}
+	uc := NewOrderUsecase(orders, products, &fakeInstanceRepo{}, &fakeOutboxRepo{}, inventory, newFakePaymentGateway(), fakeTx{}, ids, ids, log.DefaultLogger)
+
+	// 未启用库存管理的商品不限量
+	if _, _, _, err := uc.PurchaseProduct(ctx, "u1", 1001, ""); err != nil {
+		t.Fatalf("untracked product: err = %v", err)
+	}
+
+	inventory.stock = map[int64]int32{1001: 1}
+	first, _, _, err := uc.PurchaseProduct(ctx, "u1", 1001, "k1")
+	if err != nil {
+		t.Fatalf("reserve last unit: err = %v", err)
+	}
+	if inventory.stock[1001] != 0 {
+		t.Errorf("stock = %d, want 0", inventory.stock[1001])
+	}
+	if last := inventory.ledger[len(inventory.ledger)-1]; last.Reason != InventoryReasonReserve || last.OrderID != first.ID || last.Delta != -1 {
+		t.Errorf("ledger = %+v, want a reservation of order %d", last, first.ID)
+	}
+
+	if _, _, _, err := uc.PurchaseProduct(ctx, "u2", 1001, ""); err != ErrOutOfStock {
+		t.Errorf("sold out: err = %v, want %v", err, ErrOutOfStock)
+	}
+	// 售罄后带相同幂等键的重试仍返回首次创建的订单
+	again, _, replayed, err := uc.PurchaseProduct(ctx, "u1", 1001, "k1")
+	if err != nil || !replayed || again.ID != first.ID {
+		t.Errorf("retry after sold out: order = %+v replayed = %t err = %v, want order %d", again, replayed, err, first.ID)
+	}
+	if _, _, _, err := uc.PurchaseProduct(ctx, "u1", 1001, "k2"); err != ErrOutOfStock {
+		t.Errorf("new key after sold out: err = %v, want %v", err, ErrOutOfStock)
+	}
+
+	// 秒杀订单的库存在 Redis 中扣减，不预占 Postgres 库存
+	if _, _, err := uc.CreateOrderFromSeckill(ctx, 1001, "u3", 7, 990); err != nil {
+		t.Errorf("seckill order with zero stock: err = %v", err)
+	}
+	if len(orders.orders) != 3 {
+		t.Errorf("orders = %d, want 3", len(orders.orders))
+	}
+}
+
+func TestInventoryUsecase(t *testing.T) {
+	ctx := context.Background()
+	products := &fakeProductRepo{product: &Product{ID: 1001, Status: ProductStatusEnabled}}
+	repo := &fakeInventoryRepo{}
+	uc := NewInventoryUsecase(repo, products, log.DefaultLogger)
+
+	if _, _, err := uc.GetInventory(ctx, 1001, 0); err != ErrInventoryNotTracked {
+		t.Errorf("untracked: err = %v, want %v", err, ErrInventoryNotTracked)
+	}
+	if _, err := uc.Restock(ctx, 1001, 0, ""); err != ErrInvalidRestockQuantity {
+		t.Errorf("restock 0: err = %v, want %v", err, ErrInvalidRestockQuantity)
+	}
+	if _, err := uc.Adjust(ctx, 1001, -1, ""); err != ErrInvalidInventoryStock {
+		t.Errorf("adjust -1: err = %v, want %v", err, ErrInvalidInventoryStock)
+	}
+	if _, err := uc.Restock(ctx, 2002, 5, ""); err != ErrProductNotFound {
+		t.Errorf("restock unknown product: err = %v, want %v", err, ErrProductNotFound)
+	}
+
+	if inv, err := uc.Restock(ctx, 1001, 10, "initial"); err != nil || inv.Stock != 10 {
+		t.Fatalf("restock: inventory = %+v err = %v, want 10", inv, err)
+	}
+	if inv, err := uc.Adjust(ctx, 1001, 7, "stocktake"); err != nil || inv.Stock != 7 {
+		t.Fatalf("adjust: inventory = %+v err = %v, want 7", inv, err)
+	}
+	inv, ledger, err := uc.GetInventory(ctx, 1001, 0)
+	if err != nil || inv.Stock != 7 {
+		t.Fatalf("get: inventory = %+v err = %v, want 7", inv, err)
+	}
+	if len(ledger) != 2 || ledger[0].Reason != InventoryReasonAdjust || ledger[0].Delta != -3 || ledger[1].Reason != InventoryReasonRestock {
+		t.Errorf("ledger = %+v, want ADJUST -3 then RESTOCK", ledger)
+	}
+}
@@ -9,9 +9,10 @@ import (
 )
 
 var (
-	ErrProductNotFound = errors.New("product not found")
-	ErrProductDisabled = errors.New("product is disabled")
-	ErrInvalidUserID   = errors.New("invalid user id")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrProductDisabled     = errors.New("product is disabled")
+	ErrInvalidUserID       = errors.New("invalid user id")
+	ErrProductSpecNotFound = errors.New("product spec not found")
 )
 
 // ============================================================================
@@ -44,6 +45,16 @@ type ProductSpec struct {
 	CreatedAt  time.Time
 }
 
+// ProductSpecVersion 商品规格版本
+// 规格是值对象，变更规格时创建新规格并生成新版本，旧规格保留给历史订单
+type ProductSpecVersion struct {
+	ProductID     int64
+	Version       int32        // 版本号（从 1 开始递增）
+	Spec          *ProductSpec // 该版本的规格
+	EffectiveFrom time.Time    // 生效时间
+	EffectiveTo   *time.Time   // 失效时间（nil 表示当前版本）
+}
+
 // InstanceSpec 实例规格（用于 MQ 消息）
 // 实例是用户购买商品后，由资源域创建的实际运行资源（K8s Pod）
 type InstanceSpec struct {
@@ -57,16 +68,28 @@ type InstanceSpec struct {
 	ConfigJSON []byte // 扩展配置（JSON）
 }
 
-// InstanceInfo 实例信息（用于查询）
+// InstanceInfo 实例信息
+// 下单时创建，固化规格快照；生命周期状态由资源域事件驱动
 type InstanceInfo struct {
-	InstanceID  int64        // 实例ID
-	UserID      string       // 用户ID
-	OrderID     int64        // 订单ID
-	ProductID   int64        // 商品ID
-	ProductName string       // 商品名称
-	Spec        *ProductSpec // 商品规格
-	Status      string       // 实例状态（从订单状态推断）
-	CreatedAt   time.Time    // 创建时间
+	InstanceID    int64        // 实例ID
+	UserID        string       // 用户ID（实例归属）
+	OrderID       int64        // 订单ID
+	ProductID     int64        // 商品ID
+	ProductName   string       // 商品名称（下单时快照）
+	Spec          *ProductSpec // 实例规格（下单时快照）
+	Status        string       // 生命周期状态（CREATING, RUNNING, STOPPED, FAILED, DELETED）
+	ResourceState string       // 资源域最近一次上报的原始状态（如 Running/Failed）
+	Reason        string       // 状态原因（如失败原因）
+	PendingAction string       // 已下发、等待资源域确认的操作（START, STOP, DELETE, RESIZE），空表示无
+	PendingAt     *time.Time   // 操作下发时间
+	LastEventAt   *time.Time   // 最近一次生效的资源域事件时间
+	CreatedAt     time.Time    // 创建时间
+	UpdatedAt     time.Time    // 更新时间
+
+	// 变更规格（RESIZE）的目标，资源域回传 RUNNING 后才写入 ProductID/ProductName/Spec
+	PendingProductID   int64        // 目标商品ID
+	PendingProductName string       // 目标商品名称
+	PendingSpec        *ProductSpec // 目标规格，无待确认的变更规格时为 nil
 }
 
 // InstanceFilter 实例查询过滤器
@@ -83,10 +106,10 @@ type InstanceIDGenerator interface {
 	Generate(ctx context.Context, uuid string) (int64, error)
 }
 
-// InstanceRepo 实例仓储接口（用于查询订单关联的实例信息）
+// InstanceRepo 实例仓储接口
 type InstanceRepo interface {
-	// GetProductByID 获取商品信息（包含规格）
-	GetProductByID(ctx context.Context, productID int64) (*Product, error)
+	// Create 创建实例记录（与订单同事务写入）
+	Create(ctx context.Context, instance *InstanceInfo) error
 
 	// GetInstanceByID 根据实例ID获取实例信息
 	GetInstanceByID(ctx context.Context, instanceID int64) (*InstanceInfo, error)
@@ -96,6 +119,12 @@ type InstanceRepo interface {
 
 	// ListInstances 查询实例列表
 	ListInstances(ctx context.Context, filter InstanceFilter) ([]*InstanceInfo, int64, error)
+
+	// UpdateStatus 写入资源域回传的状态，记录中的事件时间更新时返回 false（旧事件不覆盖新状态）
+	UpdateStatus(ctx context.Context, record *InstanceStatusRecord) (bool, error)
+
+	// MarkPending 记录待处理操作（连同变更规格的目标规格），已有未超时的待处理操作时返回 ErrInstanceBusy
+	MarkPending(ctx context.Context, instance *InstanceInfo) error
 }
 
 // ============================================================================
@@ -104,16 +133,18 @@ type InstanceRepo interface {
 
 // Order 订单聚合根（与 DDL 对应）
 type Order struct {
-	ID          int64      // order_id (主键)
-	UserID      string     // user_id (UUID)
-	ProductID   int64      // product_id
-	ReqID       int64      // req_id（请求号，与 product_id 组成唯一索引）
-	Amount      int64      // amount（订单金额，单位：分）
-	InstanceID  int64      // instance_id（资源实例ID，支付后填充）
-	Status      string     // status: PENDING, PAID, CANCELLED, COMPLETED
-	CreatedAt   time.Time  // created_at
-	PaidAt      *time.Time // paid_at
-	CompletedAt *time.Time // completed_at
+	ID          int64        // order_id (主键)
+	UserID      string       // user_id (UUID)
+	ProductID   int64        // product_id
+	ReqID       int64        // req_id（请求号，与 product_id 组成唯一索引）
+	Amount      int64        // amount（订单金额，单位：分）
+	InstanceID  int64        // instance_id（资源实例ID，下单时分配，支付后创建实例）
+	Status      string       // status: PENDING, PAID, CANCELLED, COMPLETED
+	PaymentID   string       // payment_id（支付意图ID，正常购买下单后填充）
+	CreatedAt   time.Time    // created_at
+	PaidAt      *time.Time   // paid_at
+	CompletedAt *time.Time   // completed_at
+	Refund      *OrderRefund // refund_amount, refund_reason, refund_id, refunded_at（未退款为 nil）
 
 	// 业务扩展字段（不在 DDL 中）
 	ProductSnapshot *ProductSnapshot // 商品快照（业务逻辑需要）
@@ -121,6 +152,7 @@ type Order struct {
 }
 
 // ProductSnapshot 商品快照（值对象）
+// 下单时固化商品名称、价格和规格，订单查询以快照为准而非当前商品
 type ProductSnapshot struct {
 	ProductID int64
 	Name      string
@@ -131,14 +163,31 @@ type ProductSnapshot struct {
 // OrderRepo 订单仓储接口
 type OrderRepo interface {
 	Create(ctx context.Context, order *Order) error
+	// CreateBatch 以一条多行 INSERT 写入订单（连同商品快照），(product_id, req_id) 已存在的订单跳过；
+	// 返回值与 orders 一一对应，true 表示已写入
+	CreateBatch(ctx context.Context, orders []*Order) ([]bool, error)
 	GetByID(ctx context.Context, orderID int64) (*Order, error)
-	UpdateStatus(ctx context.Context, orderID int64, status string) error
+	// LockByID 锁定订单行（不含商品快照）直到事务结束，需在事务内调用
+	LockByID(ctx context.Context, orderID int64) (*Order, error)
+	// UpdateStatus 乐观并发更新状态：仅当当前状态仍为 from 时更新为 to，否则返回 ErrOrderStatusConflict
+	UpdateStatus(ctx context.Context, orderID int64, from, to string) error
+	// ListByProduct 列出商品在 since 之后创建的订单（不含商品快照），用于秒杀对账
+	ListByProduct(ctx context.Context, productID int64, since time.Time) ([]*Order, error)
+	// GetByReqID 按 (product_id, req_id) 查询订单（不含商品快照），不存在时返回 ErrOrderNotFound
+	GetByReqID(ctx context.Context, productID int64, reqID int64) (*Order, error)
+	// SetPaymentID 记录订单的支付意图ID，订单已关联其他支付意图时返回 ErrOrderStatusConflict
+	SetPaymentID(ctx context.Context, orderID int64, paymentID string) error
+	// MarkRefunded 记录退款并把订单迁移为 CANCELLED，仅当当前状态仍为 from 时更新，否则返回 ErrOrderStatusConflict
+	MarkRefunded(ctx context.Context, orderID int64, from string, refund *OrderRefund) error
+	// ClaimExpiredPending 认领创建早于 before 的待支付订单（不含商品快照），认领后 lease 内其他副本不会再认领；
+	// 认领不持有行锁，处理时需重新加锁
+	ClaimExpiredPending(ctx context.Context, before time.Time, limit int, lease time.Duration) ([]*Order, error)
 }
 
 // MQPublisher MQ 发布器接口
 type MQPublisher interface {
-	// PublishInstanceCreated 发布实例创建事件
-	PublishInstanceCreated(ctx context.Context, spec InstanceSpec) error
+	// Publish 发布已序列化的消息（由发件箱中继调用）
+	Publish(ctx context.Context, routingKey string, body []byte) error
 }
 
 // OrderIDGenerator 订单ID生成器接口
@@ -150,8 +199,11 @@ type OrderIDGenerator interface {
 type OrderUsecase struct {
 	orderRepo     OrderRepo
 	productRepo   ProductRepo
-	instanceRepo  InstanceRepo // 用于实例查询
-	mqPublisher   MQPublisher
+	instanceRepo  InstanceRepo   // 实例记录与订单同事务写入
+	outboxRepo    OutboxRepo     // 实例事件与订单同事务写入发件箱
+	inventoryRepo InventoryRepo  // 正常购买在订单事务内预占库存
+	payment       PaymentGateway // 正常购买下单后创建支付意图，支付确认后才创建实例
+	tx            Transaction
 	orderIDGen    OrderIDGenerator
 	instanceIDGen InstanceIDGenerator
 	log           *log.Helper
@@ -162,7 +214,10 @@ func NewOrderUsecase(
 	orderRepo OrderRepo,
 	productRepo ProductRepo,
 	instanceRepo InstanceRepo,
-	mqPublisher MQPublisher,
+	outboxRepo OutboxRepo,
+	inventoryRepo InventoryRepo,
+	payment PaymentGateway,
+	tx Transaction,
 	orderIDGen OrderIDGenerator,
 	instanceIDGen InstanceIDGenerator,
 	logger log.Logger,
@@ -171,56 +226,59 @@ func NewOrderUsecase(
 		orderRepo:     orderRepo,
 		productRepo:   productRepo,
 		instanceRepo:  instanceRepo,
-		mqPublisher:   mqPublisher,
+		outboxRepo:    outboxRepo,
+		inventoryRepo: inventoryRepo,
+		payment:       payment,
+		tx:            tx,
 		orderIDGen:    orderIDGen,
 		instanceIDGen: instanceIDGen,
 		log:           log.NewHelper(logger),
 	}
 }
 
-// CreateOrder 创建订单（统一入口）
-// 支持两种场景：
-// 1. 秒杀：reqID 由外部传入（Redis INCR 生成）
-// 2. 正常购买：reqID 传 0，内部生成随机大数
-// 返回：orderID, instanceID, error
+// CreateOrder 创建待支付订单（正常购买入口，秒杀订单走 CreateOrderFromSeckill）
+// reqID 传 0 时使用订单 ID 作为 req_id；商品启用了库存管理时在订单事务内预占 1 件库存，库存不足返回 ErrOutOfStock
+// 返回：orderID, instanceID（已分配，支付确认后才创建实例）, error
 func (uc *OrderUsecase) CreateOrder(ctx context.Context, productID int64, userID string, reqID int64) (int64, int64, error) {
-	uc.log.Infof("creating order: productID=%d userID=%s reqID=%d", productID, userID, reqID)
+	return uc.createOrder(ctx, productID, userID, reqID, 0, true)
+}
 
-	// 1. 如果 reqID 为 0，生成随机 req_id（正常购买场景）
-	if reqID == 0 {
-		var err error
-		reqID, err = uc.orderIDGen.Generate(ctx, userID)
-		if err != nil {
-			uc.log.Errorf("generate req_id failed: %v", err)
-			return 0, 0, err
-		}
-		uc.log.Infof("generated req_id: %d", reqID)
+// createOrder 创建订单，price 为成交价（分），0 表示按商品当前价格
+// normal 为 true 表示正常购买：预占 Postgres 库存，订单待支付，支付确认后才写入实例记录和实例创建事件；
+// 秒杀订单的库存已在 Redis 中扣减、视为已支付，直接与实例记录和实例创建事件一起写入
+func (uc *OrderUsecase) createOrder(ctx context.Context, productID int64, userID string, reqID int64, price int64, normal bool) (int64, int64, error) {
+	uc.log.Infof("creating order: productID=%d userID=%s reqID=%d", productID, userID, reqID)
+	if userID == "" {
+		return 0, 0, ErrInvalidUserID
 	}
 
-	// 2. 查询商品信息
+	// 1. 查询商品信息
 	product, err := uc.productRepo.GetByID(ctx, productID)
 	if err != nil {
 		uc.log.Errorf("get product failed: productID=%d err=%v", productID, err)
 		return 0, 0, err
 	}
 
-	if product.Status != "ENABLED" {
+	if product.Status != ProductStatusEnabled {
 		return 0, 0, ErrProductDisabled
 	}
 
 	if product.Spec == nil {
 		uc.log.Errorf("product spec not found: productID=%d", productID)
-		return 0, 0, errors.New("product spec not found")
+		return 0, 0, ErrProductSpecNotFound
 	}
 
-	// 3. 生成订单 ID
+	// 2. 生成订单 ID；reqID 为 0 时（正常购买场景）以订单 ID 作为 req_id，订单 ID 全局唯一
 	orderID, err := uc.orderIDGen.Generate(ctx, userID)
 	if err != nil {
 		uc.log.Errorf("generate order id failed: %v", err)
 		return 0, 0, err
 	}
+	if reqID == 0 {
+		reqID = orderID
+	}
 
-	// 4. 生成实例 ID
+	// 3. 生成实例 ID
 	instanceID, err := uc.instanceIDGen.Generate(ctx, userID)
 	if err != nil {
 		uc.log.Errorf("generate instance id failed: %v", err)
@@ -228,78 +286,157 @@ func (uc *OrderUsecase) CreateOrder(ctx context.Context, productID int64, userID
 	}
 	uc.log.Infof("generated orderID=%d instanceID=%d", orderID, instanceID)
 
-	// 5. 创建订单（一次性写入所有字段）
-	now := time.Now()
-	order := &Order{
-		ID:         orderID,
-		UserID:     userID,
-		ProductID:  productID,
-		ReqID:      reqID,
-		Amount:     product.Price,
-		InstanceID: instanceID,
-		Status:     "PAID", // 两种场景都是支付完成后才创建订单
-		CreatedAt:  now,
-		PaidAt:     &now,
-	}
-
-	if err := uc.orderRepo.Create(ctx, order); err != nil {
+	// 4. 创建订单（一次性写入所有字段）
+	status := OrderStatusPaid
+	if normal {
+		status = OrderStatusPending
+	}
+	draft := newOrderDraft(product, userID, reqID, price, orderID, instanceID, status, time.Now())
+
+	// 5. 库存预占与订单在同一事务中写入；已支付的订单同时写入实例记录与实例创建事件，事件由发件箱中继异步投递给 Resource Domain
+	// 订单写入失败（如幂等重放）时事务回滚，预占的库存随之归还
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if normal {
+			if err := uc.reserveStock(ctx, productID, orderID); err != nil {
+				return err
+			}
+		}
+		if err := uc.orderRepo.Create(ctx, draft.order); err != nil {
+			return err
+		}
+		if status != OrderStatusPaid {
+			return nil
+		}
+		return uc.saveInstance(ctx, draft)
+	})
+	if err != nil {
 		uc.log.Errorf("create order failed: %v", err)
 		return 0, 0, err
 	}
-	uc.log.Infof("order created: orderID=%d instanceID=%d reqID=%d", orderID, instanceID, reqID)
+	uc.log.Infof("order created: orderID=%d instanceID=%d reqID=%d status=%s", orderID, instanceID, reqID, status)
+	return orderID, instanceID, nil
+}
 
-	// 6. 发送 MQ 消息给 Resource Domain
-	spec := InstanceSpec{
-		InstanceID: instanceID,
-		UserID:     userID,
-		Name:       product.Name,
-		CPU:        product.Spec.CPU,
-		Memory:     product.Spec.Memory,
-		GPU:        product.Spec.GPU,
-		Image:      product.Spec.Image,
-		ConfigJSON: product.Spec.ConfigJSON,
+// reserveStock 预占 1 件库存，未启用库存管理的商品不限量
+func (uc *OrderUsecase) reserveStock(ctx context.Context, productID int64, orderID int64) error {
+	inventory, err := uc.inventoryRepo.Reserve(ctx, productID, 1, orderID)
+	if errors.Is(err, ErrInventoryNotTracked) {
+		return nil
 	}
-
-	if err := uc.mqPublisher.PublishInstanceCreated(ctx, spec); err != nil {
-		uc.log.Errorf("publish mq message failed: %v", err)
-		return 0, 0, err
+	if err != nil {
+		if errors.Is(err, ErrOutOfStock) {
+			uc.log.Warnf("product out of stock: productID=%d orderID=%d", productID, orderID)
+		}
+		return err
 	}
-	uc.log.Infof("mq message published: instanceID=%d", instanceID)
+	uc.log.Infof("stock reserved: productID=%d orderID=%d stock=%d", productID, orderID, inventory.Stock)
+	return nil
+}
 
-	uc.log.Infof("order created successfully: orderID=%d instanceID=%d", orderID, instanceID)
-	return orderID, instanceID, nil
+// releaseStock 归还订单预占的库存，需在订单所在的事务内调用；订单没有预占库存时不做任何事
+func (uc *OrderUsecase) releaseStock(ctx context.Context, productID int64, orderID int64) error {
+	inventory, err := uc.inventoryRepo.Release(ctx, productID, orderID)
+	if err != nil {
+		return err
+	}
+	if inventory != nil {
+		uc.log.Infof("stock released: productID=%d orderID=%d stock=%d", productID, orderID, inventory.Stock)
+	}
+	return nil
 }
 
-// PurchaseProduct 正常购买商品
-func (uc *OrderUsecase) PurchaseProduct(ctx context.Context, userID string, productID int64) (*Order, int64, error) {
+// PurchaseProduct 正常购买商品：创建待支付订单和支付意图，支付确认（ConfirmPayment 或网关回调）后才创建实例
+// idempotencyKey 为客户端提供的幂等键（可为空），同一用户、同一商品、同一幂等键的重试返回首次创建的订单，
+// replayed 为 true 表示返回的是已有订单
+func (uc *OrderUsecase) PurchaseProduct(ctx context.Context, userID string, productID int64, idempotencyKey string) (order *Order, instanceID int64, replayed bool, err error) {
 	if userID == "" {
-		return nil, 0, ErrInvalidUserID
+		return nil, 0, false, ErrInvalidUserID
 	}
 
-	// 调用 CreateOrder 统一处理，reqID 传 0（内部生成随机大数）
-	orderID, instanceID, err := uc.CreateOrder(ctx, productID, userID, 0)
+	// 没有幂等键时 reqID 传 0（使用订单 ID），有幂等键时由 (userID, 幂等键) 确定性地映射为 req_id
+	var reqID int64
+	if idempotencyKey != "" {
+		if reqID, err = IdempotencyReqID(userID, idempotencyKey); err != nil {
+			return nil, 0, false, err
+		}
+	}
+
+	orderID, instanceID, err := uc.CreateOrder(ctx, productID, userID, reqID)
+	if errors.Is(err, ErrOrderReqIDExists) && reqID != 0 {
+		orderID, instanceID, err = uc.findIdempotentOrder(ctx, productID, userID, reqID)
+		replayed = err == nil
+	} else if errors.Is(err, ErrOutOfStock) && reqID != 0 {
+		// 库存预占先于订单写入，售罄后的重试需要先确认首次请求是否已下单
+		if orderID, instanceID, err = uc.findIdempotentOrder(ctx, productID, userID, reqID); errors.Is(err, ErrOrderNotFound) {
+			err = ErrOutOfStock
+		}
+		replayed = err == nil
+	}
 	if err != nil {
 		uc.log.Errorf("create order failed: %v", err)
-		return nil, 0, err
+		return nil, 0, false, err
 	}
 
-	// 查询刚创建的订单
-	order, err := uc.orderRepo.GetByID(ctx, orderID)
+	// 查询订单（含商品快照）
+	order, err = uc.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		uc.log.Errorf("get order failed: %v", err)
-		return nil, 0, err
+		return nil, 0, false, err
 	}
 
-	uc.log.Infof("purchase completed: order_id=%d, instance_id=%d, user_id=%s, product_id=%d",
-		orderID, instanceID, userID, productID)
+	// 创建支付意图；失败时订单保持待支付，可带相同幂等键重试补建，未支付的订单超时后取消
+	if err := uc.preparePayment(ctx, order); err != nil {
+		return nil, 0, false, err
+	}
+
+	uc.log.Infof("purchase completed: order_id=%d, instance_id=%d, user_id=%s, product_id=%d, replayed=%t",
+		orderID, instanceID, userID, productID, replayed)
 
-	return order, instanceID, nil
+	return order, instanceID, replayed, nil
+}
+
+// findIdempotentOrder 幂等键对应的 req_id 已有订单时返回该订单；订单属于其他用户（哈希碰撞）时返回 ErrIdempotencyKeyConflict
+func (uc *OrderUsecase) findIdempotentOrder(ctx context.Context, productID int64, userID string, reqID int64) (int64, int64, error) {
+	existing, err := uc.orderRepo.GetByReqID(ctx, productID, reqID)
+	if err != nil {
+		uc.log.Errorf("get order by req_id failed: productID=%d reqID=%d err=%v", productID, reqID, err)
+		return 0, 0, err
+	}
+	if existing.UserID != userID {
+		uc.log.Errorf("idempotency key conflict: productID=%d reqID=%d userID=%s existingUserID=%s",
+			productID, reqID, userID, existing.UserID)
+		return 0, 0, ErrIdempotencyKeyConflict
+	}
+	uc.log.Infof("purchase replayed by idempotency key: productID=%d reqID=%d orderID=%d", productID, reqID, existing.ID)
+	return existing.ID, existing.InstanceID, nil
 }
 
 // CreateOrderFromSeckill 秒杀场景创建订单
-// reqID: Redis INCR 生成的请求号
-func (uc *OrderUsecase) CreateOrderFromSeckill(ctx context.Context, productID int64, userID string, reqID int64) (int64, int64, error) {
-	return uc.CreateOrder(ctx, productID, userID, reqID)
+// reqID: 订单流消息ID打包的请求号（SeckillOrderReqID）；price: 活动秒杀价（分），0 表示按商品原价
+// 同一请求号的订单已存在时，属于同一用户视为消息重放，返回已有订单；属于其他用户返回 ErrSeckillReqIDCollision，不能当作成功 ACK
+func (uc *OrderUsecase) CreateOrderFromSeckill(ctx context.Context, productID int64, userID string, reqID int64, price int64) (int64, int64, error) {
+	orderID, instanceID, err := uc.createOrder(ctx, productID, userID, reqID, price, false)
+	if !errors.Is(err, ErrOrderReqIDExists) {
+		return orderID, instanceID, err
+	}
+	return uc.resolveSeckillReplay(ctx, productID, userID, reqID)
+}
+
+// resolveSeckillReplay 秒杀订单 req_id 已存在时区分消息重放与 req_id 冲突
+func (uc *OrderUsecase) resolveSeckillReplay(ctx context.Context, productID int64, userID string, reqID int64) (int64, int64, error) {
+	existing, err := uc.orderRepo.GetByReqID(ctx, productID, reqID)
+	if err != nil {
+		uc.log.Errorf("get order by req_id failed: productID=%d reqID=%d err=%v", productID, reqID, err)
+		return 0, 0, err
+	}
+	if existing.UserID != userID {
+		uc.log.Errorf("seckill req_id collision: productID=%d reqID=%d userID=%s existingOrderID=%d existingUserID=%s",
+			productID, reqID, userID, existing.ID, existing.UserID)
+		return 0, 0, ErrSeckillReqIDCollision
+	}
+	uc.log.Warnf("seckill order already exists (replay): productID=%d reqID=%d userID=%s orderID=%d",
+		productID, reqID, userID, existing.ID)
+	return existing.ID, existing.InstanceID, nil
 }
 
 // GetOrderByID 根据订单ID获取订单
@@ -307,10 +444,65 @@ func (uc *OrderUsecase) GetOrderByID(ctx context.Context, orderID int64) (*Order
 	return uc.orderRepo.GetByID(ctx, orderID)
 }
 
-// GetInstance 获取实例信息（通过订单查询）
+// CancelOrder 用户取消自己的订单，待支付订单同时归还预占的库存；已支付或已完成的订单按使用时长比例退款并删除实例
+func (uc *OrderUsecase) CancelOrder(ctx context.Context, userID string, orderID int64) (*Order, error) {
+	order, err := uc.getOwnedOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case OrderStatusPending:
+		return uc.cancelPendingOrder(ctx, order)
+	case OrderStatusPaid, OrderStatusCompleted:
+		return uc.refundOrder(ctx, orderID, 0, RefundReasonCancelled)
+	}
+	return uc.transitOrder(ctx, orderID, OrderStatusCancelled)
+}
+
+// getOwnedOrder 查询订单并校验归属
+func (uc *OrderUsecase) getOwnedOrder(ctx context.Context, userID string, orderID int64) (*Order, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	order, err := uc.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		uc.log.Warnf("order ownership mismatch: orderID=%d userID=%s", orderID, userID)
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// CompleteOrder 完成订单（实例交付后调用）
+func (uc *OrderUsecase) CompleteOrder(ctx context.Context, orderID int64) (*Order, error) {
+	return uc.transitOrder(ctx, orderID, OrderStatusCompleted)
+}
+
+// transitOrder 按状态机校验并迁移订单状态
+func (uc *OrderUsecase) transitOrder(ctx context.Context, orderID int64, to string) (*Order, error) {
+	order, err := uc.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !CanTransitOrder(order.Status, to) {
+		uc.log.Warnf("illegal order transition: orderID=%d %s -> %s", orderID, order.Status, to)
+		return nil, &OrderTransitionError{OrderID: orderID, From: order.Status, To: to}
+	}
+
+	if err := uc.orderRepo.UpdateStatus(ctx, orderID, order.Status, to); err != nil {
+		uc.log.Errorf("update order status failed: orderID=%d %s -> %s err=%v", orderID, order.Status, to, err)
+		return nil, err
+	}
+	uc.log.Infof("order status changed: orderID=%d %s -> %s", orderID, order.Status, to)
+
+	return uc.orderRepo.GetByID(ctx, orderID)
+}
+
+// GetInstance 获取实例信息
 func (uc *OrderUsecase) GetInstance(ctx context.Context, instanceID int64) (*InstanceInfo, error) {
-	// 实例信息实际上是订单关联的资源信息
-	// 这里需要通过 InstanceRepo 查询（因为需要跨表查询 orders + products）
 	return uc.instanceRepo.GetInstanceByID(ctx, instanceID)
 }
 
@@ -0,0 +1,200 @@
+package biz
+
+import (
+	"context"
+	"time"
+)
+
+// orderDraft 一笔待写入的订单及其实例记录和实例创建事件
+type orderDraft struct {
+	order    *Order
+	instance *InstanceInfo
+	spec     InstanceSpec
+}
+
+// newOrderDraft 按商品和成交价生成订单草稿，price 为 0 时按商品当前价格
+// status 为 PAID 时（秒杀）记录支付时间，PENDING（正常购买）在支付确认后才记录
+func newOrderDraft(product *Product, userID string, reqID, price, orderID, instanceID int64, status string, now time.Time) *orderDraft {
+	if price <= 0 {
+		price = product.Price
+	}
+	order := &Order{
+		ID:         orderID,
+		UserID:     userID,
+		ProductID:  product.ID,
+		ReqID:      reqID,
+		Amount:     price,
+		InstanceID: instanceID,
+		Status:     status,
+		CreatedAt:  now,
+		ProductSnapshot: &ProductSnapshot{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Price:     product.Price,
+			Spec:      product.Spec,
+		},
+	}
+	if status == OrderStatusPaid {
+		order.PaidAt = &now
+	}
+	return instanceDraftOf(order, now)
+}
+
+// newInstanceDraft 支付确认后按订单的商品快照生成实例记录和实例创建事件
+func newInstanceDraft(order *Order) (*orderDraft, error) {
+	if order.ProductSnapshot == nil || order.ProductSnapshot.Spec == nil {
+		return nil, ErrProductSpecNotFound
+	}
+	return instanceDraftOf(order, time.Now()), nil
+}
+
+// instanceDraftOf 按订单的商品快照生成实例记录和实例创建事件
+func instanceDraftOf(order *Order, now time.Time) *orderDraft {
+	snapshot := order.ProductSnapshot
+	return &orderDraft{
+		order: order,
+		instance: &InstanceInfo{
+			InstanceID:  order.InstanceID,
+			UserID:      order.UserID,
+			OrderID:     order.ID,
+			ProductID:   order.ProductID,
+			ProductName: snapshot.Name,
+			Spec:        snapshot.Spec,
+			Status:      InstanceStatusCreating,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+		spec: InstanceSpec{
+			InstanceID: order.InstanceID,
+			UserID:     order.UserID,
+			Name:       snapshot.Name,
+			CPU:        snapshot.Spec.CPU,
+			Memory:     snapshot.Spec.Memory,
+			GPU:        snapshot.Spec.GPU,
+			Image:      snapshot.Spec.Image,
+			ConfigJSON: snapshot.Spec.ConfigJSON,
+		},
+	}
+}
+
+// saveInstance 写入实例记录和实例创建事件，需在订单所在的事务内调用
+func (uc *OrderUsecase) saveInstance(ctx context.Context, draft *orderDraft) error {
+	if err := uc.instanceRepo.Create(ctx, draft.instance); err != nil {
+		return err
+	}
+	return uc.outboxRepo.EnqueueInstanceEvent(ctx, EventInstanceCreated, draft.spec)
+}
+
+// SeckillOrderRequest 批量创建秒杀订单中的一条请求
+type SeckillOrderRequest struct {
+	UserID string
+	ReqID  int64 // SeckillOrderReqID 打包的请求号
+}
+
+// SeckillOrderOutcome 批量创建秒杀订单中一条请求的结果
+type SeckillOrderOutcome struct {
+	OrderID    int64
+	InstanceID int64
+	Err        error // nil 表示订单已创建（或同一用户的消息重放）
+}
+
+// CreateOrderBatchFromSeckill 批量创建同一商品的秒杀订单，结果与 reqs 一一对应
+// 商品只查询一次，订单以一条多行 INSERT ... ON CONFLICT (product_id, req_id) DO NOTHING 写入；
+// 已存在的 req_id 按 CreateOrderFromSeckill 的规则区分重放与冲突。
+// 批量写入失败时（如某个用户ID非法导致整条语句失败）逐条创建，得到每条请求各自的结果
+func (uc *OrderUsecase) CreateOrderBatchFromSeckill(ctx context.Context, productID int64, price int64, reqs []*SeckillOrderRequest) []*SeckillOrderOutcome {
+	outcomes := make([]*SeckillOrderOutcome, len(reqs))
+	for i := range outcomes {
+		outcomes[i] = &SeckillOrderOutcome{}
+	}
+	if len(reqs) == 0 {
+		return outcomes
+	}
+
+	product, err := uc.productRepo.GetByID(ctx, productID)
+	if err == nil && product.Status != ProductStatusEnabled {
+		err = ErrProductDisabled
+	}
+	if err == nil && product.Spec == nil {
+		err = ErrProductSpecNotFound
+	}
+	if err != nil {
+		uc.log.Errorf("seckill batch rejected: productID=%d size=%d err=%v", productID, len(reqs), err)
+		for _, outcome := range outcomes {
+			outcome.Err = err
+		}
+		return outcomes
+	}
+
+	// 生成订单草稿，idx 记录草稿对应的请求下标
+	now := time.Now()
+	drafts := make([]*orderDraft, 0, len(reqs))
+	idx := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if req.UserID == "" {
+			outcomes[i].Err = ErrInvalidUserID
+			continue
+		}
+		orderID, err := uc.orderIDGen.Generate(ctx, req.UserID)
+		if err != nil {
+			uc.log.Errorf("generate order id failed: %v", err)
+			outcomes[i].Err = err
+			continue
+		}
+		instanceID, err := uc.instanceIDGen.Generate(ctx, req.UserID)
+		if err != nil {
+			uc.log.Errorf("generate instance id failed: %v", err)
+			outcomes[i].Err = err
+			continue
+		}
+		drafts = append(drafts, newOrderDraft(product, req.UserID, req.ReqID, price, orderID, instanceID, OrderStatusPaid, now))
+		idx = append(idx, i)
+	}
+	if len(drafts) == 0 {
+		return outcomes
+	}
+
+	orders := make([]*Order, len(drafts))
+	for i, draft := range drafts {
+		orders[i] = draft.order
+	}
+	var inserted []bool
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		inserted, err = uc.orderRepo.CreateBatch(ctx, orders)
+		if err != nil {
+			return err
+		}
+		for i, draft := range drafts {
+			if !inserted[i] {
+				continue
+			}
+			if err := uc.saveInstance(ctx, draft); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		uc.log.Warnf("create seckill order batch failed, falling back to one by one: productID=%d size=%d err=%v",
+			productID, len(drafts), err)
+		for _, i := range idx {
+			outcome := outcomes[i]
+			outcome.OrderID, outcome.InstanceID, outcome.Err = uc.CreateOrderFromSeckill(ctx, productID, reqs[i].UserID, reqs[i].ReqID, price)
+		}
+		return outcomes
+	}
+
+	created := 0
+	for j, draft := range drafts {
+		outcome := outcomes[idx[j]]
+		if inserted[j] {
+			outcome.OrderID, outcome.InstanceID = draft.order.ID, draft.order.InstanceID
+			created++
+			continue
+		}
+		outcome.OrderID, outcome.InstanceID, outcome.Err = uc.resolveSeckillReplay(ctx, productID, draft.order.UserID, draft.order.ReqID)
+	}
+	uc.log.Infof("seckill order batch created: productID=%d size=%d created=%d", productID, len(reqs), created)
+	return outcomes
+}
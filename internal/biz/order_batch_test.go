@@ -0,0 +1,133 @@
+package biz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeProductRepo struct {
+	ProductRepo
+	product *Product
+	gets    int
+}
+
+func (r *fakeProductRepo) GetByID(ctx context.Context, id int64) (*Product, error) {
+	r.gets++
+	if r.product == nil || r.product.ID != id {
+		return nil, ErrProductNotFound
+	}
+	return r.product, nil
+}
+
+type fakeInstanceRepo struct {
+	InstanceRepo
+	created []*InstanceInfo
+}
+
+func (r *fakeInstanceRepo) Create(ctx context.Context, instance *InstanceInfo) error {
+	r.created = append(r.created, instance)
+	return nil
+}
+
+func (r *fakeInstanceRepo) GetInstanceByOrderID(ctx context.Context, orderID int64) (*InstanceInfo, error) {
+	for _, instance := range r.created {
+		if instance.OrderID == orderID {
+			return instance, nil
+		}
+	}
+	return nil, ErrInstanceNotFound
+}
+
+func (r *fakeInstanceRepo) MarkPending(ctx context.Context, instance *InstanceInfo) error {
+	return nil
+}
+
+func (r *fakeInstanceRepo) GetInstanceByID(ctx context.Context, instanceID int64) (*InstanceInfo, error) {
+	for _, instance := range r.created {
+		if instance.InstanceID == instanceID {
+			return instance, nil
+		}
+	}
+	return nil, ErrInstanceNotFound
+}
+
+func (r *fakeInstanceRepo) UpdateStatus(ctx context.Context, record *InstanceStatusRecord) (bool, error) {
+	instance, err := r.GetInstanceByID(ctx, record.InstanceID)
+	if err != nil {
+		return false, err
+	}
+	instance.Status = record.Status
+	if record.ApplyPending && instance.PendingSpec != nil {
+		instance.ProductID, instance.ProductName, instance.Spec = instance.PendingProductID, instance.PendingProductName, instance.PendingSpec
+	}
+	if record.ClearPending {
+		instance.clearPending()
+	}
+	return true, nil
+}
+
+type fakeIDGenerator struct {
+	next int64
+}
+
+func (g *fakeIDGenerator) Generate(ctx context.Context, userID string) (int64, error) {
+	g.next++
+	return g.next, nil
+}
+
+func TestOrderUsecase_CreateOrderBatchFromSeckill(t *testing.T) {
+	ctx := context.Background()
+	products := &fakeProductRepo{product: &Product{
+		ID: 1001, Name: "GPU", Status: ProductStatusEnabled, Price: 1000,
+		Spec: &ProductSpec{ID: 1, CPU: 4, Memory: 8192, GPU: 1, Image: "ubuntu:22.04"},
+	}}
+	orders := &fakeOrderRepo{orders: []*Order{
+		{ID: 900, ProductID: 1001, UserID: "u2", ReqID: 2, InstanceID: 901},
+		{ID: 910, ProductID: 1001, UserID: "other", ReqID: 3, InstanceID: 911},
+	}}
+	instances := &fakeInstanceRepo{}
+	ids := &fakeIDGenerator{next: 100}
+	uc := NewOrderUsecase(orders, products, instances, &fakeOutboxRepo{}, &fakeInventoryRepo{}, newFakePaymentGateway(), fakeTx{}, ids, ids, log.DefaultLogger)
+
+	outcomes := uc.CreateOrderBatchFromSeckill(ctx, 1001, 990, []*SeckillOrderRequest{
+		{UserID: "u1", ReqID: 1},
+		{UserID: "", ReqID: 4},
+		{UserID: "u2", ReqID: 2}, // 同一用户的重放
+		{UserID: "u3", ReqID: 3}, // req_id 被其他用户占用
+	})
+
+	if products.gets != 1 {
+		t.Errorf("product lookups = %d, want 1 per batch", products.gets)
+	}
+	if outcomes[0].Err != nil || outcomes[0].OrderID == 0 {
+		t.Errorf("outcome[0] = %+v, want created", outcomes[0])
+	}
+	if outcomes[1].Err != ErrInvalidUserID {
+		t.Errorf("outcome[1].Err = %v, want %v", outcomes[1].Err, ErrInvalidUserID)
+	}
+	if outcomes[2].Err != nil || outcomes[2].OrderID != 900 || outcomes[2].InstanceID != 901 {
+		t.Errorf("outcome[2] = %+v, want the existing order 900", outcomes[2])
+	}
+	if outcomes[3].Err != ErrSeckillReqIDCollision {
+		t.Errorf("outcome[3].Err = %v, want %v", outcomes[3].Err, ErrSeckillReqIDCollision)
+	}
+
+	if len(orders.orders) != 3 {
+		t.Fatalf("orders = %d, want 3", len(orders.orders))
+	}
+	if created := orders.orders[2]; created.UserID != "u1" || created.Amount != 990 || created.Status != OrderStatusPaid {
+		t.Errorf("created order = %+v, want u1 paid at the seckill price", created)
+	}
+	if len(instances.created) != 1 || instances.created[0].OrderID != outcomes[0].OrderID {
+		t.Errorf("instances = %+v, want one for the created order", instances.created)
+	}
+
+	products.product.Status = ProductStatusDisabled
+	for _, outcome := range uc.CreateOrderBatchFromSeckill(ctx, 1001, 990, []*SeckillOrderRequest{{UserID: "u4", ReqID: 5}}) {
+		if outcome.Err != ErrProductDisabled {
+			t.Errorf("disabled product: err = %v, want %v", outcome.Err, ErrProductDisabled)
+		}
+	}
+}
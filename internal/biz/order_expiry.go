@@ -0,0 +1,97 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// OrderExpiryOptions 待支付订单过期参数
+type OrderExpiryOptions struct {
+	TTL       time.Duration // 待支付订单的有效期
+	BatchSize int           // 单次认领的订单数
+	Lease     time.Duration // 认领租期，处理失败的订单在租期后重试
+}
+
+// OrderExpiryResult 单轮过期处理结果
+type OrderExpiryResult struct {
+	Expired int // 已取消并归还库存
+	Paid    int // 网关中已支付成功（回调丢失），补记为已支付
+	Failed  int // 处理失败，保持待支付，下一轮重试
+}
+
+// ExpirePendingOrders 取消超过有效期仍未支付的订单并归还预占的库存
+// 多个副本以 SKIP LOCKED 认领不同的订单；每个订单先在事务外向网关查询支付结果，再在锁定订单行的独立事务中处理：
+// 已支付成功（回调丢失）的订单补记为已支付而不是取消。单个订单失败不影响同批其他订单，租期过后排在新过期的订单之后重试；
+// 与支付确认并发时以订单行锁串行，后到的一方看到订单已不是待支付而跳过。秒杀订单创建即为已支付，不会过期
+func (uc *OrderUsecase) ExpirePendingOrders(ctx context.Context, opts OrderExpiryOptions) (OrderExpiryResult, error) {
+	orders, err := uc.orderRepo.ClaimExpiredPending(ctx, time.Now().Add(-opts.TTL), opts.BatchSize, opts.Lease)
+	if err != nil {
+		uc.log.Errorf("claim expired pending orders failed: %v", err)
+		return OrderExpiryResult{}, err
+	}
+
+	var result OrderExpiryResult
+	for _, order := range orders {
+		paid, err := uc.expireOrder(ctx, order)
+		switch {
+		case errors.Is(err, ErrOrderStatusConflict):
+			uc.log.Infof("expired order already handled, skip: orderID=%d", order.ID)
+		case err != nil:
+			result.Failed++
+			uc.log.Errorf("expire pending order failed: orderID=%d err=%v", order.ID, err)
+		case paid:
+			result.Paid++
+		default:
+			result.Expired++
+		}
+	}
+	return result, nil
+}
+
+// expireOrder 处理一个过期订单，返回 true 表示订单在网关中已支付成功并补记为已支付；
+// 订单在查询网关期间已被支付确认或取消时返回 ErrOrderStatusConflict
+func (uc *OrderUsecase) expireOrder(ctx context.Context, order *Order) (bool, error) {
+	var intent *PaymentIntent
+	if order.PaymentID != "" {
+		got, err := uc.payment.Query(ctx, order.PaymentID)
+		// 网关中没有支付意图（如 fake 网关重启）视为未支付
+		if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+			return false, err
+		}
+		if err == nil && got.Status == PaymentStatusSucceeded {
+			intent = got
+		}
+	}
+
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		locked, err := uc.orderRepo.LockByID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != OrderStatusPending {
+			return ErrOrderStatusConflict
+		}
+		if intent == nil {
+			_, err = uc.cancelPendingOrder(ctx, locked)
+			return err
+		}
+
+		// 补记已支付需要商品快照写入实例
+		full, err := uc.orderRepo.GetByID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		uc.log.Warnf("expired order was paid, webhook missed: orderID=%d paymentID=%s", order.ID, intent.ID)
+		_, err = uc.markPaid(ctx, full, intent)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if intent == nil {
+		uc.log.Infof("pending order expired: orderID=%d productID=%d age=%s",
+			order.ID, order.ProductID, time.Since(order.CreatedAt).Truncate(time.Second))
+	}
+	return intent != nil, nil
+}
@@ -0,0 +1,91 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrderUsecase_ExpirePendingOrders(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+	opts := OrderExpiryOptions{TTL: 15 * time.Minute, BatchSize: 10}
+
+	var orders []*Order
+	for _, userID := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		order, _, _, err := f.uc.PurchaseProduct(ctx, userID, 1001, "")
+		if err != nil {
+			t.Fatalf("purchase: err = %v", err)
+		}
+		orders = append(orders, order)
+	}
+	expired, paidLate, lost, fresh, unreachable := orders[0], orders[1], orders[2], orders[3], orders[4]
+	for _, order := range []*Order{expired, paidLate, lost, unreachable} {
+		order.CreatedAt = order.CreatedAt.Add(-time.Hour)
+	}
+	// 支付成功但回调丢失；网关中没有支付意图（fake 网关重启）
+	f.gateway.intents[paidLate.PaymentID].Status = PaymentStatusSucceeded
+	delete(f.gateway.intents, lost.PaymentID)
+	// 网关查询失败的订单不影响同批其他订单
+	f.gateway.queryErrs = map[string]error{unreachable.PaymentID: errors.New("gateway timeout")}
+
+	res, err := f.uc.ExpirePendingOrders(ctx, opts)
+	if err != nil {
+		t.Fatalf("expire: err = %v", err)
+	}
+	if res != (OrderExpiryResult{Expired: 2, Paid: 1, Failed: 1}) {
+		t.Errorf("result = %+v, want 2 expired 1 paid 1 failed", res)
+	}
+	if expired.Status != OrderStatusCancelled || lost.Status != OrderStatusCancelled {
+		t.Errorf("statuses = %s %s, want cancelled", expired.Status, lost.Status)
+	}
+	if paidLate.Status != OrderStatusPaid || len(f.instances.created) != 1 {
+		t.Errorf("paid late: status = %s instances = %d, want paid with an instance", paidLate.Status, len(f.instances.created))
+	}
+	if fresh.Status != OrderStatusPending || unreachable.Status != OrderStatusPending {
+		t.Errorf("fresh = %s unreachable = %s, want both pending", fresh.Status, unreachable.Status)
+	}
+	if f.inventory.stock[1001] != 2 {
+		t.Errorf("stock = %d, want 2 (two released)", f.inventory.stock[1001])
+	}
+
+	// 网关恢复后下一轮过期
+	f.gateway.queryErrs = nil
+	if res, err := f.uc.ExpirePendingOrders(ctx, opts); err != nil || res != (OrderExpiryResult{Expired: 1}) {
+		t.Errorf("second run: result = %+v err = %v, want 1 expired", res, err)
+	}
+	if res, err := f.uc.ExpirePendingOrders(ctx, opts); err != nil || res != (OrderExpiryResult{}) {
+		t.Errorf("third run: result = %+v err = %v, want nothing to expire", res, err)
+	}
+}
+
+func TestOrderUsecase_ExpirePendingOrders_FailedRequeuedBehind(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+	opts := OrderExpiryOptions{TTL: 15 * time.Minute, BatchSize: 1, Lease: time.Minute}
+
+	stuck, _, _, _ := f.uc.PurchaseProduct(ctx, "u1", 1001, "")
+	newer, _, _, _ := f.uc.PurchaseProduct(ctx, "u2", 1001, "")
+	stuck.CreatedAt = stuck.CreatedAt.Add(-2 * time.Hour)
+	newer.CreatedAt = newer.CreatedAt.Add(-time.Hour)
+	f.gateway.queryErrs = map[string]error{stuck.PaymentID: errors.New("gateway timeout")}
+
+	if res, err := f.uc.ExpirePendingOrders(ctx, opts); err != nil || res != (OrderExpiryResult{Failed: 1}) {
+		t.Fatalf("first run: result = %+v err = %v, want the oldest order failed", res, err)
+	}
+	// 失败的订单在租期内不会被再次认领，也不会挡住较新的过期订单
+	if res, err := f.uc.ExpirePendingOrders(ctx, opts); err != nil || res != (OrderExpiryResult{Expired: 1}) {
+		t.Errorf("second run: result = %+v err = %v, want the newer order expired", res, err)
+	}
+	if stuck.Status != OrderStatusPending || newer.Status != OrderStatusCancelled {
+		t.Errorf("stuck = %s newer = %s, want pending and cancelled", stuck.Status, newer.Status)
+	}
+
+	// 租期过后重试，并排在新过期的订单之后
+	f.gateway.queryErrs = nil
+	f.orders.expiryAttempts[stuck.ID] = time.Now().Add(-time.Second)
+	if res, err := f.uc.ExpirePendingOrders(ctx, opts); err != nil || res != (OrderExpiryResult{Expired: 1}) || stuck.Status != OrderStatusCancelled {
+		t.Errorf("after lease: result = %+v err = %v status = %s, want the stuck order expired", res, err, stuck.Status)
+	}
+}
@@ -0,0 +1,53 @@
+package biz
+
+import "fmt"
+
+// 订单状态
+const (
+	OrderStatusPending   = "PENDING"   // 待支付
+	OrderStatusPaid      = "PAID"      // 已支付
+	OrderStatusCompleted = "COMPLETED" // 已完成（实例已交付）
+	OrderStatusCancelled = "CANCELLED" // 已取消
+)
+
+// orderTransitions 订单状态机：当前状态 -> 允许迁移到的状态
+//
+//	PENDING -> PAID -> COMPLETED
+//	   |        |          |
+//	   +--------+----------+----> CANCELLED
+//
+// 已支付和已完成的订单只能通过退款取消（RefundOrder）
+var orderTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusCompleted, OrderStatusCancelled},
+	OrderStatusCompleted: {OrderStatusCancelled},
+}
+
+// 订单状态错误
+var (
+	ErrOrderNotFound       = &BizError{Code: 404, Message: "order not found", Permanent: true}
+	ErrOrderForbidden      = &BizError{Code: 403, Message: "order does not belong to user", Permanent: true}
+	ErrOrderStatusConflict = &BizError{Code: 409, Message: "order status changed concurrently, please retry"}
+	ErrOrderReqIDExists    = &BizError{Code: 409, Message: "order with the same req_id already exists"}
+)
+
+// OrderTransitionError 非法的订单状态迁移
+type OrderTransitionError struct {
+	OrderID int64
+	From    string
+	To      string
+}
+
+func (e *OrderTransitionError) Error() string {
+	return fmt.Sprintf("illegal order status transition: orderID=%d %s -> %s", e.OrderID, e.From, e.To)
+}
+
+// CanTransitOrder 判断订单能否从 from 迁移到 to
+func CanTransitOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package biz
+
+import "testing"
+
+func TestCanTransitOrder(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderStatusPending, OrderStatusPaid, true},
+		{OrderStatusPending, OrderStatusCancelled, true},
+		{OrderStatusPending, OrderStatusCompleted, false},
+		{OrderStatusPaid, OrderStatusCompleted, true},
+		{OrderStatusPaid, OrderStatusCancelled, true},
+		{OrderStatusPaid, OrderStatusPending, false},
+		{OrderStatusCompleted, OrderStatusCancelled, true},
+		{OrderStatusCompleted, OrderStatusPaid, false},
+		{OrderStatusCancelled, OrderStatusPaid, false},
+		{"UNKNOWN", OrderStatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransitOrder(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitOrder(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
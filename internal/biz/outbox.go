@@ -0,0 +1,183 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// MQ 事件类型（与 api/mq/event.proto 中的 EventType 名称一致）
+const (
+	EventInstanceCreated       = "INSTANCE_CREATED"
+	EventInstanceDeleted       = "INSTANCE_DELETED"
+	EventInstanceSpecChanged   = "INSTANCE_SPEC_CHANGED"
+	EventInstanceStarted       = "INSTANCE_STARTED"
+	EventInstanceStopped       = "INSTANCE_STOPPED"
+	EventInstanceStatusChanged = "INSTANCE_STATUS_CHANGED"
+	EventInstanceK8sSync       = "INSTANCE_K8S_SYNC"
+)
+
+// EventSourceProduct 商品域发布事件的来源标识（AMQP AppId），消费端据此跳过自己发布的命令
+const EventSourceProduct = "product"
+
+// ErrMQUnavailable MQ 未配置或连接不可用（重连中），发件箱消息保持待投递且不计入投递次数
+var ErrMQUnavailable = errors.New("mq publisher not available")
+
+// 发件箱消息状态
+const (
+	OutboxStatusPending = "PENDING" // 待投递
+	OutboxStatusSent    = "SENT"    // 已投递
+	OutboxStatusDead    = "DEAD"    // 超过最大投递次数，需人工处理
+)
+
+// OutboxMessage 发件箱消息
+// 与业务数据在同一事务中写入，由中继异步投递到 MQ，保证"写库成功则消息必达"
+type OutboxMessage struct {
+	ID            int64
+	EventType     string    // 事件类型（如 INSTANCE_CREATED）
+	RoutingKey    string    // MQ routing key
+	AggregateID   int64     // 聚合根 ID（实例 ID）
+	Payload       []byte    // 已序列化的消息体
+	Status        string    // PENDING, SENT, DEAD
+	Attempts      int32     // 已投递次数
+	NextAttemptAt time.Time // 下次可投递时间
+	LastError     string    // 最近一次投递失败原因
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// OutboxRepo 发件箱仓储接口
+type OutboxRepo interface {
+	// EnqueueInstanceEvent 写入实例事件，需在 Transaction.InTx 内调用才能与业务数据原子提交
+	EnqueueInstanceEvent(ctx context.Context, eventType string, spec InstanceSpec) error
+
+	// ClaimPending 认领到期的待投递消息，并把 next_attempt_at 推迟 lease，避免多副本重复投递
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+
+	// MarkSent 标记消息已投递
+	MarkSent(ctx context.Context, id int64) error
+
+	// MarkRetry 记录投递失败并安排下次重试
+	MarkRetry(ctx context.Context, id int64, attempts int32, nextAttemptAt time.Time, lastErr string) error
+
+	// MarkDead 记录投递失败并停止重试
+	MarkDead(ctx context.Context, id int64, attempts int32, lastErr string) error
+
+	// CountPending 统计待投递消息数
+	CountPending(ctx context.Context) (int64, error)
+}
+
+// OutboxOptions 中继投递参数
+type OutboxOptions struct {
+	BatchSize   int
+	MaxAttempts int32
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Lease       time.Duration
+}
+
+// RelayResult 单轮中继结果
+type RelayResult struct {
+	Sent    int // 投递成功
+	Retried int // 投递失败，等待重试
+	Dead    int // 投递失败，超过最大次数
+}
+
+// OutboxUsecase 发件箱中继业务逻辑
+type OutboxUsecase struct {
+	repo      OutboxRepo
+	publisher MQPublisher
+	log       *log.Helper
+}
+
+// NewOutboxUsecase 创建发件箱中继用例
+func NewOutboxUsecase(repo OutboxRepo, publisher MQPublisher, logger log.Logger) *OutboxUsecase {
+	return &OutboxUsecase{
+		repo:      repo,
+		publisher: publisher,
+		log:       log.NewHelper(logger),
+	}
+}
+
+// Relay 认领一批待投递消息并发布到 MQ
+func (uc *OutboxUsecase) Relay(ctx context.Context, opts OutboxOptions) (RelayResult, error) {
+	var result RelayResult
+
+	msgs, err := uc.repo.ClaimPending(ctx, opts.BatchSize, opts.Lease)
+	if err != nil {
+		uc.log.Errorf("claim outbox messages failed: %v", err)
+		return result, err
+	}
+
+	for _, msg := range msgs {
+		pubErr := uc.publisher.Publish(ctx, msg.RoutingKey, msg.Payload)
+		if pubErr == nil {
+			if err := uc.repo.MarkSent(ctx, msg.ID); err != nil {
+				// 消息已发出但状态未更新，租期过后会重复投递，下游需按事件幂等处理
+				uc.log.Errorf("mark outbox message sent failed: id=%d err=%v", msg.ID, err)
+				continue
+			}
+			result.Sent++
+			continue
+		}
+
+		// MQ 不可用不是消息本身的问题，不计入投递次数，避免 MQ 长时间中断后消息被标记为 DEAD
+		if errors.Is(pubErr, ErrMQUnavailable) {
+			next := time.Now().Add(opts.BaseBackoff)
+			if err := uc.repo.MarkRetry(ctx, msg.ID, msg.Attempts, next, pubErr.Error()); err != nil {
+				uc.log.Errorf("mark outbox message retry failed: id=%d err=%v", msg.ID, err)
+				continue
+			}
+			result.Retried++
+			continue
+		}
+
+		attempts := msg.Attempts + 1
+		if opts.MaxAttempts > 0 && attempts >= opts.MaxAttempts {
+			uc.log.Errorf("outbox message dead: id=%d eventType=%s aggregateID=%d attempts=%d err=%v",
+				msg.ID, msg.EventType, msg.AggregateID, attempts, pubErr)
+			if err := uc.repo.MarkDead(ctx, msg.ID, attempts, pubErr.Error()); err != nil {
+				uc.log.Errorf("mark outbox message dead failed: id=%d err=%v", msg.ID, err)
+				continue
+			}
+			result.Dead++
+			continue
+		}
+
+		next := time.Now().Add(OutboxBackoff(attempts, opts.BaseBackoff, opts.MaxBackoff))
+		uc.log.Warnf("outbox publish failed, retry at %s: id=%d attempts=%d err=%v",
+			next.Format(time.RFC3339), msg.ID, attempts, pubErr)
+		if err := uc.repo.MarkRetry(ctx, msg.ID, attempts, next, pubErr.Error()); err != nil {
+			uc.log.Errorf("mark outbox message retry failed: id=%d err=%v", msg.ID, err)
+			continue
+		}
+		result.Retried++
+	}
+
+	return result, nil
+}
+
+// CountPending 统计待投递消息数
+func (uc *OutboxUsecase) CountPending(ctx context.Context) (int64, error) {
+	return uc.repo.CountPending(ctx)
+}
+
+// OutboxBackoff 计算第 attempts 次失败后的退避时间（指数退避，上限 max）
+func OutboxBackoff(attempts int32, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	backoff := base
+	for i := int32(1); i < attempts; i++ {
+		backoff *= 2
+		if max > 0 && backoff >= max {
+			return max
+		}
+	}
+	if max > 0 && backoff > max {
+		return max
+	}
+	return backoff
+}
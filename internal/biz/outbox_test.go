@@ -0,0 +1,114 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeOutboxRepo struct {
+	enqueued []InstanceSpec
+	pending  []*OutboxMessage
+	sent     []int64
+	retried  map[int64]int32
+	dead     map[int64]int32
+}
+
+func (r *fakeOutboxRepo) EnqueueInstanceEvent(ctx context.Context, eventType string, spec InstanceSpec) error {
+	r.enqueued = append(r.enqueued, spec)
+	return nil
+}
+
+func (r *fakeOutboxRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	return r.pending, nil
+}
+
+func (r *fakeOutboxRepo) MarkSent(ctx context.Context, id int64) error {
+	r.sent = append(r.sent, id)
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkRetry(ctx context.Context, id int64, attempts int32, nextAttemptAt time.Time, lastErr string) error {
+	r.retried[id] = attempts
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkDead(ctx context.Context, id int64, attempts int32, lastErr string) error {
+	r.dead[id] = attempts
+	return nil
+}
+
+func (r *fakeOutboxRepo) CountPending(ctx context.Context) (int64, error) {
+	return int64(len(r.pending)), nil
+}
+
+type fakePublisher struct {
+	fail map[string]bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, routingKey string, body []byte) error {
+	if p.fail[string(body)] {
+		return errors.New("broker unavailable")
+	}
+	if string(body) == "down" {
+		return fmt.Errorf("%w: connection refused", ErrMQUnavailable)
+	}
+	return nil
+}
+
+func TestOutboxUsecase_Relay(t *testing.T) {
+	repo := &fakeOutboxRepo{
+		pending: []*OutboxMessage{
+			{ID: 1, RoutingKey: "instance.created", Payload: []byte("ok")},
+			{ID: 2, RoutingKey: "instance.created", Payload: []byte("bad"), Attempts: 0},
+			{ID: 3, RoutingKey: "instance.created", Payload: []byte("bad"), Attempts: 4},
+			{ID: 4, RoutingKey: "instance.created", Payload: []byte("down"), Attempts: 4},
+		},
+		retried: map[int64]int32{},
+		dead:    map[int64]int32{},
+	}
+	uc := NewOutboxUsecase(repo, &fakePublisher{fail: map[string]bool{"bad": true}}, log.DefaultLogger)
+
+	res, err := uc.Relay(context.Background(), OutboxOptions{BatchSize: 10, MaxAttempts: 5, BaseBackoff: time.Second})
+	if err != nil {
+		t.Fatalf("Relay() error = %v", err)
+	}
+	if res.Sent != 1 || res.Retried != 2 || res.Dead != 1 {
+		t.Fatalf("Relay() = %+v, want 1 sent, 2 retried, 1 dead", res)
+	}
+	if len(repo.sent) != 1 || repo.sent[0] != 1 {
+		t.Errorf("sent = %v, want [1]", repo.sent)
+	}
+	if repo.retried[2] != 1 {
+		t.Errorf("retried[2] = %d, want 1", repo.retried[2])
+	}
+	if repo.dead[3] != 5 {
+		t.Errorf("dead[3] = %d, want 5", repo.dead[3])
+	}
+	// MQ 不可用时不计入投递次数，不会被标记为 DEAD
+	if attempts, ok := repo.retried[4]; !ok || attempts != 4 {
+		t.Errorf("retried[4] = %d, want 4 (attempts unchanged)", attempts)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := OutboxBackoff(tt.attempts, time.Second, time.Minute); got != tt.want {
+			t.Errorf("OutboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
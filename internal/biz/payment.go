@@ -0,0 +1,207 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 支付状态
+const (
+	PaymentStatusRequiresConfirmation = "REQUIRES_CONFIRMATION" // 已创建，等待确认
+	PaymentStatusSucceeded            = "SUCCEEDED"             // 支付成功
+	PaymentStatusFailed               = "FAILED"                // 本次确认失败（如余额不足），可重新确认
+	PaymentStatusCanceled             = "CANCELED"              // 已撤销，不会再支付成功
+)
+
+// 支付错误
+var (
+	ErrPaymentNotFound   = &BizError{Code: 404, Message: "payment not found"}
+	ErrPaymentMismatch   = &BizError{Code: 409, Message: "payment does not belong to the order", Permanent: true}
+	ErrPaymentDeclined   = &BizError{Code: 402, Message: "payment declined"}
+	ErrInvalidRefund     = &BizError{Code: 400, Message: "invalid refund amount: must be between 1 and the unrefunded amount", Permanent: true}
+	ErrPaymentNotSettled = &BizError{Code: 409, Message: "payment has not succeeded"}
+)
+
+// PaymentIntent 支付意图（一笔订单对应一个）
+type PaymentIntent struct {
+	ID            string
+	OrderID       int64
+	UserID        string
+	Amount        int64  // 应付金额（分）
+	Refunded      int64  // 已退款金额（分）
+	Status        string // REQUIRES_CONFIRMATION / SUCCEEDED / FAILED / CANCELED
+	FailureReason string
+	CreatedAt     time.Time
+}
+
+// PaymentRefund 退款记录
+type PaymentRefund struct {
+	ID        string
+	PaymentID string
+	Amount    int64 // 退款金额（分）
+	CreatedAt time.Time
+}
+
+// PaymentGateway 支付网关接口，由 data 层按配置选择实现
+type PaymentGateway interface {
+	// CreateIntent 为订单创建支付意图，同一订单重复调用返回同一意图
+	CreateIntent(ctx context.Context, order *Order) (*PaymentIntent, error)
+	// Confirm 同步确认支付，返回确认后的意图（状态可能为 SUCCEEDED 或 FAILED）
+	Confirm(ctx context.Context, paymentID string) (*PaymentIntent, error)
+	// Refund 退款，amount 不能超过未退款金额；仅支付成功的意图可以退款
+	Refund(ctx context.Context, paymentID string, amount int64) (*PaymentRefund, error)
+	// Query 查询支付意图的最新状态，不存在时返回 ErrPaymentNotFound
+	Query(ctx context.Context, paymentID string) (*PaymentIntent, error)
+}
+
+// preparePayment 待支付订单还没有支付意图时创建（下单后网关调用失败的订单可通过幂等重试补建）
+func (uc *OrderUsecase) preparePayment(ctx context.Context, order *Order) error {
+	if order.Status != OrderStatusPending || order.PaymentID != "" {
+		return nil
+	}
+	intent, err := uc.payment.CreateIntent(ctx, order)
+	if err != nil {
+		uc.log.Errorf("create payment intent failed: orderID=%d err=%v", order.ID, err)
+		return err
+	}
+	if err := uc.orderRepo.SetPaymentID(ctx, order.ID, intent.ID); err != nil {
+		uc.log.Errorf("save payment id failed: orderID=%d paymentID=%s err=%v", order.ID, intent.ID, err)
+		return err
+	}
+	order.PaymentID = intent.ID
+	uc.log.Infof("payment intent created: orderID=%d paymentID=%s amount=%d", order.ID, intent.ID, intent.Amount)
+	return nil
+}
+
+// ConfirmPayment 同步确认订单支付；订单已支付时直接返回（与异步回调并发时幂等）
+// 支付失败时订单保持待支付，可重新确认，超时未支付由过期任务取消
+func (uc *OrderUsecase) ConfirmPayment(ctx context.Context, orderID int64) (*Order, error) {
+	order, err := uc.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case OrderStatusPaid, OrderStatusCompleted:
+		return order, nil
+	case OrderStatusPending:
+	default:
+		return nil, &OrderTransitionError{OrderID: orderID, From: order.Status, To: OrderStatusPaid}
+	}
+
+	if err := uc.preparePayment(ctx, order); err != nil {
+		return nil, err
+	}
+	intent, err := uc.payment.Confirm(ctx, order.PaymentID)
+	if err != nil {
+		uc.log.Errorf("confirm payment failed: orderID=%d paymentID=%s err=%v", orderID, order.PaymentID, err)
+		return nil, err
+	}
+
+	order, err = uc.applyPayment(ctx, order, intent)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status == PaymentStatusFailed {
+		uc.log.Warnf("payment declined: orderID=%d paymentID=%s reason=%s", orderID, intent.ID, intent.FailureReason)
+		return nil, ErrPaymentDeclined
+	}
+	return order, nil
+}
+
+// HandlePaymentWebhook 处理支付网关的异步通知
+// 回调内容只作为触发信号，支付状态以 Query 查询到的网关结果为准，伪造的回调无法把订单标记为已支付
+func (uc *OrderUsecase) HandlePaymentWebhook(ctx context.Context, paymentID string) (*Order, error) {
+	if paymentID == "" {
+		return nil, ErrPaymentNotFound
+	}
+	intent, err := uc.payment.Query(ctx, paymentID)
+	if err != nil {
+		uc.log.Errorf("query payment failed: paymentID=%s err=%v", paymentID, err)
+		return nil, err
+	}
+	order, err := uc.orderRepo.GetByID(ctx, intent.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentID != intent.ID {
+		uc.log.Errorf("payment does not belong to order: paymentID=%s orderID=%d orderPaymentID=%s",
+			intent.ID, order.ID, order.PaymentID)
+		return nil, ErrPaymentMismatch
+	}
+	uc.log.Infof("payment webhook: paymentID=%s orderID=%d status=%s", intent.ID, order.ID, intent.Status)
+	return uc.applyPayment(ctx, order, intent)
+}
+
+// applyPayment 按支付意图的状态推进订单
+func (uc *OrderUsecase) applyPayment(ctx context.Context, order *Order, intent *PaymentIntent) (*Order, error) {
+	switch intent.Status {
+	case PaymentStatusSucceeded:
+		return uc.markPaid(ctx, order, intent)
+	case PaymentStatusCanceled:
+		if order.Status == OrderStatusPending {
+			return uc.cancelPendingOrder(ctx, order)
+		}
+	}
+	return order, nil
+}
+
+// markPaid 支付成功：订单迁移为已支付，同一事务中写入实例记录和实例创建事件
+// 订单在待支付时被取消（如支付期间过期）时全额退款
+func (uc *OrderUsecase) markPaid(ctx context.Context, order *Order, intent *PaymentIntent) (*Order, error) {
+	if order.Status == OrderStatusPending {
+		draft, err := newInstanceDraft(order)
+		if err != nil {
+			return nil, err
+		}
+		err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+			if err := uc.orderRepo.UpdateStatus(ctx, order.ID, OrderStatusPending, OrderStatusPaid); err != nil {
+				return err
+			}
+			return uc.saveInstance(ctx, draft)
+		})
+		if err == nil {
+			uc.log.Infof("order paid, instance event enqueued: orderID=%d paymentID=%s instanceID=%d",
+				order.ID, intent.ID, order.InstanceID)
+			return uc.orderRepo.GetByID(ctx, order.ID)
+		}
+		if !errors.Is(err, ErrOrderStatusConflict) {
+			uc.log.Errorf("mark order paid failed: orderID=%d err=%v", order.ID, err)
+			return nil, err
+		}
+		// 并发的确认/回调已处理，或订单已被取消
+		if order, err = uc.orderRepo.GetByID(ctx, order.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 只有未支付即取消的订单（如支付期间过期）才全额退款；退款取消的订单已由 RefundOrder 退过款，
+	// 重放或迟到的回调不能再退还剩余金额
+	if order.Status == OrderStatusCancelled && order.Refund == nil && order.PaidAt == nil {
+		if remaining := intent.Amount - intent.Refunded; remaining > 0 {
+			if _, err := uc.payment.Refund(ctx, intent.ID, remaining); err != nil {
+				uc.log.Errorf("refund payment of cancelled order failed: orderID=%d paymentID=%s err=%v", order.ID, intent.ID, err)
+				return nil, err
+			}
+			uc.log.Warnf("payment succeeded after order was cancelled, refunded: orderID=%d paymentID=%s amount=%d",
+				order.ID, intent.ID, remaining)
+		}
+	}
+	return order, nil
+}
+
+// cancelPendingOrder 取消待支付订单并归还预占的库存
+func (uc *OrderUsecase) cancelPendingOrder(ctx context.Context, order *Order) (*Order, error) {
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := uc.orderRepo.UpdateStatus(ctx, order.ID, OrderStatusPending, OrderStatusCancelled); err != nil {
+			return err
+		}
+		return uc.releaseStock(ctx, order.ProductID, order.ID)
+	})
+	if err != nil {
+		uc.log.Errorf("cancel pending order failed: orderID=%d err=%v", order.ID, err)
+		return nil, err
+	}
+	uc.log.Infof("pending order cancelled: orderID=%d", order.ID)
+	return uc.orderRepo.GetByID(ctx, order.ID)
+}
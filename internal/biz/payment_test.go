@@ -0,0 +1,226 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// fakePaymentGateway 内存支付网关，decline 为 true 时确认支付失败，refundErr 非 nil 时退款失败，
+// queryErrs 中的支付意图查询失败
+type fakePaymentGateway struct {
+	intents   map[string]*PaymentIntent
+	refunds   []*PaymentRefund
+	decline   bool
+	refundErr error
+	queryErrs map[string]error
+}
+
+func newFakePaymentGateway() *fakePaymentGateway {
+	return &fakePaymentGateway{intents: map[string]*PaymentIntent{}}
+}
+
+func (g *fakePaymentGateway) CreateIntent(ctx context.Context, order *Order) (*PaymentIntent, error) {
+	id := fmt.Sprintf("pi_%d", order.ID)
+	if _, ok := g.intents[id]; !ok {
+		g.intents[id] = &PaymentIntent{ID: id, OrderID: order.ID, UserID: order.UserID, Amount: order.Amount,
+			Status: PaymentStatusRequiresConfirmation}
+	}
+	copied := *g.intents[id]
+	return &copied, nil
+}
+
+func (g *fakePaymentGateway) Confirm(ctx context.Context, paymentID string) (*PaymentIntent, error) {
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	if intent.Status != PaymentStatusSucceeded {
+		intent.Status = PaymentStatusSucceeded
+		if g.decline {
+			intent.Status, intent.FailureReason = PaymentStatusFailed, "insufficient funds"
+		}
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (g *fakePaymentGateway) Refund(ctx context.Context, paymentID string, amount int64) (*PaymentRefund, error) {
+	if g.refundErr != nil {
+		return nil, g.refundErr
+	}
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	if amount <= 0 || amount > intent.Amount-intent.Refunded {
+		return nil, ErrInvalidRefund
+	}
+	intent.Refunded += amount
+	refund := &PaymentRefund{ID: fmt.Sprintf("re_%d", len(g.refunds)+1), PaymentID: paymentID, Amount: amount}
+	g.refunds = append(g.refunds, refund)
+	return refund, nil
+}
+
+func (g *fakePaymentGateway) Query(ctx context.Context, paymentID string) (*PaymentIntent, error) {
+	if err := g.queryErrs[paymentID]; err != nil {
+		return nil, err
+	}
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+type paymentFixture struct {
+	uc        *OrderUsecase
+	orders    *fakeOrderRepo
+	instances *fakeInstanceRepo
+	outbox    *fakeOutboxRepo
+	inventory *fakeInventoryRepo
+	gateway   *fakePaymentGateway
+}
+
+func newPaymentFixture() *paymentFixture {
+	f := &paymentFixture{
+		orders:    &fakeOrderRepo{},
+		instances: &fakeInstanceRepo{},
+		outbox:    &fakeOutboxRepo{},
+		inventory: &fakeInventoryRepo{stock: map[int64]int32{1001: 5}},
+		gateway:   newFakePaymentGateway(),
+	}
+	products := &fakeProductRepo{product: &Product{
+		ID: 1001, Name: "GPU", Status: ProductStatusEnabled, Price: 1000,
+		Spec: &ProductSpec{ID: 1, CPU: 4, Memory: 8192, GPU: 1, Image: "ubuntu:22.04"},
+	}}
+	ids := &fakeIDGenerator{next: 100}
+	f.uc = NewOrderUsecase(f.orders, products, f.instances, f.outbox, f.inventory, f.gateway, fakeTx{}, ids, ids, log.DefaultLogger)
+	return f
+}
+
+func TestOrderUsecase_PurchaseProduct_Pending(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+
+	order, instanceID, _, err := f.uc.PurchaseProduct(ctx, "u1", 1001, "")
+	if err != nil {
+		t.Fatalf("purchase: err = %v", err)
+	}
+	if order.Status != OrderStatusPending || order.PaidAt != nil || order.PaymentID == "" || instanceID == 0 {
+		t.Errorf("order = %+v instanceID = %d, want a pending order with a payment intent", order, instanceID)
+	}
+	if len(f.instances.created) != 0 || len(f.outbox.enqueued) != 0 {
+		t.Errorf("instances = %d events = %d before payment, want 0 0", len(f.instances.created), len(f.outbox.enqueued))
+	}
+
+	paid, err := f.uc.ConfirmPayment(ctx, order.ID)
+	if err != nil || paid.Status != OrderStatusPaid {
+		t.Fatalf("confirm: order = %+v err = %v, want paid", paid, err)
+	}
+	if len(f.instances.created) != 1 || f.instances.created[0].InstanceID != instanceID {
+		t.Errorf("instances = %+v, want instance %d", f.instances.created, instanceID)
+	}
+	if len(f.outbox.enqueued) != 1 || f.outbox.enqueued[0].InstanceID != instanceID || f.outbox.enqueued[0].CPU != 4 {
+		t.Errorf("events = %+v, want one for instance %d with the snapshot spec", f.outbox.enqueued, instanceID)
+	}
+
+	// 同步确认与异步回调重复到达时不会重复创建实例
+	if _, err := f.uc.ConfirmPayment(ctx, order.ID); err != nil {
+		t.Errorf("confirm again: err = %v", err)
+	}
+	if got, err := f.uc.HandlePaymentWebhook(ctx, order.PaymentID); err != nil || got.Status != OrderStatusPaid {
+		t.Errorf("webhook after confirm: order = %+v err = %v", got, err)
+	}
+	if len(f.instances.created) != 1 || len(f.outbox.enqueued) != 1 {
+		t.Errorf("instances = %d events = %d after duplicate confirmations, want 1 1", len(f.instances.created), len(f.outbox.enqueued))
+	}
+}
+
+func TestOrderUsecase_PaymentWebhook(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+
+	order, _, _, err := f.uc.PurchaseProduct(ctx, "u1", 1001, "")
+	if err != nil {
+		t.Fatalf("purchase: err = %v", err)
+	}
+
+	// 回调只是触发信号：网关中支付尚未成功时订单保持待支付
+	if got, err := f.uc.HandlePaymentWebhook(ctx, order.PaymentID); err != nil || got.Status != OrderStatusPending {
+		t.Errorf("webhook before settlement: order = %+v err = %v, want pending", got, err)
+	}
+	if _, err := f.uc.HandlePaymentWebhook(ctx, "pi_unknown"); err != ErrPaymentNotFound {
+		t.Errorf("unknown payment: err = %v, want %v", err, ErrPaymentNotFound)
+	}
+
+	f.gateway.intents[order.PaymentID].Status = PaymentStatusSucceeded
+	if got, err := f.uc.HandlePaymentWebhook(ctx, order.PaymentID); err != nil || got.Status != OrderStatusPaid {
+		t.Errorf("webhook after settlement: order = %+v err = %v, want paid", got, err)
+	}
+	if len(f.outbox.enqueued) != 1 {
+		t.Errorf("events = %d, want 1", len(f.outbox.enqueued))
+	}
+
+	// 支付被撤销：取消订单并归还库存
+	revoked, _, _, _ := f.uc.PurchaseProduct(ctx, "u2", 1001, "")
+	f.gateway.intents[revoked.PaymentID].Status = PaymentStatusCanceled
+	if got, err := f.uc.HandlePaymentWebhook(ctx, revoked.PaymentID); err != nil || got.Status != OrderStatusCancelled {
+		t.Errorf("canceled payment: order = %+v err = %v, want cancelled", got, err)
+	}
+	if f.inventory.stock[1001] != 4 {
+		t.Errorf("stock = %d, want 4 (one paid, one released)", f.inventory.stock[1001])
+	}
+
+	// 订单取消后支付才成功：全额退款
+	late, _, _, _ := f.uc.PurchaseProduct(ctx, "u3", 1001, "")
+	if _, err := f.uc.CancelOrder(ctx, "u3", late.ID); err != nil {
+		t.Fatalf("cancel: err = %v", err)
+	}
+	f.gateway.intents[late.PaymentID].Status = PaymentStatusSucceeded
+	if got, err := f.uc.HandlePaymentWebhook(ctx, late.PaymentID); err != nil || got.Status != OrderStatusCancelled {
+		t.Errorf("late payment: order = %+v err = %v, want cancelled", got, err)
+	}
+	if len(f.gateway.refunds) != 1 || f.gateway.refunds[0].Amount != late.Amount {
+		t.Errorf("refunds = %+v, want a full refund of %d", f.gateway.refunds, late.Amount)
+	}
+	if _, err := f.uc.HandlePaymentWebhook(ctx, late.PaymentID); err != nil || len(f.gateway.refunds) != 1 {
+		t.Errorf("repeated late payment webhook: refunds = %d err = %v, want no second refund", len(f.gateway.refunds), err)
+	}
+}
+
+func TestOrderUsecase_ConfirmPayment_Declined(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+	f.gateway.decline = true
+
+	order, _, _, err := f.uc.PurchaseProduct(ctx, "u1", 1001, "")
+	if err != nil {
+		t.Fatalf("purchase: err = %v", err)
+	}
+	if _, err := f.uc.ConfirmPayment(ctx, order.ID); err != ErrPaymentDeclined {
+		t.Errorf("declined: err = %v, want %v", err, ErrPaymentDeclined)
+	}
+	if order.Status != OrderStatusPending || len(f.instances.created) != 0 {
+		t.Errorf("declined: status = %s instances = %d, want pending without instance", order.Status, len(f.instances.created))
+	}
+
+	// 支付失败后可重新确认
+	f.gateway.decline = false
+	if paid, err := f.uc.ConfirmPayment(ctx, order.ID); err != nil || paid.Status != OrderStatusPaid {
+		t.Errorf("retry: order = %+v err = %v, want paid", paid, err)
+	}
+
+	cancelled, _, _, _ := f.uc.PurchaseProduct(ctx, "u2", 1001, "")
+	if _, err := f.uc.CancelOrder(ctx, "u2", cancelled.ID); err != nil {
+		t.Fatalf("cancel: err = %v", err)
+	}
+	var transitionErr *OrderTransitionError
+	if _, err := f.uc.ConfirmPayment(ctx, cancelled.ID); !errors.As(err, &transitionErr) {
+		t.Errorf("confirm cancelled order: err = %v, want OrderTransitionError", err)
+	}
+}
@@ -3,6 +3,7 @@ package biz
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/go-kratos/kratos/v2/log"
 )
@@ -14,6 +15,23 @@ var (
 	ErrInvalidPrice        = errors.New("price must be greater than 0")
 	ErrInvalidSpec         = errors.New("cpu and memory must be greater than 0")
 	ErrImageRequired       = errors.New("image is required")
+	ErrInvalidProductID    = errors.New("invalid product id")
+	ErrInvalidStatus       = errors.New("status must be ENABLED or DISABLED")
+	ErrEmptyUpdateMask     = errors.New("update mask is required")
+	ErrUnsupportedField    = errors.New("unsupported update field")
+)
+
+// 商品状态
+const (
+	ProductStatusEnabled  = "ENABLED"
+	ProductStatusDisabled = "DISABLED"
+)
+
+// 可通过 UpdateProduct 修改的字段（规格是值对象，不允许原地修改）
+const (
+	ProductFieldName        = "name"
+	ProductFieldDescription = "description"
+	ProductFieldPrice       = "price"
 )
 
 // ProductSortBy defines sorting fields for product listing.
@@ -48,11 +66,20 @@ type ProductFilter struct {
 	PageSize  uint32
 }
 
-// ProductRepo provides access to products for listing.
+// ProductRepo provides access to products.
 type ProductRepo interface {
 	GetByID(ctx context.Context, productID int64) (*Product, error)
 	List(ctx context.Context, filter ProductFilter) ([]*Product, int64, error)
 	Create(ctx context.Context, product *Product) error
+	// Update writes only the given fields of product.
+	Update(ctx context.Context, product *Product, fields []string) error
+	UpdateStatus(ctx context.Context, productID int64, status string) error
+	// Delete soft deletes a product; orders keep referencing it.
+	Delete(ctx context.Context, productID int64) error
+	// ChangeSpec creates a new spec and repoints the product to it atomically.
+	ChangeSpec(ctx context.Context, productID int64, spec *ProductSpec) (*ProductSpecVersion, error)
+	// ListSpecHistory returns all spec versions of a product, newest first.
+	ListSpecHistory(ctx context.Context, productID int64) ([]*ProductSpecVersion, error)
 }
 
 // ProductUsecase handles product queries.
@@ -88,17 +115,132 @@ func (uc *ProductUsecase) CreateProduct(ctx context.Context, product *Product) e
 	if product.Price <= 0 {
 		return ErrInvalidPrice
 	}
-	if product.Spec.CPU <= 0 || product.Spec.Memory <= 0 {
-		return ErrInvalidSpec
-	}
-	if product.Spec.Image == "" {
-		return ErrImageRequired
+	if err := validateSpec(product.Spec); err != nil {
+		return err
 	}
 
 	// 默认状态为启用
 	if product.Status == "" {
-		product.Status = "ENABLED"
+		product.Status = ProductStatusEnabled
 	}
 
 	return uc.repo.Create(ctx, product)
 }
+
+// GetProduct returns a product with its spec.
+func (uc *ProductUsecase) GetProduct(ctx context.Context, productID int64) (*Product, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	return uc.repo.GetByID(ctx, productID)
+}
+
+// UpdateProduct updates the fields listed in paths and returns the updated product.
+func (uc *ProductUsecase) UpdateProduct(ctx context.Context, product *Product, paths []string) (*Product, error) {
+	if product == nil {
+		return nil, ErrInvalidProduct
+	}
+	if product.ID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if len(paths) == 0 {
+		return nil, ErrEmptyUpdateMask
+	}
+
+	for _, path := range paths {
+		switch path {
+		case ProductFieldName:
+			if product.Name == "" {
+				return nil, ErrProductNameRequired
+			}
+		case ProductFieldDescription:
+		case ProductFieldPrice:
+			if product.Price <= 0 {
+				return nil, ErrInvalidPrice
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedField, path)
+		}
+	}
+
+	if err := uc.repo.Update(ctx, product, paths); err != nil {
+		uc.log.Errorf("update product failed: productID=%d err=%v", product.ID, err)
+		return nil, err
+	}
+	return uc.repo.GetByID(ctx, product.ID)
+}
+
+// SetProductStatus enables or disables a product.
+func (uc *ProductUsecase) SetProductStatus(ctx context.Context, productID int64, status string) (*Product, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if status != ProductStatusEnabled && status != ProductStatusDisabled {
+		return nil, ErrInvalidStatus
+	}
+
+	if err := uc.repo.UpdateStatus(ctx, productID, status); err != nil {
+		uc.log.Errorf("update product status failed: productID=%d status=%s err=%v", productID, status, err)
+		return nil, err
+	}
+	uc.log.Infof("product status changed: productID=%d status=%s", productID, status)
+	return uc.repo.GetByID(ctx, productID)
+}
+
+// DeleteProduct soft deletes a product.
+func (uc *ProductUsecase) DeleteProduct(ctx context.Context, productID int64) error {
+	if productID <= 0 {
+		return ErrInvalidProductID
+	}
+
+	if err := uc.repo.Delete(ctx, productID); err != nil {
+		uc.log.Errorf("delete product failed: productID=%d err=%v", productID, err)
+		return err
+	}
+	uc.log.Infof("product deleted: productID=%d", productID)
+	return nil
+}
+
+// ChangeProductSpec re-specs a product. The old spec is kept for historic orders.
+func (uc *ProductUsecase) ChangeProductSpec(ctx context.Context, productID int64, spec *ProductSpec) (*Product, *ProductSpecVersion, error) {
+	if productID <= 0 {
+		return nil, nil, ErrInvalidProductID
+	}
+	if spec == nil {
+		return nil, nil, ErrInvalidProductSpec
+	}
+	if err := validateSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	version, err := uc.repo.ChangeSpec(ctx, productID, spec)
+	if err != nil {
+		uc.log.Errorf("change product spec failed: productID=%d err=%v", productID, err)
+		return nil, nil, err
+	}
+	uc.log.Infof("product spec changed: productID=%d specID=%d version=%d", productID, version.Spec.ID, version.Version)
+
+	product, err := uc.repo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, version, nil
+}
+
+// ListProductSpecHistory returns the spec versions a product has been sold under.
+func (uc *ProductUsecase) ListProductSpecHistory(ctx context.Context, productID int64) ([]*ProductSpecVersion, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	return uc.repo.ListSpecHistory(ctx, productID)
+}
+
+func validateSpec(spec *ProductSpec) error {
+	if spec.CPU <= 0 || spec.Memory <= 0 {
+		return ErrInvalidSpec
+	}
+	if spec.Image == "" {
+		return ErrImageRequired
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// fakeCatalogRepo 内存商品仓储，按版本保存每个商品的规格历史（旧版本在前）
+type fakeCatalogRepo struct {
+	products map[int64]*Product
+	versions map[int64][]*ProductSpecVersion
+	nextSpec int64
+	calls    int // 写操作次数
+}
+
+func newFakeCatalogRepo(products ...*Product) *fakeCatalogRepo {
+	r := &fakeCatalogRepo{products: map[int64]*Product{}, versions: map[int64][]*ProductSpecVersion{}, nextSpec: 100}
+	for _, p := range products {
+		r.products[p.ID] = p
+	}
+	return r
+}
+
+func (r *fakeCatalogRepo) GetByID(ctx context.Context, productID int64) (*Product, error) {
+	p, ok := r.products[productID]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeCatalogRepo) List(ctx context.Context, filter ProductFilter) ([]*Product, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeCatalogRepo) Create(ctx context.Context, product *Product) error {
+	r.calls++
+	r.products[product.ID] = product
+	return nil
+}
+
+func (r *fakeCatalogRepo) Update(ctx context.Context, product *Product, fields []string) error {
+	r.calls++
+	p, ok := r.products[product.ID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	for _, field := range fields {
+		switch field {
+		case ProductFieldName:
+			p.Name = product.Name
+		case ProductFieldDescription:
+			p.Description = product.Description
+		case ProductFieldPrice:
+			p.Price = product.Price
+		}
+	}
+	return nil
+}
+
+func (r *fakeCatalogRepo) UpdateStatus(ctx context.Context, productID int64, status string) error {
+	r.calls++
+	p, ok := r.products[productID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	p.Status = status
+	return nil
+}
+
+func (r *fakeCatalogRepo) Delete(ctx context.Context, productID int64) error {
+	r.calls++
+	if _, ok := r.products[productID]; !ok {
+		return ErrProductNotFound
+	}
+	delete(r.products, productID)
+	return nil
+}
+
+func (r *fakeCatalogRepo) ChangeSpec(ctx context.Context, productID int64, spec *ProductSpec) (*ProductSpecVersion, error) {
+	r.calls++
+	p, ok := r.products[productID]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	history := r.versions[productID]
+	if len(history) == 0 {
+		// 没有版本记录的旧商品：当前规格补记为版本 1
+		history = []*ProductSpecVersion{{ProductID: productID, Version: 1, Spec: p.Spec, EffectiveFrom: p.CreatedAt}}
+	}
+	now := time.Now()
+	history[len(history)-1].EffectiveTo = &now
+
+	r.nextSpec++
+	created := *spec
+	created.ID = r.nextSpec
+	version := &ProductSpecVersion{
+		ProductID:     productID,
+		Version:       history[len(history)-1].Version + 1,
+		Spec:          &created,
+		EffectiveFrom: now,
+	}
+	r.versions[productID] = append(history, version)
+	p.SpecID, p.Spec = created.ID, &created
+	return version, nil
+}
+
+func (r *fakeCatalogRepo) ListSpecHistory(ctx context.Context, productID int64) ([]*ProductSpecVersion, error) {
+	history := r.versions[productID]
+	versions := make([]*ProductSpecVersion, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		versions = append(versions, history[i])
+	}
+	return versions, nil
+}
+
+func testCatalogProduct() *Product {
+	spec := &ProductSpec{ID: 10, CPU: 2, Memory: 4096, Image: "ubuntu:22.04"}
+	return &Product{ID: 1, Name: "basic", Description: "2C4G", Status: ProductStatusEnabled, Price: 1000, SpecID: spec.ID, Spec: spec}
+}
+
+func TestValidateSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    ProductSpec
+		wantErr error
+	}{
+		{name: "valid", spec: ProductSpec{CPU: 1, Memory: 512, Image: "ubuntu:22.04"}},
+		{name: "valid with gpu", spec: ProductSpec{CPU: 8, Memory: 32768, GPU: 1, Image: "cuda:12"}},
+		{name: "zero cpu", spec: ProductSpec{Memory: 512, Image: "ubuntu:22.04"}, wantErr: ErrInvalidSpec},
+		{name: "negative memory", spec: ProductSpec{CPU: 1, Memory: -1, Image: "ubuntu:22.04"}, wantErr: ErrInvalidSpec},
+		{name: "missing image", spec: ProductSpec{CPU: 1, Memory: 512}, wantErr: ErrImageRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSpec(&tt.spec); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateSpec() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProductUsecase_CreateProduct_DefaultsToEnabled(t *testing.T) {
+	repo := newFakeCatalogRepo()
+	uc := NewProductUsecase(repo, log.DefaultLogger)
+
+	product := &Product{ID: 2, Name: "gpu", Price: 5000, Spec: &ProductSpec{CPU: 8, Memory: 32768, GPU: 1, Image: "cuda:12"}}
+	if err := uc.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("CreateProduct() error = %v", err)
+	}
+	if product.Status != ProductStatusEnabled {
+		t.Errorf("status = %q, want %q", product.Status, ProductStatusEnabled)
+	}
+
+	invalid := &Product{ID: 3, Name: "bad", Price: 5000, Spec: &ProductSpec{CPU: 8, Memory: 32768}}
+	if err := uc.CreateProduct(context.Background(), invalid); !errors.Is(err, ErrImageRequired) {
+		t.Errorf("CreateProduct() invalid spec error = %v, want %v", err, ErrImageRequired)
+	}
+}
+
+func TestProductUsecase_UpdateProduct(t *testing.T) {
+	tests := []struct {
+		name    string
+		product *Product
+		paths   []string
+		wantErr error
+		want    func(*Product) bool
+	}{
+		{name: "nil product", paths: []string{ProductFieldName}, wantErr: ErrInvalidProduct},
+		{name: "invalid id", product: &Product{Name: "x"}, paths: []string{ProductFieldName}, wantErr: ErrInvalidProductID},
+		{name: "empty mask", product: &Product{ID: 1, Name: "x"}, wantErr: ErrEmptyUpdateMask},
+		{name: "empty name", product: &Product{ID: 1}, paths: []string{ProductFieldName}, wantErr: ErrProductNameRequired},
+		{name: "zero price", product: &Product{ID: 1}, paths: []string{ProductFieldPrice}, wantErr: ErrInvalidPrice},
+		{name: "spec not updatable", product: &Product{ID: 1}, paths: []string{"spec"}, wantErr: ErrUnsupportedField},
+		{name: "status not updatable", product: &Product{ID: 1}, paths: []string{"status"}, wantErr: ErrUnsupportedField},
+		{name: "not found", product: &Product{ID: 9, Name: "x"}, paths: []string{ProductFieldName}, wantErr: ErrProductNotFound},
+		{
+			name:    "only masked fields are written",
+			product: &Product{ID: 1, Name: "renamed", Price: 1},
+			paths:   []string{ProductFieldName},
+			want:    func(p *Product) bool { return p.Name == "renamed" && p.Price == 1000 && p.Description == "2C4G" },
+		},
+		{
+			name:    "description can be cleared",
+			product: &Product{ID: 1, Price: 2000},
+			paths:   []string{ProductFieldDescription, ProductFieldPrice},
+			want:    func(p *Product) bool { return p.Name == "basic" && p.Price == 2000 && p.Description == "" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeCatalogRepo(testCatalogProduct())
+			uc := NewProductUsecase(repo, log.DefaultLogger)
+
+			got, err := uc.UpdateProduct(context.Background(), tt.product, tt.paths)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProduct() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if tt.wantErr != ErrProductNotFound && repo.calls != 0 {
+					t.Errorf("repo writes = %d, want 0 for invalid input", repo.calls)
+				}
+				return
+			}
+			if !tt.want(got) {
+				t.Errorf("UpdateProduct() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestProductUsecase_SetProductStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID int64
+		status    string
+		wantErr   error
+	}{
+		{name: "disable", productID: 1, status: ProductStatusDisabled},
+		{name: "enable", productID: 1, status: ProductStatusEnabled},
+		{name: "unspecified", productID: 1, status: "", wantErr: ErrInvalidStatus},
+		{name: "unknown", productID: 1, status: "enabled", wantErr: ErrInvalidStatus},
+		{name: "invalid id", productID: 0, status: ProductStatusDisabled, wantErr: ErrInvalidProductID},
+		{name: "not found", productID: 9, status: ProductStatusDisabled, wantErr: ErrProductNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := testCatalogProduct()
+			product.Status = ProductStatusDisabled
+			if tt.status == ProductStatusDisabled {
+				product.Status = ProductStatusEnabled
+			}
+			initial := product.Status
+			repo := newFakeCatalogRepo(product)
+			uc := NewProductUsecase(repo, log.DefaultLogger)
+
+			got, err := uc.SetProductStatus(context.Background(), tt.productID, tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetProductStatus() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if repo.calls != 0 && tt.wantErr != ErrProductNotFound {
+					t.Errorf("repo writes = %d, want 0 for invalid input", repo.calls)
+				}
+				if stored := repo.products[1].Status; stored != initial {
+					t.Errorf("status = %q after rejected change, want %q", stored, initial)
+				}
+				return
+			}
+			if got.Status != tt.status {
+				t.Errorf("SetProductStatus() status = %q, want %q", got.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestProductUsecase_DeleteProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID int64
+		wantErr   error
+	}{
+		{name: "delete", productID: 1},
+		{name: "invalid id", productID: -1, wantErr: ErrInvalidProductID},
+		{name: "not found", productID: 9, wantErr: ErrProductNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeCatalogRepo(testCatalogProduct())
+			uc := NewProductUsecase(repo, log.DefaultLogger)
+
+			if err := uc.DeleteProduct(context.Background(), tt.productID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteProduct() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := repo.products[1]; ok == (tt.wantErr == nil) {
+				t.Errorf("product 1 exists = %v after DeleteProduct(%d)", ok, tt.productID)
+			}
+		})
+	}
+}
+
+func TestProductUsecase_ChangeProductSpec(t *testing.T) {
+	validSpec := &ProductSpec{CPU: 4, Memory: 8192, Image: "ubuntu:24.04"}
+	tests := []struct {
+		name      string
+		productID int64
+		spec      *ProductSpec
+		wantErr   error
+	}{
+		{name: "invalid id", productID: 0, spec: validSpec, wantErr: ErrInvalidProductID},
+		{name: "nil spec", productID: 1, wantErr: ErrInvalidProductSpec},
+		{name: "invalid spec", productID: 1, spec: &ProductSpec{CPU: 4, Image: "ubuntu:24.04"}, wantErr: ErrInvalidSpec},
+		{name: "missing image", productID: 1, spec: &ProductSpec{CPU: 4, Memory: 8192}, wantErr: ErrImageRequired},
+		{name: "not found", productID: 9, spec: validSpec, wantErr: ErrProductNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeCatalogRepo(testCatalogProduct())
+			uc := NewProductUsecase(repo, log.DefaultLogger)
+
+			if _, _, err := uc.ChangeProductSpec(context.Background(), tt.productID, tt.spec); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeProductSpec() error = %v, want %v", err, tt.wantErr)
+			}
+			if repo.products[1].SpecID != 10 {
+				t.Errorf("spec id = %d after rejected change, want 10", repo.products[1].SpecID)
+			}
+		})
+	}
+}
+
+func TestProductUsecase_ChangeProductSpec_Versioning(t *testing.T) {
+	ctx := context.Background()
+	original := testCatalogProduct()
+	oldSpec := *original.Spec
+	repo := newFakeCatalogRepo(original)
+	uc := NewProductUsecase(repo, log.DefaultLogger)
+
+	// 未记录版本的商品第一次变更：原规格为版本 1，新规格为版本 2
+	product, version, err := uc.ChangeProductSpec(ctx, 1, &ProductSpec{CPU: 4, Memory: 8192, Image: "ubuntu:24.04"})
+	if err != nil {
+		t.Fatalf("ChangeProductSpec() error = %v", err)
+	}
+	if version.Version != 2 || product.SpecID != version.Spec.ID || product.Spec.CPU != 4 {
+		t.Fatalf("ChangeProductSpec() = product spec %d cpu %d, version %d spec %d, want version 2 repointed",
+			product.SpecID, product.Spec.CPU, version.Version, version.Spec.ID)
+	}
+
+	_, version, err = uc.ChangeProductSpec(ctx, 1, &ProductSpec{CPU: 8, Memory: 16384, Image: "ubuntu:24.04"})
+	if err != nil || version.Version != 3 {
+		t.Fatalf("ChangeProductSpec() second = %+v, %v, want version 3", version, err)
+	}
+
+	history, err := uc.ListProductSpecHistory(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListProductSpecHistory() error = %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("history = %d versions, want 3", len(history))
+	}
+	// 最新版本在前，只有当前版本没有失效时间；旧规格保持不变，供历史订单引用
+	for i, want := range []int32{3, 2, 1} {
+		if history[i].Version != want {
+			t.Errorf("history[%d].Version = %d, want %d", i, history[i].Version, want)
+		}
+		if current := history[i].EffectiveTo == nil; current != (i == 0) {
+			t.Errorf("history[%d] current = %v, want %v", i, current, i == 0)
+		}
+	}
+	if got := *history[2].Spec; got.ID != oldSpec.ID || got.CPU != oldSpec.CPU || got.Memory != oldSpec.Memory || got.Image != oldSpec.Image {
+		t.Errorf("version 1 spec = %+v, want unchanged %+v", got, oldSpec)
+	}
+
+	if _, err := uc.ListProductSpecHistory(ctx, 0); !errors.Is(err, ErrInvalidProductID) {
+		t.Errorf("ListProductSpecHistory(0) error = %v, want %v", err, ErrInvalidProductID)
+	}
+}
@@ -0,0 +1,199 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+// RefundServicePeriod 订单金额对应的服务周期，按比例退款时以支付后已使用的时长折算
+const RefundServicePeriod = 30 * 24 * time.Hour
+
+// RefundReasonCancelled 用户取消已支付订单时记录的退款原因
+const RefundReasonCancelled = "cancelled by user"
+
+// maxRefundReasonLen 退款原因最大长度（字符）
+const maxRefundReasonLen = 256
+
+// RefundGatewayTimeout 退款时单次调用支付网关的超时时间；网关调用在持有订单行锁的事务内进行，超时限制锁的持有时长
+const RefundGatewayTimeout = 10 * time.Second
+
+// 退款错误
+var (
+	ErrInvalidRefundReason = &BizError{Code: 400, Message: "invalid refund reason: must be 1-256 characters", Permanent: true}
+	ErrOrderNotRefundable  = &BizError{Code: 409, Message: "only paid or completed orders can be refunded"}
+)
+
+// OrderRefund 订单退款记录（退款后订单迁移为 CANCELLED）
+type OrderRefund struct {
+	Amount     int64  // 退款金额（分）
+	Reason     string // 退款原因
+	RefundID   string // 支付网关退款单号（没有支付意图的订单为空）
+	Manual     bool   // 没有支付意图（秒杀订单），金额未经网关退还，需要人工退款
+	RefundedAt time.Time
+}
+
+// ProratedRefund 按服务周期内未使用的时长折算退款金额（向下取整到分）
+// 使用时长从支付时间算起，超过服务周期时退款为 0
+func ProratedRefund(amount int64, paidAt, now time.Time) int64 {
+	used := now.Sub(paidAt)
+	if used < 0 {
+		used = 0
+	}
+	if used >= RefundServicePeriod {
+		return 0
+	}
+	remaining := int64(RefundServicePeriod - used)
+	// amount 与纳秒直接相乘可能溢出，按秒折算
+	return amount * (remaining / int64(time.Second)) / int64(RefundServicePeriod/time.Second)
+}
+
+// RefundOrder 用户为自己已支付（或已完成）的订单申请退款，见 refundOrder
+func (uc *OrderUsecase) RefundOrder(ctx context.Context, userID string, orderID int64, amount int64, reason string) (*Order, error) {
+	if _, err := uc.getOwnedOrder(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+	return uc.refundOrder(ctx, orderID, amount, reason)
+}
+
+// refundOrder 退款并取消已支付（或已完成）的订单，同时下发删除实例
+// amount 为 0 时按支付后的使用时长比例退款，大于 0 时为指定金额的部分退款（不能超过订单金额）
+// 整个退款在锁定订单行的事务中完成：并发的退款串行执行，后到的请求看到订单已取消，不会重复退款；
+// 网关退款后事务提交失败时，重试以网关中已退款的金额为准只补退差额。
+// 同一事务中记录退款、取消订单、归还库存并通过发件箱下发 INSTANCE_DELETED。
+// 没有支付意图的订单（秒杀）无法经网关退款，记录退款金额并标记为需要人工退款（Manual）
+func (uc *OrderUsecase) refundOrder(ctx context.Context, orderID int64, amount int64, reason string) (*Order, error) {
+	if reason == "" || utf8.RuneCountInString(reason) > maxRefundReasonLen {
+		return nil, ErrInvalidRefundReason
+	}
+	if amount < 0 {
+		return nil, ErrInvalidRefund
+	}
+
+	var refund *OrderRefund
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		order, err := uc.orderRepo.LockByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != OrderStatusPaid && order.Status != OrderStatusCompleted {
+			return ErrOrderNotRefundable
+		}
+		if amount > order.Amount {
+			return ErrInvalidRefund
+		}
+
+		// 实例有未完成的操作时先拒绝，避免钱已退出但实例无法删除
+		instance, err := uc.instanceToDelete(ctx, order)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		refund = &OrderRefund{Amount: amount, Reason: reason, RefundedAt: now}
+		if amount == 0 {
+			paidAt := order.CreatedAt
+			if order.PaidAt != nil {
+				paidAt = *order.PaidAt
+			}
+			refund.Amount = ProratedRefund(order.Amount, paidAt, now)
+		}
+		if order.PaymentID == "" {
+			refund.Manual = true
+			uc.log.Warnf("order has no payment intent, manual refund required: orderID=%d amount=%d", order.ID, refund.Amount)
+		} else if refund.RefundID, err = uc.refundPayment(ctx, order, refund.Amount); err != nil {
+			return err
+		}
+
+		if err := uc.orderRepo.MarkRefunded(ctx, order.ID, order.Status, refund); err != nil {
+			return err
+		}
+		if err := uc.releaseStock(ctx, order.ProductID, order.ID); err != nil {
+			return err
+		}
+		if instance == nil {
+			return nil
+		}
+		return uc.deleteInstance(ctx, instance, now)
+	})
+	if err != nil {
+		uc.log.Errorf("refund order failed: orderID=%d amount=%d err=%v", orderID, amount, err)
+		return nil, err
+	}
+	uc.log.Infof("order refunded: orderID=%d amount=%d refundID=%s reason=%s",
+		orderID, refund.Amount, refund.RefundID, reason)
+
+	return uc.orderRepo.GetByID(ctx, orderID)
+}
+
+// instanceToDelete 查询订单关联的实例，已删除（或删除中）时返回 nil；实例有其他未超时的待处理操作时返回 ErrInstanceBusy
+func (uc *OrderUsecase) instanceToDelete(ctx context.Context, order *Order) (*InstanceInfo, error) {
+	if order.InstanceID == 0 {
+		return nil, nil
+	}
+	instance, err := uc.instanceRepo.GetInstanceByOrderID(ctx, order.ID)
+	if errors.Is(err, ErrInstanceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if instance.PendingExpired(time.Now()) {
+		instance.clearPending()
+	}
+	if instance.Status == InstanceStatusDeleted || instance.PendingAction == InstanceActionDelete {
+		return nil, nil
+	}
+	if instance.PendingAction != "" {
+		uc.log.Warnf("refund denied, instance busy: orderID=%d instanceID=%d pending=%s",
+			order.ID, instance.InstanceID, instance.PendingAction)
+		return nil, ErrInstanceBusy
+	}
+	return instance, nil
+}
+
+// refundPayment 通过支付网关退款，返回退款单号；网关中已退款的金额（上次提交失败的重试）不再重复退，需在锁定订单的事务内调用
+// 每次网关调用最长 RefundGatewayTimeout，超时返回错误并回滚事务，重试时以网关中已退款的金额为准
+func (uc *OrderUsecase) refundPayment(ctx context.Context, order *Order, amount int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, RefundGatewayTimeout)
+	defer cancel()
+
+	intent, err := uc.payment.Query(ctx, order.PaymentID)
+	if err != nil {
+		uc.log.Errorf("query payment failed: orderID=%d paymentID=%s err=%v", order.ID, order.PaymentID, err)
+		return "", err
+	}
+	if amount <= intent.Refunded {
+		return "", nil
+	}
+	refund, err := uc.payment.Refund(ctx, order.PaymentID, amount-intent.Refunded)
+	if err != nil {
+		uc.log.Errorf("refund payment failed: orderID=%d paymentID=%s amount=%d err=%v", order.ID, order.PaymentID, amount, err)
+		return "", err
+	}
+	return refund.ID, nil
+}
+
+// deleteInstance 记录待处理的删除操作并通过发件箱下发 INSTANCE_DELETED，需在订单所在的事务内调用
+func (uc *OrderUsecase) deleteInstance(ctx context.Context, instance *InstanceInfo, now time.Time) error {
+	instance.PendingAction = InstanceActionDelete
+	instance.PendingAt = &now
+	instance.UpdatedAt = now
+	if err := uc.instanceRepo.MarkPending(ctx, instance); err != nil {
+		return err
+	}
+	spec := InstanceSpec{
+		InstanceID: instance.InstanceID,
+		UserID:     instance.UserID,
+		Name:       instance.ProductName,
+	}
+	if instance.Spec != nil {
+		spec.CPU = instance.Spec.CPU
+		spec.Memory = instance.Spec.Memory
+		spec.GPU = instance.Spec.GPU
+		spec.Image = instance.Spec.Image
+		spec.ConfigJSON = instance.Spec.ConfigJSON
+	}
+	return uc.outboxRepo.EnqueueInstanceEvent(ctx, EventInstanceDeleted, spec)
+}
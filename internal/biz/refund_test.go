@@ -0,0 +1,161 @@
+package biz
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProratedRefund(t *testing.T) {
+	paidAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		used time.Duration
+		want int64
+	}{
+		{0, 3000},
+		{-time.Hour, 3000}, // 时钟回拨按未使用计
+		{10 * 24 * time.Hour, 2000},
+		{RefundServicePeriod / 2, 1500},
+		{RefundServicePeriod - time.Second, 0}, // 向下取整到分
+		{RefundServicePeriod, 0},
+		{2 * RefundServicePeriod, 0},
+	}
+	for _, tt := range tests {
+		if got := ProratedRefund(3000, paidAt, paidAt.Add(tt.used)); got != tt.want {
+			t.Errorf("ProratedRefund(3000, used %s) = %d, want %d", tt.used, got, tt.want)
+		}
+	}
+}
+
+// paidOrder 购买并确认支付，返回已支付的订单
+func (f *paymentFixture) paidOrder(t *testing.T, userID string) *Order {
+	t.Helper()
+	ctx := context.Background()
+	order, _, _, err := f.uc.PurchaseProduct(ctx, userID, 1001, "")
+	if err != nil {
+		t.Fatalf("purchase: err = %v", err)
+	}
+	paid, err := f.uc.ConfirmPayment(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("confirm: err = %v", err)
+	}
+	return paid
+}
+
+func TestOrderUsecase_RefundOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+
+	pending, _, _, _ := f.uc.PurchaseProduct(ctx, "u0", 1001, "")
+	if _, err := f.uc.RefundOrder(ctx, "u0", pending.ID, 0, "test"); err != ErrOrderNotRefundable {
+		t.Errorf("pending order: err = %v, want %v", err, ErrOrderNotRefundable)
+	}
+
+	order := f.paidOrder(t, "u1")
+	if _, err := f.uc.RefundOrder(ctx, "u1", order.ID, 0, ""); err != ErrInvalidRefundReason {
+		t.Errorf("empty reason: err = %v, want %v", err, ErrInvalidRefundReason)
+	}
+	if _, err := f.uc.RefundOrder(ctx, "u1", order.ID, order.Amount+1, "test"); err != ErrInvalidRefund {
+		t.Errorf("amount over order amount: err = %v, want %v", err, ErrInvalidRefund)
+	}
+	// 只能退款和取消自己的订单
+	if _, err := f.uc.RefundOrder(ctx, "u2", order.ID, 0, "test"); err != ErrOrderForbidden {
+		t.Errorf("other user's order: err = %v, want %v", err, ErrOrderForbidden)
+	}
+	if _, err := f.uc.CancelOrder(ctx, "u2", order.ID); err != ErrOrderForbidden {
+		t.Errorf("cancel other user's order: err = %v, want %v", err, ErrOrderForbidden)
+	}
+	if _, err := f.uc.RefundOrder(ctx, "", order.ID, 0, "test"); err != ErrInvalidUserID {
+		t.Errorf("missing user id: err = %v, want %v", err, ErrInvalidUserID)
+	}
+
+	// 支付后已使用半个服务周期：按比例退一半
+	paidAt := time.Now().Add(-RefundServicePeriod/2 + time.Minute)
+	order.PaidAt = &paidAt
+	refunded, err := f.uc.RefundOrder(ctx, "u1", order.ID, 0, "customer request")
+	if err != nil {
+		t.Fatalf("prorated refund: err = %v", err)
+	}
+	if refunded.Status != OrderStatusCancelled || refunded.Refund == nil || refunded.Refund.Amount != order.Amount/2 ||
+		refunded.Refund.Reason != "customer request" || refunded.Refund.RefundID == "" {
+		t.Errorf("refunded order = %+v refund = %+v, want cancelled with half refunded", refunded, refunded.Refund)
+	}
+	if intent := f.gateway.intents[order.PaymentID]; intent.Refunded != order.Amount/2 {
+		t.Errorf("gateway refunded = %d, want %d", intent.Refunded, order.Amount/2)
+	}
+	instance, _ := f.instances.GetInstanceByOrderID(ctx, order.ID)
+	if instance.PendingAction != InstanceActionDelete {
+		t.Errorf("instance pending = %q, want %q", instance.PendingAction, InstanceActionDelete)
+	}
+	if last := f.outbox.enqueued[len(f.outbox.enqueued)-1]; len(f.outbox.enqueued) != 2 || last.InstanceID != order.InstanceID {
+		t.Errorf("events = %+v, want a delete event for instance %d", f.outbox.enqueued, order.InstanceID)
+	}
+	if f.inventory.stock[1001] != 4 {
+		t.Errorf("stock = %d, want 4 (one pending, one released)", f.inventory.stock[1001])
+	}
+	if _, err := f.uc.RefundOrder(ctx, "u1", order.ID, 0, "again"); err != ErrOrderNotRefundable {
+		t.Errorf("refund twice: err = %v, want %v", err, ErrOrderNotRefundable)
+	}
+	// 重放的支付回调不会退还剩余金额
+	if _, err := f.uc.HandlePaymentWebhook(ctx, order.PaymentID); err != nil || f.gateway.intents[order.PaymentID].Refunded != order.Amount/2 {
+		t.Errorf("replayed webhook: gateway refunded = %d err = %v, want %d",
+			f.gateway.intents[order.PaymentID].Refunded, err, order.Amount/2)
+	}
+
+	// 实例有未完成的操作时拒绝退款，不经过支付网关
+	busy := f.paidOrder(t, "u2")
+	busyInstance, _ := f.instances.GetInstanceByOrderID(ctx, busy.ID)
+	busyInstance.PendingAction = InstanceActionStop
+	if _, err := f.uc.RefundOrder(ctx, "u2", busy.ID, 100, "test"); err != ErrInstanceBusy {
+		t.Errorf("busy instance: err = %v, want %v", err, ErrInstanceBusy)
+	}
+	if f.gateway.intents[busy.PaymentID].Refunded != 0 {
+		t.Errorf("busy instance: gateway refunded = %d, want 0", f.gateway.intents[busy.PaymentID].Refunded)
+	}
+
+	// 上次请求已在网关退款但未记录：重试时只补退差额
+	busyInstance.PendingAction = ""
+	f.gateway.intents[busy.PaymentID].Refunded = 100
+	refunds := len(f.gateway.refunds)
+	partial, err := f.uc.RefundOrder(ctx, "u2", busy.ID, 300, "service degraded")
+	if err != nil || partial.Refund.Amount != 300 || f.gateway.intents[busy.PaymentID].Refunded != 300 {
+		t.Errorf("partial refund: order = %+v err = %v gateway refunded = %d, want 300",
+			partial, err, f.gateway.intents[busy.PaymentID].Refunded)
+	}
+	if len(f.gateway.refunds) != refunds+1 || f.gateway.refunds[refunds].Amount != 200 {
+		t.Errorf("refunds = %+v, want one more of 200", f.gateway.refunds)
+	}
+
+	// 没有支付意图的订单（秒杀）不经过网关，记录金额并标记为需要人工退款
+	seckill := f.paidOrder(t, "u3")
+	seckill.PaymentID = ""
+	refunds = len(f.gateway.refunds)
+	manual, err := f.uc.RefundOrder(ctx, "u3", seckill.ID, 500, "seckill refund")
+	if err != nil || manual.Refund == nil || !manual.Refund.Manual || manual.Refund.Amount != 500 || manual.Refund.RefundID != "" {
+		t.Errorf("seckill refund: order = %+v err = %v, want 500 recorded as manual", manual, err)
+	}
+	if len(f.gateway.refunds) != refunds {
+		t.Errorf("seckill refund: gateway refunds = %d, want %d", len(f.gateway.refunds), refunds)
+	}
+	if partial.Refund.Manual {
+		t.Errorf("gateway refund marked manual")
+	}
+}
+
+func TestOrderUsecase_CancelOrder_Paid(t *testing.T) {
+	ctx := context.Background()
+	f := newPaymentFixture()
+
+	order := f.paidOrder(t, "u1")
+	cancelled, err := f.uc.CancelOrder(ctx, "u1", order.ID)
+	if err != nil {
+		t.Fatalf("cancel paid order: err = %v", err)
+	}
+	if cancelled.Status != OrderStatusCancelled || cancelled.Refund == nil || cancelled.Refund.Reason != RefundReasonCancelled {
+		t.Errorf("cancelled order = %+v, want refunded as %q", cancelled, RefundReasonCancelled)
+	}
+	// 刚支付即取消：几乎全额退款
+	if amount := cancelled.Refund.Amount; amount < order.Amount-1 || amount > order.Amount {
+		t.Errorf("refund amount = %d, want about %d", amount, order.Amount)
+	}
+}
@@ -2,85 +2,499 @@ package biz
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 )
 
-// SeckillProductRepo 秒杀商品仓储接口
+// SeckillKeys 单个秒杀活动的 Redis Key（与 BFF 层约定）
+// 每个商品一组独立的 Key，{productID} 作为 hash tag，保证 Lua 脚本涉及的 Key 落在同一个 Redis Cluster 槽位
+type SeckillKeys struct {
+	Stock       string // 库存
+	ReqSeq      string // 请求序列号
+	UID2Req     string // 用户ID -> 请求号映射
+	Stream      string // 订单流
+	Meta        string // 活动参数（活动ID、秒杀价、限购数量等）
+	UIDCnt      string // 用户ID -> 已抢购数量（限购数量大于 1 时使用）
+	DLQ         string // 死信队列（超过最大投递次数或无法解析的订单流消息）
+	Compensated string // 已补偿的订单流消息ID -> 补偿结果（去重）
+}
+
+const (
+	// SeckillCampaignsKey 活跃秒杀活动集合（成员为商品ID）
+	SeckillCampaignsKey = "seckill:campaigns"
+	// SeckillCampaignsChannel 活动变更通知频道（消息为商品ID），Stream 消费者据此热启停
+	SeckillCampaignsChannel = "seckill:campaigns:changed"
+	// SeckillConsumerGroup 订单流消费者组
+	SeckillConsumerGroup = "g1"
+)
+
+// SeckillKeysFor 返回商品对应的秒杀 Key
+func SeckillKeysFor(productID int64) SeckillKeys {
+	tag := fmt.Sprintf("{%d}", productID)
+	return SeckillKeys{
+		Stock:       "seckill:stock:" + tag,
+		ReqSeq:      "req:seq:" + tag,
+		UID2Req:     "uid2req:" + tag,
+		Stream:      "stream:orders:" + tag,
+		Meta:        "seckill:meta:" + tag,
+		UIDCnt:      "uidcnt:" + tag,
+		DLQ:         "stream:orders:dlq:" + tag,
+		Compensated: "seckill:compensated:" + tag,
+	}
+}
+
+// 秒杀活动状态
+//
+//	SCHEDULED -> ACTIVE -> ENDED -> SETTLED
+//	    |          |         |
+//	    +----------+---------+----> CANCELLED
+const (
+	SeckillStatusScheduled = "SCHEDULED" // 已创建，等待开始时间
+	SeckillStatusActive    = "ACTIVE"    // 进行中，Redis 库存已开放
+	SeckillStatusEnded     = "ENDED"     // 已到结束时间，停止售卖，等待订单流消费完
+	SeckillStatusSettled   = "SETTLED"   // 订单流已消费完，Redis 数据已清理
+	SeckillStatusCancelled = "CANCELLED" // 管理员取消
+)
+
+// SeckillCampaign 秒杀活动（以商品为单位，多个商品可同时秒杀，同一商品同时只有一个未结束的活动）
+type SeckillCampaign struct {
+	ID           int64
+	ProductID    int64
+	Stock        int32      // 剩余库存（Redis 中的实时值）
+	InitialStock int32      // 初始库存
+	Sold         int32      // 已售数量（活动结束时结算：初始库存 - 剩余库存）
+	Price        int64      // 秒杀价（分），0 表示按商品原价
+	PerUserLimit int32      // 每个用户限购数量
+	StartAt      time.Time  // 开始时间
+	EndAt        *time.Time // 结束时间（nil 表示不自动结束）
+	Status       string     // SCHEDULED, ACTIVE, ENDED, SETTLED, CANCELLED
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// SeckillProductRepo 秒杀商品仓储接口（Redis）
 type SeckillProductRepo interface {
-	// InitSeckill 初始化秒杀（清空上次数据并设置新的 productID 和库存）
-	// 同一时间只有一个秒杀商品，productID 作为全局变量存储在 Redis
-	InitSeckill(ctx context.Context, productID int64, stock int32) error
+	// InitSeckill 开放商品的秒杀库存（清空该商品上次的数据并写入活动参数），不影响其他商品的活动
+	// 按活动ID幂等：已为同一活动初始化过时不做任何修改，重试不会重置售卖中的库存和抢购记录
+	InitSeckill(ctx context.Context, campaign *SeckillCampaign) error
+
+	// ListProductIDs 列出所有活跃秒杀活动的商品ID
+	ListProductIDs(ctx context.Context) ([]int64, error)
+
+	// GetSeckill 获取商品当前的秒杀库存和活动参数，活动不存在时返回 ErrNoActiveSeckill
+	GetSeckill(ctx context.Context, productID int64) (*SeckillCampaign, error)
+
+	// CloseSeckill 停止售卖（库存置 0，保留订单流继续消费），返回关闭前的剩余库存；重复调用返回第一次关闭时的剩余库存
+	CloseSeckill(ctx context.Context, productID int64) (int32, error)
+
+	// StreamDrained 订单流中的消息是否已全部消费并确认
+	StreamDrained(ctx context.Context, productID int64) (bool, error)
+
+	// ClearSeckill 清空商品的秒杀数据
+	ClearSeckill(ctx context.Context, productID int64) error
+
+	// ScanStream 按顺序读取订单流中的全部消息
+	ScanStream(ctx context.Context, productID int64) ([]*SeckillStreamEntry, error)
+
+	// ScanUID2Req 读取全部用户ID -> 请求号映射
+	ScanUID2Req(ctx context.Context, productID int64) (map[string]int64, error)
+
+	// AddDeadLetter 把订单流消息转入死信队列并 ACK 原消息（同一事务）
+	AddDeadLetter(ctx context.Context, productID int64, letter *SeckillDeadLetter) error
+
+	// ListDeadLetters 按顺序列出死信，after 为上一页最后一条的ID（空表示从头开始）
+	ListDeadLetters(ctx context.Context, productID int64, after string, limit int64) ([]*SeckillDeadLetter, error)
+
+	// GetDeadLetter 获取死信，不存在时返回 ErrSeckillDeadLetterNotFound
+	GetDeadLetter(ctx context.Context, productID int64, id string) (*SeckillDeadLetter, error)
+
+	// DeleteDeadLetter 删除死信，不存在时返回 ErrSeckillDeadLetterNotFound
+	DeleteDeadLetter(ctx context.Context, productID int64, id string) error
+
+	// Compensate 原子地归还库存并释放用户名额，写入补偿结果；该消息已补偿过时返回 false 并填充上次的结果
+	Compensate(ctx context.Context, compensation *SeckillCompensation) (bool, error)
+
+	// ScanCompensated 读取已补偿的订单流消息ID -> 是否归还了库存（对账使用）
+	ScanCompensated(ctx context.Context, productID int64) (map[string]bool, error)
+
+	// Purchase 原子地抢购一件：检查限购、扣减库存、生成请求号并写入订单流，活动不存在时返回 ErrNoActiveSeckill
+	Purchase(ctx context.Context, productID int64, userID string) (*SeckillPurchaseResult, error)
+}
+
+// 秒杀抢购结果
+const (
+	SeckillPurchaseAccepted      = "ACCEPTED"       // 抢购成功，订单由 Stream 消费者异步创建
+	SeckillPurchaseSoldOut       = "SOLD_OUT"       // 库存不足
+	SeckillPurchaseAlreadyBought = "ALREADY_BOUGHT" // 已达到每人限购数量
+)
+
+// SeckillPurchaseResult 秒杀抢购结果
+type SeckillPurchaseResult struct {
+	Result   string // ACCEPTED, SOLD_OUT, ALREADY_BOUGHT
+	ReqID    int64  // 请求号（ACCEPTED 为本次请求号，ALREADY_BOUGHT 为用户最近一次的请求号）
+	StreamID string // 订单流消息ID（仅 ACCEPTED）
+}
+
+// SeckillCampaignRepo 秒杀活动仓储接口（Postgres）
+type SeckillCampaignRepo interface {
+	// Create 创建活动，写入生成的活动ID
+	Create(ctx context.Context, campaign *SeckillCampaign) error
+
+	// GetCurrent 获取商品未结束的活动（SCHEDULED/ACTIVE/ENDED），没有时返回 ErrNoActiveSeckill
+	GetCurrent(ctx context.Context, productID int64) (*SeckillCampaign, error)
+
+	// List 列出所有未结束的活动
+	List(ctx context.Context) ([]*SeckillCampaign, error)
+
+	// ListToStart 列出开始时间已到、仍未开启的活动
+	ListToStart(ctx context.Context, now time.Time) ([]*SeckillCampaign, error)
 
-	// GetCurrentProductID 获取当前秒杀商品ID
-	GetCurrentProductID(ctx context.Context) (int64, error)
+	// ListToEnd 列出结束时间已到、仍在进行的活动
+	ListToEnd(ctx context.Context, now time.Time) ([]*SeckillCampaign, error)
 
-	// GetStock 获取当前库存
-	GetStock(ctx context.Context) (int32, error)
+	// ListEnded 列出已结束、等待结算的活动
+	ListEnded(ctx context.Context) ([]*SeckillCampaign, error)
 
-	// ClearSeckill 清空秒杀数据
-	ClearSeckill(ctx context.Context) error
+	// UpdateStatus 乐观并发更新活动状态和已售数量：仅当当前状态仍为 from 时更新，否则返回 ErrSeckillCampaignConflict
+	UpdateStatus(ctx context.Context, campaign *SeckillCampaign, from string) error
+}
+
+// SeckillScheduleResult 单轮调度结果
+type SeckillScheduleResult struct {
+	Started int // 开启的活动
+	Ended   int // 结束售卖的活动
+	Settled int // 完成结算的活动
 }
 
 // SeckillUsecase 秒杀业务用例
 type SeckillUsecase struct {
-	repo SeckillProductRepo
-	log  *log.Helper
+	repo             SeckillProductRepo
+	campaignRepo     SeckillCampaignRepo
+	compensationRepo SeckillCompensationRepo
+	orderRepo        OrderRepo // 对账时查询订单
+	tx               Transaction
+	log              *log.Helper
 }
 
 // NewSeckillUsecase 创建秒杀业务用例
-func NewSeckillUsecase(repo SeckillProductRepo, logger log.Logger) *SeckillUsecase {
+func NewSeckillUsecase(
+	repo SeckillProductRepo,
+	campaignRepo SeckillCampaignRepo,
+	compensationRepo SeckillCompensationRepo,
+	orderRepo OrderRepo,
+	tx Transaction,
+	logger log.Logger,
+) *SeckillUsecase {
 	return &SeckillUsecase{
-		repo: repo,
-		log:  log.NewHelper(logger),
+		repo:             repo,
+		campaignRepo:     campaignRepo,
+		compensationRepo: compensationRepo,
+		orderRepo:        orderRepo,
+		tx:               tx,
+		log:              log.NewHelper(logger),
 	}
 }
 
-// InitSeckill 初始化秒杀（管理员操作）
-// 清空上次秒杀数据并设置新的商品和库存
-func (uc *SeckillUsecase) InitSeckill(ctx context.Context, productID int64, stock int32) error {
-	// 验证库存
-	if stock <= 0 {
+// InitSeckill 创建秒杀活动（管理员操作）
+// 开始时间已到的活动立即开放库存，否则由调度器在开始时间开启；同一商品同时只能有一个未结束的活动
+func (uc *SeckillUsecase) InitSeckill(ctx context.Context, campaign *SeckillCampaign) (*SeckillCampaign, error) {
+	now := time.Now()
+	if err := validateSeckillCampaign(campaign, now); err != nil {
+		return nil, err
+	}
+	if campaign.StartAt.IsZero() {
+		campaign.StartAt = now
+	}
+	if campaign.PerUserLimit == 0 {
+		campaign.PerUserLimit = 1
+	}
+
+	if _, err := uc.campaignRepo.GetCurrent(ctx, campaign.ProductID); err == nil {
+		return nil, ErrSeckillCampaignExists
+	} else if err != ErrNoActiveSeckill {
+		return nil, err
+	}
+
+	campaign.Status = SeckillStatusScheduled
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+	if err := uc.campaignRepo.Create(ctx, campaign); err != nil {
+		uc.log.Errorf("create seckill campaign failed: productID=%d err=%v", campaign.ProductID, err)
+		return nil, err
+	}
+	uc.log.Infof("seckill campaign created: id=%d productID=%d stock=%d start=%s",
+		campaign.ID, campaign.ProductID, campaign.InitialStock, campaign.StartAt.Format(time.RFC3339))
+
+	// 立即开启失败时活动保持 SCHEDULED，由调度器重试
+	if !campaign.StartAt.After(now) {
+		_ = uc.start(ctx, campaign)
+	}
+	return campaign, nil
+}
+
+// validateSeckillCampaign 校验活动参数
+func validateSeckillCampaign(campaign *SeckillCampaign, now time.Time) error {
+	if campaign.ProductID <= 0 {
+		return ErrInvalidProductID
+	}
+	if campaign.InitialStock <= 0 {
 		return ErrInvalidStock
 	}
+	if campaign.PerUserLimit < 0 || campaign.PerUserLimit > campaign.InitialStock {
+		return ErrInvalidSeckillLimit
+	}
+	if campaign.Price < 0 {
+		return ErrInvalidPrice
+	}
+	if campaign.EndAt != nil {
+		if !campaign.EndAt.After(now) || (!campaign.StartAt.IsZero() && !campaign.EndAt.After(campaign.StartAt)) {
+			return ErrInvalidSeckillWindow
+		}
+	}
+	return nil
+}
+
+// RunSchedule 执行一轮调度：开启到点的活动、结束到期的活动、结算订单流已消费完的活动
+// 状态迁移以当前状态作为乐观锁条件，多个副本同时调度时同一活动只会被处理一次
+func (uc *SeckillUsecase) RunSchedule(ctx context.Context, now time.Time) (*SeckillScheduleResult, error) {
+	res := &SeckillScheduleResult{}
+
+	toStart, err := uc.campaignRepo.ListToStart(ctx, now)
+	if err != nil {
+		return res, err
+	}
+	for _, campaign := range toStart {
+		if err := uc.start(ctx, campaign); err != nil {
+			continue
+		}
+		res.Started++
+	}
+
+	toEnd, err := uc.campaignRepo.ListToEnd(ctx, now)
+	if err != nil {
+		return res, err
+	}
+	for _, campaign := range toEnd {
+		if err := uc.end(ctx, campaign); err != nil {
+			continue
+		}
+		res.Ended++
+	}
 
-	uc.log.Infof("initializing seckill: productID=%d, stock=%d", productID, stock)
-	return uc.repo.InitSeckill(ctx, productID, stock)
+	ended, err := uc.campaignRepo.ListEnded(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, campaign := range ended {
+		settled, err := uc.settle(ctx, campaign)
+		if err != nil || !settled {
+			continue
+		}
+		res.Settled++
+	}
+	return res, nil
 }
 
-// GetCurrentSeckill 获取当前秒杀信息
-func (uc *SeckillUsecase) GetCurrentSeckill(ctx context.Context) (productID int64, stock int32, err error) {
-	productID, err = uc.repo.GetCurrentProductID(ctx)
+// start 开启活动：先提交 ACTIVE 状态再开放 Redis 库存
+// Redis 初始化按活动ID幂等，重试不会重置已开放的库存；开放失败时状态恢复为 SCHEDULED，由调度器重试
+func (uc *SeckillUsecase) start(ctx context.Context, campaign *SeckillCampaign) error {
+	campaign.Status = SeckillStatusActive
+	campaign.UpdatedAt = time.Now()
+	if err := uc.campaignRepo.UpdateStatus(ctx, campaign, SeckillStatusScheduled); err != nil {
+		campaign.Status = SeckillStatusScheduled
+		uc.log.Errorf("start seckill campaign failed: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, err)
+		return err
+	}
+
+	if err := uc.repo.InitSeckill(ctx, campaign); err != nil {
+		uc.log.Errorf("open seckill stock failed, revert to scheduled: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, err)
+		campaign.Status = SeckillStatusScheduled
+		campaign.UpdatedAt = time.Now()
+		if rerr := uc.campaignRepo.UpdateStatus(ctx, campaign, SeckillStatusActive); rerr != nil {
+			// 活动保持 ACTIVE 但库存未开放（抢购返回无活动），需人工处理
+			uc.log.Errorf("revert seckill campaign failed: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, rerr)
+			campaign.Status = SeckillStatusActive
+		}
+		return err
+	}
+	campaign.Stock = campaign.InitialStock
+	uc.log.Infof("seckill campaign started: id=%d productID=%d stock=%d", campaign.ID, campaign.ProductID, campaign.InitialStock)
+	return nil
+}
+
+// end 结束售卖：标记为 ENDED，库存置 0 并记录已售数量，订单流继续由消费者处理
+// Redis 关闭在事务内进行，事务提交失败时 Redis 已关闭；CloseSeckill 可重复执行，重试时仍返回关闭前的库存
+func (uc *SeckillUsecase) end(ctx context.Context, campaign *SeckillCampaign) error {
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		campaign.Status = SeckillStatusEnded
+		campaign.UpdatedAt = time.Now()
+		if err := uc.campaignRepo.UpdateStatus(ctx, campaign, SeckillStatusActive); err != nil {
+			return err
+		}
+		remaining, err := uc.repo.CloseSeckill(ctx, campaign.ProductID)
+		if err != nil {
+			return err
+		}
+		campaign.Sold = campaign.InitialStock - remaining
+		return uc.campaignRepo.UpdateStatus(ctx, campaign, SeckillStatusEnded)
+	})
 	if err != nil {
-		return 0, 0, err
+		campaign.Status = SeckillStatusActive
+		uc.log.Errorf("end seckill campaign failed: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, err)
+		return err
 	}
+	campaign.Stock = 0
+	uc.log.Infof("seckill campaign ended: id=%d productID=%d sold=%d", campaign.ID, campaign.ProductID, campaign.Sold)
+	return nil
+}
 
-	stock, err = uc.repo.GetStock(ctx)
+// settle 结算活动：订单流消费完后标记为 SETTLED 并清理 Redis 数据（消费者随之停止）
+func (uc *SeckillUsecase) settle(ctx context.Context, campaign *SeckillCampaign) (bool, error) {
+	drained, err := uc.repo.StreamDrained(ctx, campaign.ProductID)
 	if err != nil {
-		return 0, 0, err
+		uc.log.Errorf("check seckill stream failed: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, err)
+		return false, err
+	}
+	if !drained {
+		return false, nil
 	}
 
-	return productID, stock, nil
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		campaign.Status = SeckillStatusSettled
+		campaign.UpdatedAt = time.Now()
+		if err := uc.campaignRepo.UpdateStatus(ctx, campaign, SeckillStatusEnded); err != nil {
+			return err
+		}
+		return uc.repo.ClearSeckill(ctx, campaign.ProductID)
+	})
+	if err != nil {
+		campaign.Status = SeckillStatusEnded
+		uc.log.Errorf("settle seckill campaign failed: id=%d productID=%d err=%v", campaign.ID, campaign.ProductID, err)
+		return false, err
+	}
+	uc.log.Infof("seckill campaign settled: id=%d productID=%d sold=%d", campaign.ID, campaign.ProductID, campaign.Sold)
+	return true, nil
 }
 
-// ClearSeckill 清空秒杀数据（管理员操作）
-func (uc *SeckillUsecase) ClearSeckill(ctx context.Context) error {
-	uc.log.Info("clearing seckill data")
-	return uc.repo.ClearSeckill(ctx)
+// GetSeckill 获取商品正在进行的秒杀（Redis 实时库存）
+func (uc *SeckillUsecase) GetSeckill(ctx context.Context, productID int64) (*SeckillCampaign, error) {
+	return uc.repo.GetSeckill(ctx, productID)
 }
 
-// 错误定义
-var (
-	ErrInvalidStock    = &BizError{Code: 400, Message: "invalid stock: must be greater than 0"}
-	ErrNoActiveSeckill = &BizError{Code: 404, Message: "no active seckill"}
-)
+// GetCampaign 获取商品未结束的秒杀活动（可能尚未开始）
+func (uc *SeckillUsecase) GetCampaign(ctx context.Context, productID int64) (*SeckillCampaign, error) {
+	campaign, err := uc.campaignRepo.GetCurrent(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	uc.fillStock(ctx, campaign)
+	return campaign, nil
+}
+
+// ListSeckills 列出所有未结束的秒杀活动，进行中的活动附带 Redis 实时库存
+func (uc *SeckillUsecase) ListSeckills(ctx context.Context) ([]*SeckillCampaign, error) {
+	campaigns, err := uc.campaignRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, campaign := range campaigns {
+		uc.fillStock(ctx, campaign)
+	}
+	return campaigns, nil
+}
 
-// BizError 业务错误
-type BizError struct {
-	Code    int
-	Message string
+// fillStock 进行中的活动读取 Redis 实时库存
+func (uc *SeckillUsecase) fillStock(ctx context.Context, campaign *SeckillCampaign) {
+	if campaign.Status != SeckillStatusActive {
+		return
+	}
+	live, err := uc.repo.GetSeckill(ctx, campaign.ProductID)
+	if err != nil {
+		uc.log.Warnf("get seckill stock failed: productID=%d err=%v", campaign.ProductID, err)
+		return
+	}
+	campaign.Stock = live.Stock
 }
 
-func (e *BizError) Error() string {
-	return e.Message
+// ListLiveSeckills 列出 Redis 中所有已开放的秒杀（含已结束、订单流尚未消费完的活动）
+func (uc *SeckillUsecase) ListLiveSeckills(ctx context.Context) ([]*SeckillCampaign, error) {
+	productIDs, err := uc.repo.ListProductIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]*SeckillCampaign, 0, len(productIDs))
+	for _, productID := range productIDs {
+		campaign, err := uc.repo.GetSeckill(ctx, productID)
+		if err == ErrNoActiveSeckill {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, nil
 }
+
+// Purchase 用户秒杀抢购（与 BFF 层执行相同的 Lua 脚本），抢购成功后订单由 Stream 消费者异步创建
+func (uc *SeckillUsecase) Purchase(ctx context.Context, productID int64, userID string) (*SeckillPurchaseResult, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	res, err := uc.repo.Purchase(ctx, productID, userID)
+	if err != nil {
+		if err != ErrNoActiveSeckill {
+			uc.log.Errorf("seckill purchase failed: productID=%d userID=%s err=%v", productID, userID, err)
+		}
+		return nil, err
+	}
+	uc.log.Infof("seckill purchase: productID=%d userID=%s result=%s reqID=%d", productID, userID, res.Result, res.ReqID)
+	return res, nil
+}
+
+// ClearSeckill 取消商品当前的秒杀活动并清空秒杀数据（管理员操作）
+func (uc *SeckillUsecase) ClearSeckill(ctx context.Context, productID int64) error {
+	if productID <= 0 {
+		return ErrInvalidProductID
+	}
+	uc.log.Infof("clearing seckill data: productID=%d", productID)
+
+	return uc.tx.InTx(ctx, func(ctx context.Context) error {
+		campaign, err := uc.campaignRepo.GetCurrent(ctx, productID)
+		if err != nil && err != ErrNoActiveSeckill {
+			return err
+		}
+		if campaign != nil {
+			from := campaign.Status
+			campaign.Status = SeckillStatusCancelled
+			campaign.UpdatedAt = time.Now()
+			if err := uc.campaignRepo.UpdateStatus(ctx, campaign, from); err != nil {
+				return err
+			}
+			uc.log.Infof("seckill campaign cancelled: id=%d productID=%d", campaign.ID, productID)
+		}
+		return uc.repo.ClearSeckill(ctx, productID)
+	})
+}
+
+// 错误定义
+var (
+	ErrInvalidStock            = &BizError{Code: 400, Message: "invalid stock: must be greater than 0"}
+	ErrInvalidSeckillLimit     = &BizError{Code: 400, Message: "invalid per-user limit: must be between 0 and stock"}
+	ErrInvalidSeckillWindow    = &BizError{Code: 400, Message: "invalid seckill window: end time must be in the future and after start time"}
+	ErrNoActiveSeckill         = &BizError{Code: 404, Message: "no active seckill"}
+	ErrSeckillCampaignExists   = &BizError{Code: 409, Message: "product already has an unfinished seckill campaign"}
+	ErrSeckillCampaignConflict = &BizError{Code: 409, Message: "seckill campaign status changed concurrently"}
+
+	ErrSeckillDeadLetterNotFound      = &BizError{Code: 404, Message: "seckill dead letter not found"}
+	ErrSeckillDeadLetterNotReplayable = &BizError{Code: 400, Message: "seckill dead letter has no uid and cannot be replayed"}
+
+	ErrSeckillReqIDCollision = &BizError{Code: 409, Message: "seckill req_id is already used by another user's order"}
+)
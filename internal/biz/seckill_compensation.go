@@ -0,0 +1,62 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SeckillCompensation 秒杀补偿记录
+// 订单被确定拒绝（重试也不会成功）时，归还 Redis 中已扣减的库存并释放用户的抢购名额，每条订单流消息最多补偿一次
+type SeckillCompensation struct {
+	ID            int64
+	ProductID     int64
+	StreamID      string // 订单流消息ID
+	UserID        string
+	ReqID         int64  // 订单 req_id
+	Reason        string // 订单被拒绝的原因
+	StockReturned bool   // 是否归还了库存（活动已结束售卖时不归还，避免重新开卖）
+	SlotReleased  bool   // 是否释放了用户名额（uid2req / uidcnt）
+	CreatedAt     time.Time
+}
+
+// SeckillCompensationRepo 秒杀补偿审计记录仓储接口（Postgres）
+type SeckillCompensationRepo interface {
+	// Create 写入补偿记录，同一条订单流消息已有记录时忽略
+	Create(ctx context.Context, compensation *SeckillCompensation) error
+}
+
+// IsSeckillOrderRejected 订单是否被确定拒绝：商品不存在、已下架、规格缺失或用户ID非法，重试也不会成功
+func IsSeckillOrderRejected(err error) bool {
+	return errors.Is(err, ErrProductNotFound) ||
+		errors.Is(err, ErrProductDisabled) ||
+		errors.Is(err, ErrProductSpecNotFound) ||
+		errors.Is(err, ErrInvalidUserID)
+}
+
+// Compensate 补偿被拒绝的秒杀订单：原子地归还库存、释放用户名额，并写入审计记录
+// Redis 中以订单流消息ID去重，审计记录写入失败时返回错误，消息重试时不会重复归还库存
+func (uc *SeckillUsecase) Compensate(ctx context.Context, compensation *SeckillCompensation) error {
+	if compensation.CreatedAt.IsZero() {
+		compensation.CreatedAt = time.Now()
+	}
+
+	applied, err := uc.repo.Compensate(ctx, compensation)
+	if err != nil {
+		uc.log.Errorf("compensate seckill order failed: productID=%d streamID=%s err=%v",
+			compensation.ProductID, compensation.StreamID, err)
+		return err
+	}
+	if err := uc.compensationRepo.Create(ctx, compensation); err != nil {
+		uc.log.Errorf("record seckill compensation failed: productID=%d streamID=%s err=%v",
+			compensation.ProductID, compensation.StreamID, err)
+		return err
+	}
+
+	if applied {
+		uc.log.Warnf("seckill order compensated: productID=%d streamID=%s uid=%s stockReturned=%t slotReleased=%t reason=%s",
+			compensation.ProductID, compensation.StreamID, compensation.UserID,
+			compensation.StockReturned, compensation.SlotReleased, compensation.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestIsSeckillOrderRejected(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{ErrProductNotFound, true},
+		{ErrProductDisabled, true},
+		{ErrProductSpecNotFound, true},
+		{fmt.Errorf("create order: %w", ErrInvalidUserID), true},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tc := range cases {
+		if got := IsSeckillOrderRejected(tc.err); got != tc.want {
+			t.Errorf("IsSeckillOrderRejected(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestSeckillUsecase_Compensate(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSeckillProductRepo{
+		stock:   map[int64]int32{1001: 0},
+		uid2req: map[string]int64{"u1": 1, "u2": 2},
+	}
+	audit := &fakeSeckillCompensationRepo{}
+	uc := NewSeckillUsecase(repo, &fakeSeckillCampaignRepo{}, audit, nil, fakeTx{}, log.DefaultLogger)
+
+	c := &SeckillCompensation{ProductID: 1001, StreamID: "1-0", UserID: "u1", ReqID: 7, Reason: ErrProductDisabled.Error()}
+	if err := uc.Compensate(ctx, c); err != nil {
+		t.Fatalf("compensate: %v", err)
+	}
+	if !c.StockReturned || !c.SlotReleased || repo.stock[1001] != 1 {
+		t.Errorf("compensation = %+v stock=%d, want stock returned and slot released", c, repo.stock[1001])
+	}
+	if _, ok := repo.uid2req["u1"]; ok {
+		t.Error("u1 still holds a seckill slot")
+	}
+
+	// 消息重试时不重复归还库存
+	retry := &SeckillCompensation{ProductID: 1001, StreamID: "1-0", UserID: "u1", ReqID: 7}
+	if err := uc.Compensate(ctx, retry); err != nil {
+		t.Fatalf("compensate retry: %v", err)
+	}
+	if repo.stock[1001] != 1 || !retry.StockReturned {
+		t.Errorf("retry stock=%d compensation=%+v, want stock unchanged and previous result", repo.stock[1001], retry)
+	}
+	if len(audit.records) != 1 {
+		t.Errorf("audit records = %d, want 1", len(audit.records))
+	}
+
+	// 活动结束售卖后只释放名额，不归还库存
+	if _, err := repo.CloseSeckill(ctx, 1001); err != nil {
+		t.Fatal(err)
+	}
+	closed := &SeckillCompensation{ProductID: 1001, StreamID: "1-1", UserID: "u2", ReqID: 8}
+	if err := uc.Compensate(ctx, closed); err != nil {
+		t.Fatalf("compensate after close: %v", err)
+	}
+	if closed.StockReturned || !closed.SlotReleased || repo.stock[1001] != 0 {
+		t.Errorf("compensation after close = %+v stock=%d, want slot released only", closed, repo.stock[1001])
+	}
+}
@@ -0,0 +1,71 @@
+package biz
+
+import (
+	"context"
+	"time"
+)
+
+// 死信原因（处理失败时为处理器返回的错误信息）
+const (
+	SeckillDeadLetterMissingUID = "missing uid field"
+)
+
+// defaultDeadLetterLimit 死信列表默认返回条数
+const defaultDeadLetterLimit = 100
+
+// SeckillDeadLetter 死信（无法处理的订单流消息），记录足够的信息以便补单
+type SeckillDeadLetter struct {
+	ID         string    // 死信队列中的消息ID
+	StreamID   string    // 原订单流消息ID（补单时据此生成 req_id）
+	UserID     string    // uid 字段（原消息缺失时为空）
+	Req        int64     // req 字段
+	Price      int64     // 活动秒杀价（分），0 表示按商品原价
+	Reason     string    // 最后一次失败原因
+	Deliveries int64     // 转入死信时的投递次数
+	FailedAt   time.Time // 转入死信的时间
+}
+
+// DeadLetter 把无法处理的订单流消息转入死信队列，原消息随之 ACK，不再重试
+func (uc *SeckillUsecase) DeadLetter(ctx context.Context, productID int64, letter *SeckillDeadLetter) error {
+	if letter.FailedAt.IsZero() {
+		letter.FailedAt = time.Now()
+	}
+	if err := uc.repo.AddDeadLetter(ctx, productID, letter); err != nil {
+		uc.log.Errorf("dead-letter seckill message failed: productID=%d streamID=%s err=%v", productID, letter.StreamID, err)
+		return err
+	}
+	uc.log.Warnf("seckill message dead-lettered: productID=%d streamID=%s uid=%s deliveries=%d reason=%s",
+		productID, letter.StreamID, letter.UserID, letter.Deliveries, letter.Reason)
+	return nil
+}
+
+// ListDeadLetters 列出商品的死信（管理员操作）
+func (uc *SeckillUsecase) ListDeadLetters(ctx context.Context, productID int64, after string, limit int64) ([]*SeckillDeadLetter, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	return uc.repo.ListDeadLetters(ctx, productID, after, limit)
+}
+
+// GetDeadLetter 获取死信（补单前读取）
+func (uc *SeckillUsecase) GetDeadLetter(ctx context.Context, productID int64, id string) (*SeckillDeadLetter, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	return uc.repo.GetDeadLetter(ctx, productID, id)
+}
+
+// DiscardDeadLetter 丢弃死信（管理员确认无需补单，或补单成功后删除）
+func (uc *SeckillUsecase) DiscardDeadLetter(ctx context.Context, productID int64, id string) error {
+	if productID <= 0 {
+		return ErrInvalidProductID
+	}
+	if err := uc.repo.DeleteDeadLetter(ctx, productID, id); err != nil {
+		return err
+	}
+	uc.log.Infof("seckill dead letter discarded: productID=%d id=%s", productID, id)
+	return nil
+}
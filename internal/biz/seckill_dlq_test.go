@@ -0,0 +1,51 @@
+package biz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestSeckillUsecase_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSeckillProductRepo{}
+	uc := NewSeckillUsecase(repo, &fakeSeckillCampaignRepo{}, &fakeSeckillCompensationRepo{}, nil, fakeTx{}, log.DefaultLogger)
+
+	for _, letter := range []*SeckillDeadLetter{
+		{StreamID: "1-0", UserID: "u1", Reason: "product not found", Deliveries: 5},
+		{StreamID: "1-1", Reason: SeckillDeadLetterMissingUID, Deliveries: 1},
+	} {
+		if err := uc.DeadLetter(ctx, 1001, letter); err != nil {
+			t.Fatalf("dead letter %s: %v", letter.StreamID, err)
+		}
+		if letter.FailedAt.IsZero() {
+			t.Errorf("dead letter %s has no failed time", letter.StreamID)
+		}
+	}
+
+	letters, err := uc.ListDeadLetters(ctx, 1001, "", 0)
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("list dead letters = %d, want 2", len(letters))
+	}
+	page, err := uc.ListDeadLetters(ctx, 1001, letters[0].ID, 10)
+	if err != nil || len(page) != 1 || page[0].StreamID != "1-1" {
+		t.Errorf("list after %s = %+v, %v, want the second letter", letters[0].ID, page, err)
+	}
+
+	if err := uc.DiscardDeadLetter(ctx, 1001, letters[0].ID); err != nil {
+		t.Fatalf("discard: %v", err)
+	}
+	if _, err := uc.GetDeadLetter(ctx, 1001, letters[0].ID); err != ErrSeckillDeadLetterNotFound {
+		t.Errorf("get discarded letter: err = %v, want %v", err, ErrSeckillDeadLetterNotFound)
+	}
+	if err := uc.DiscardDeadLetter(ctx, 1001, letters[0].ID); err != ErrSeckillDeadLetterNotFound {
+		t.Errorf("discard twice: err = %v, want %v", err, ErrSeckillDeadLetterNotFound)
+	}
+	if _, err := uc.ListDeadLetters(ctx, 0, "", 0); err != ErrInvalidProductID {
+		t.Errorf("list with invalid product: err = %v, want %v", err, ErrInvalidProductID)
+	}
+}
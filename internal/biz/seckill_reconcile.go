@@ -0,0 +1,204 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SeckillStreamEntry 订单流中的一条抢购消息
+type SeckillStreamEntry struct {
+	StreamID string // Stream 消息ID
+	UserID   string // uid 字段
+	Req      int64  // req 字段（Lua 脚本生成的请求号，BFF 旧版本写入的消息可能没有）
+}
+
+// Stream ID 打包为 req_id 的位宽：毫秒时间戳占 42 位（可用到 2109 年），同一毫秒内的序号占 21 位
+const (
+	seckillReqIDSeqBits = 21
+	seckillReqIDSeqMax  = 1<<seckillReqIDSeqBits - 1
+	seckillReqIDMsMax   = 1<<(63-seckillReqIDSeqBits) - 1
+)
+
+// SeckillOrderReqID 订单流消息对应的订单 req_id，消费者创建订单与对账使用同一规则
+// Stream ID 格式：timestamp-sequence (如 "1609459200000-0")，按 毫秒<<21 | 序号 无损打包，不同消息不会得到相同的 req_id
+// 不使用消息中的 req 字段：reqseq 在每次初始化活动时重置，同一商品的多次活动会得到相同的请求号
+func SeckillOrderReqID(streamID string) (int64, error) {
+	msPart, seqPart, ok := strings.Cut(streamID, "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid stream id %q", streamID)
+	}
+	ms, err := strconv.ParseInt(msPart, 10, 64)
+	if err != nil || ms < 0 || ms > seckillReqIDMsMax {
+		return 0, fmt.Errorf("invalid stream id %q: timestamp out of range", streamID)
+	}
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil || seq < 0 || seq > seckillReqIDSeqMax {
+		return 0, fmt.Errorf("invalid stream id %q: sequence out of range", streamID)
+	}
+	return ms<<seckillReqIDSeqBits | seq, nil
+}
+
+// SeckillStreamIDFromReqID 还原 req_id 对应的 Stream 消息ID（SeckillOrderReqID 的逆运算）
+func SeckillStreamIDFromReqID(reqID int64) string {
+	return fmt.Sprintf("%d-%d", reqID>>seckillReqIDSeqBits, reqID&seckillReqIDSeqMax)
+}
+
+// SeckillReconcileReport 秒杀对账报告（Redis 订单流、uid2req 与 Postgres 订单的比对结果）
+type SeckillReconcileReport struct {
+	ProductID    int64
+	CampaignID   int64 // 0 表示没有活动记录（如配置文件固定开启的商品）
+	Price        int64 // 活动秒杀价，补单时使用
+	PerUserLimit int32
+	InitialStock int32
+	Sold         int32 // 已售数量：初始库存 - 剩余库存（已结束的活动取结算值）
+
+	StreamEntries  int // 订单流消息数
+	UID2ReqEntries int // uid2req 用户数
+	OrdersCreated  int // 有对应订单流消息的订单数
+	Compensated    int // 订单被拒绝、已补偿的消息数
+	StockReturned  int // 补偿时归还了库存的消息数
+
+	Missing           []*SeckillStreamEntry // 有订单流消息但没有订单（已补偿的消息除外）
+	Duplicated        []*Order              // 同一请求号的重复订单，或同一用户超出限购数量的订单
+	Orphaned          []*Order              // 秒杀用户的订单没有对应的订单流消息
+	UsersWithoutEntry []string              // uid2req 中有记录但订单流中没有消息的用户
+}
+
+// Consistent 订单流、uid2req、订单和库存是否完全一致
+// 已补偿的消息没有订单，归还的库存不计入已售数量
+func (r *SeckillReconcileReport) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Duplicated) == 0 && len(r.Orphaned) == 0 &&
+		len(r.UsersWithoutEntry) == 0 &&
+		int(r.Sold) == r.StreamEntries-r.StockReturned &&
+		r.OrdersCreated == r.StreamEntries-r.Compensated
+}
+
+// ReconcileSeckill 对账商品当前活动的 Redis 数据与订单
+// 订单表没有来源字段，孤儿订单只在 uid2req 中的用户、活动开始后创建的订单里查找，避免把普通购买算进来
+func (uc *SeckillUsecase) ReconcileSeckill(ctx context.Context, productID int64) (*SeckillReconcileReport, error) {
+	if productID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+
+	report := &SeckillReconcileReport{ProductID: productID, PerUserLimit: 1}
+	var since time.Time
+
+	// 活动参数以 Postgres 记录为准，没有记录时读取 Redis 中的活动参数
+	campaign, err := uc.campaignRepo.GetCurrent(ctx, productID)
+	if err != nil && err != ErrNoActiveSeckill {
+		return nil, err
+	}
+	live, err := uc.repo.GetSeckill(ctx, productID)
+	if err != nil && err != ErrNoActiveSeckill {
+		return nil, err
+	}
+	switch {
+	case campaign != nil:
+		report.CampaignID = campaign.ID
+		report.Price = campaign.Price
+		report.PerUserLimit = campaign.PerUserLimit
+		report.InitialStock = campaign.InitialStock
+		report.Sold = campaign.Sold
+		if campaign.Status == SeckillStatusActive && live != nil {
+			report.Sold = campaign.InitialStock - live.Stock
+		}
+		since = campaign.StartAt
+	case live != nil:
+		report.CampaignID = live.ID
+		report.Price = live.Price
+		report.PerUserLimit = live.PerUserLimit
+		report.InitialStock = live.InitialStock
+		report.Sold = live.InitialStock - live.Stock
+	default:
+		return nil, ErrNoActiveSeckill
+	}
+
+	entries, err := uc.repo.ScanStream(ctx, productID)
+	if err != nil {
+		uc.log.Errorf("scan seckill stream failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+	uid2req, err := uc.repo.ScanUID2Req(ctx, productID)
+	if err != nil {
+		uc.log.Errorf("scan seckill uid2req failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+	compensated, err := uc.repo.ScanCompensated(ctx, productID)
+	if err != nil {
+		uc.log.Errorf("scan seckill compensations failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+	orders, err := uc.orderRepo.ListByProduct(ctx, productID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report.StreamEntries = len(entries)
+	report.UID2ReqEntries = len(uid2req)
+	reconcileSeckillOrders(report, entries, uid2req, compensated, orders)
+
+	uc.log.Infof("seckill reconciled: productID=%d sold=%d entries=%d orders=%d missing=%d duplicated=%d orphaned=%d",
+		productID, report.Sold, report.StreamEntries, report.OrdersCreated,
+		len(report.Missing), len(report.Duplicated), len(report.Orphaned))
+	return report, nil
+}
+
+// reconcileSeckillOrders 按 req_id 和用户比对订单流消息与订单，结果写入 report
+// compensated 为已补偿的消息ID -> 是否归还了库存
+func reconcileSeckillOrders(report *SeckillReconcileReport, entries []*SeckillStreamEntry, uid2req map[string]int64, compensated map[string]bool, orders []*Order) {
+	byReqID := make(map[int64][]*Order, len(orders))
+	for _, order := range orders {
+		byReqID[order.ReqID] = append(byReqID[order.ReqID], order)
+	}
+
+	streamUsers := make(map[string]struct{}, len(entries))
+	matched := make(map[int64]struct{}, len(entries))
+	perUser := make(map[string]int)
+	for _, entry := range entries {
+		streamUsers[entry.UserID] = struct{}{}
+		// Redis 生成的 Stream ID 总能打包，解析失败按缺失订单上报
+		reqID, err := SeckillOrderReqID(entry.StreamID)
+		found := byReqID[reqID]
+		if err != nil || len(found) == 0 {
+			if stockReturned, ok := compensated[entry.StreamID]; ok {
+				report.Compensated++
+				if stockReturned {
+					report.StockReturned++
+				}
+				continue
+			}
+			report.Missing = append(report.Missing, entry)
+			continue
+		}
+		matched[reqID] = struct{}{}
+		report.OrdersCreated++
+		// 唯一索引 (product_id, req_id) 正常情况下不会出现重复
+		report.Duplicated = append(report.Duplicated, found[1:]...)
+
+		perUser[found[0].UserID]++
+		if perUser[found[0].UserID] > int(report.PerUserLimit) {
+			report.Duplicated = append(report.Duplicated, found[0])
+		}
+	}
+
+	for _, order := range orders {
+		if _, ok := matched[order.ReqID]; ok {
+			continue
+		}
+		if _, ok := uid2req[order.UserID]; ok {
+			report.Orphaned = append(report.Orphaned, order)
+		}
+	}
+
+	// 补偿时已释放名额的用户不在 uid2req 中
+	for userID := range uid2req {
+		if _, ok := streamUsers[userID]; !ok {
+			report.UsersWithoutEntry = append(report.UsersWithoutEntry, userID)
+		}
+	}
+	sort.Strings(report.UsersWithoutEntry)
+}
@@ -0,0 +1,242 @@
+package biz
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeOrderRepo struct {
+	orders         []*Order
+	expiryAttempts map[int64]time.Time // 订单 ID -> expiry_attempt_at
+}
+
+func (r *fakeOrderRepo) Create(ctx context.Context, order *Order) error {
+	// 与唯一索引 (product_id, req_id) 一致
+	if existing, _ := r.GetByReqID(ctx, order.ProductID, order.ReqID); existing != nil {
+		return ErrOrderReqIDExists
+	}
+	r.orders = append(r.orders, order)
+	return nil
+}
+
+func (r *fakeOrderRepo) CreateBatch(ctx context.Context, orders []*Order) ([]bool, error) {
+	inserted := make([]bool, len(orders))
+	for i, order := range orders {
+		if existing, _ := r.GetByReqID(ctx, order.ProductID, order.ReqID); existing != nil {
+			continue
+		}
+		r.orders = append(r.orders, order)
+		inserted[i] = true
+	}
+	return inserted, nil
+}
+
+func (r *fakeOrderRepo) GetByID(ctx context.Context, orderID int64) (*Order, error) {
+	for _, o := range r.orders {
+		if o.ID == orderID {
+			return o, nil
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+func (r *fakeOrderRepo) LockByID(ctx context.Context, orderID int64) (*Order, error) {
+	return r.GetByID(ctx, orderID)
+}
+
+func (r *fakeOrderRepo) UpdateStatus(ctx context.Context, orderID int64, from, to string) error {
+	order, err := r.GetByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != from {
+		return ErrOrderStatusConflict
+	}
+	order.Status = to
+	return nil
+}
+
+func (r *fakeOrderRepo) ListByProduct(ctx context.Context, productID int64, since time.Time) ([]*Order, error) {
+	var out []*Order
+	for _, o := range r.orders {
+		if o.ProductID == productID && !o.CreatedAt.Before(since) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeOrderRepo) GetByReqID(ctx context.Context, productID int64, reqID int64) (*Order, error) {
+	for _, o := range r.orders {
+		if o.ProductID == productID && o.ReqID == reqID {
+			return o, nil
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+func (r *fakeOrderRepo) SetPaymentID(ctx context.Context, orderID int64, paymentID string) error {
+	order, err := r.GetByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.PaymentID != "" && order.PaymentID != paymentID {
+		return ErrOrderStatusConflict
+	}
+	order.PaymentID = paymentID
+	return nil
+}
+
+func (r *fakeOrderRepo) MarkRefunded(ctx context.Context, orderID int64, from string, refund *OrderRefund) error {
+	if err := r.UpdateStatus(ctx, orderID, from, OrderStatusCancelled); err != nil {
+		return err
+	}
+	order, _ := r.GetByID(ctx, orderID)
+	order.Refund = refund
+	return nil
+}
+
+func (r *fakeOrderRepo) ClaimExpiredPending(ctx context.Context, before time.Time, limit int, lease time.Duration) ([]*Order, error) {
+	now := time.Now()
+	// 与 ORDER BY COALESCE(expiry_attempt_at, created_at) 一致
+	attemptAt := func(o *Order) time.Time {
+		if at, ok := r.expiryAttempts[o.ID]; ok {
+			return at
+		}
+		return o.CreatedAt
+	}
+	var out []*Order
+	for _, o := range r.orders {
+		if at, ok := r.expiryAttempts[o.ID]; o.Status == OrderStatusPending && o.CreatedAt.Before(before) && (!ok || !at.After(now)) {
+			out = append(out, o)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return attemptAt(out[i]).Before(attemptAt(out[j])) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	if r.expiryAttempts == nil {
+		r.expiryAttempts = map[int64]time.Time{}
+	}
+	for _, o := range out {
+		r.expiryAttempts[o.ID] = now.Add(lease)
+	}
+	return out, nil
+}
+
+func mustSeckillOrderReqID(streamID string) int64 {
+	reqID, err := SeckillOrderReqID(streamID)
+	if err != nil {
+		panic(err)
+	}
+	return reqID
+}
+
+func TestSeckillOrderReqID(t *testing.T) {
+	for _, id := range []string{"0-0", "1-1", "1700000000000-0", "1700000000000-2097151", "4398046511103-7"} {
+		reqID := mustSeckillOrderReqID(id)
+		if reqID < 0 {
+			t.Errorf("req_id(%s) = %d, want non-negative", id, reqID)
+		}
+		if got := SeckillStreamIDFromReqID(reqID); got != id {
+			t.Errorf("stream id round trip = %s, want %s", got, id)
+		}
+	}
+	// 打包保持 Stream 顺序，不同消息的 req_id 必然不同
+	if mustSeckillOrderReqID("1700000000000-1") <= mustSeckillOrderReqID("1700000000000-0") ||
+		mustSeckillOrderReqID("1700000000001-0") <= mustSeckillOrderReqID("1700000000000-2097151") {
+		t.Error("req_id does not preserve stream order")
+	}
+
+	for _, id := range []string{"", "1700000000000", "abc-0", "1-x", "-1-0", "1700000000000-2097152", "4398046511104-0"} {
+		if _, err := SeckillOrderReqID(id); err == nil {
+			t.Errorf("SeckillOrderReqID(%q) succeeded, want error", id)
+		}
+	}
+}
+
+func TestSeckillUsecase_ReconcileSeckill(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+
+	redis := &fakeSeckillProductRepo{
+		stock: map[int64]int32{1001: 6},
+		// 10 件卖出 4 件：u1、u2 已下单，u3 的订单缺失，u4 只写入了 uid2req
+		uid2req: map[string]int64{"u1": 1, "u2": 2, "u3": 3, "u4": 4},
+		entries: []*SeckillStreamEntry{
+			{StreamID: "1700000000000-0", UserID: "u1", Req: 1},
+			{StreamID: "1700000000000-1", UserID: "u2", Req: 2},
+			{StreamID: "1700000000001-0", UserID: "u3", Req: 3},
+		},
+	}
+	campaigns := &fakeSeckillCampaignRepo{campaigns: []*SeckillCampaign{{
+		ID: 7, ProductID: 1001, InitialStock: 10, PerUserLimit: 1, Price: 990,
+		StartAt: start, Status: SeckillStatusActive,
+	}}}
+	orders := &fakeOrderRepo{orders: []*Order{
+		{ID: 1, ProductID: 1001, UserID: "u1", ReqID: mustSeckillOrderReqID("1700000000000-0"), CreatedAt: start.Add(time.Minute)},
+		{ID: 2, ProductID: 1001, UserID: "u2", ReqID: mustSeckillOrderReqID("1700000000000-1"), CreatedAt: start.Add(time.Minute)},
+		// u1 没有对应消息的订单（孤儿）
+		{ID: 3, ProductID: 1001, UserID: "u1", ReqID: 42, CreatedAt: start.Add(time.Minute)},
+		// 普通用户的订单和活动开始前的订单不参与对账
+		{ID: 4, ProductID: 1001, UserID: "other", ReqID: 43, CreatedAt: start.Add(time.Minute)},
+		{ID: 5, ProductID: 1001, UserID: "u2", ReqID: 44, CreatedAt: start.Add(-time.Minute)},
+	}}
+	uc := NewSeckillUsecase(redis, campaigns, &fakeSeckillCompensationRepo{}, orders, fakeTx{}, log.DefaultLogger)
+
+	report, err := uc.ReconcileSeckill(ctx, 1001)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.CampaignID != 7 || report.Price != 990 || report.Sold != 4 {
+		t.Errorf("report campaign = id %d price %d sold %d, want 7 990 4", report.CampaignID, report.Price, report.Sold)
+	}
+	if report.StreamEntries != 3 || report.UID2ReqEntries != 4 || report.OrdersCreated != 2 {
+		t.Errorf("report counts = entries %d uid2req %d orders %d, want 3 4 2",
+			report.StreamEntries, report.UID2ReqEntries, report.OrdersCreated)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].UserID != "u3" {
+		t.Errorf("missing = %+v, want the u3 entry", report.Missing)
+	}
+	if len(report.Orphaned) != 1 || report.Orphaned[0].ID != 3 {
+		t.Errorf("orphaned = %+v, want order 3", report.Orphaned)
+	}
+	if len(report.Duplicated) != 0 {
+		t.Errorf("duplicated = %+v, want none", report.Duplicated)
+	}
+	if !reflect.DeepEqual(report.UsersWithoutEntry, []string{"u4"}) {
+		t.Errorf("users without entry = %v, want [u4]", report.UsersWithoutEntry)
+	}
+	if report.Consistent() {
+		t.Error("report is consistent, want inconsistent")
+	}
+
+	if _, err := uc.ReconcileSeckill(ctx, 1002); err != ErrNoActiveSeckill {
+		t.Errorf("reconcile without campaign: err = %v, want %v", err, ErrNoActiveSeckill)
+	}
+}
+
+func TestReconcileSeckillOrders_PerUserLimit(t *testing.T) {
+	report := &SeckillReconcileReport{PerUserLimit: 1}
+	entries := []*SeckillStreamEntry{
+		{StreamID: "1-0", UserID: "u1"},
+		{StreamID: "1-1", UserID: "u1"},
+	}
+	orders := []*Order{
+		{ID: 1, UserID: "u1", ReqID: mustSeckillOrderReqID("1-0")},
+		{ID: 2, UserID: "u1", ReqID: mustSeckillOrderReqID("1-1")},
+	}
+	reconcileSeckillOrders(report, entries, map[string]int64{"u1": 2}, nil, orders)
+
+	if report.OrdersCreated != 2 {
+		t.Errorf("orders created = %d, want 2", report.OrdersCreated)
+	}
+	if len(report.Duplicated) != 1 || report.Duplicated[0].ID != 2 {
+		t.Errorf("duplicated = %+v, want order 2 beyond the per-user limit", report.Duplicated)
+	}
+}
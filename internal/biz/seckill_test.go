@@ -0,0 +1,416 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestSeckillKeysFor(t *testing.T) {
+	got := SeckillKeysFor(1001)
+	want := SeckillKeys{
+		Stock:       "seckill:stock:{1001}",
+		ReqSeq:      "req:seq:{1001}",
+		UID2Req:     "uid2req:{1001}",
+		Stream:      "stream:orders:{1001}",
+		Meta:        "seckill:meta:{1001}",
+		UIDCnt:      "uidcnt:{1001}",
+		DLQ:         "stream:orders:dlq:{1001}",
+		Compensated: "seckill:compensated:{1001}",
+	}
+	if got != want {
+		t.Errorf("SeckillKeysFor(1001) = %+v, want %+v", got, want)
+	}
+	if SeckillKeysFor(1002).Stream == got.Stream {
+		t.Errorf("campaigns of different products share stream %s", got.Stream)
+	}
+}
+
+type fakeTx struct{}
+
+func (fakeTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeSeckillProductRepo struct {
+	stock   map[int64]int32
+	drained map[int64]bool
+	reqSeq  int64
+	uid2req map[string]int64 // 每人限购 1 件
+	entries []*SeckillStreamEntry
+	dlq     []*SeckillDeadLetter
+	closed  map[int64]bool
+	// 商品ID -> 结束售卖时的剩余库存
+	remaining map[int64]int32
+	// 已补偿的消息ID -> 补偿结果
+	compensated map[string]*SeckillCompensation
+	// 商品ID -> 已初始化的活动ID；initErr 非 nil 时初始化失败
+	campaignIDs map[int64]int64
+	initErr     error
+}
+
+func (r *fakeSeckillProductRepo) InitSeckill(ctx context.Context, campaign *SeckillCampaign) error {
+	if r.initErr != nil {
+		return r.initErr
+	}
+	if r.campaignIDs == nil {
+		r.campaignIDs = map[int64]int64{}
+	}
+	if r.campaignIDs[campaign.ProductID] == campaign.ID {
+		return nil
+	}
+	r.campaignIDs[campaign.ProductID] = campaign.ID
+	r.stock[campaign.ProductID] = campaign.InitialStock
+	delete(r.closed, campaign.ProductID)
+	delete(r.remaining, campaign.ProductID)
+	return nil
+}
+
+func (r *fakeSeckillProductRepo) ListProductIDs(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	for id := range r.stock {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *fakeSeckillProductRepo) GetSeckill(ctx context.Context, productID int64) (*SeckillCampaign, error) {
+	stock, ok := r.stock[productID]
+	if !ok {
+		return nil, ErrNoActiveSeckill
+	}
+	return &SeckillCampaign{ProductID: productID, Stock: stock}, nil
+}
+
+func (r *fakeSeckillProductRepo) CloseSeckill(ctx context.Context, productID int64) (int32, error) {
+	if remaining, ok := r.remaining[productID]; ok {
+		return remaining, nil
+	}
+	remaining := r.stock[productID]
+	r.stock[productID] = 0
+	if r.closed == nil {
+		r.closed = map[int64]bool{}
+		r.remaining = map[int64]int32{}
+	}
+	r.closed[productID] = true
+	r.remaining[productID] = remaining
+	return remaining, nil
+}
+
+func (r *fakeSeckillProductRepo) StreamDrained(ctx context.Context, productID int64) (bool, error) {
+	return r.drained[productID], nil
+}
+
+func (r *fakeSeckillProductRepo) ClearSeckill(ctx context.Context, productID int64) error {
+	delete(r.stock, productID)
+	delete(r.closed, productID)
+	delete(r.remaining, productID)
+	return nil
+}
+
+func (r *fakeSeckillProductRepo) ScanStream(ctx context.Context, productID int64) ([]*SeckillStreamEntry, error) {
+	return r.entries, nil
+}
+
+func (r *fakeSeckillProductRepo) ScanUID2Req(ctx context.Context, productID int64) (map[string]int64, error) {
+	return r.uid2req, nil
+}
+
+func (r *fakeSeckillProductRepo) AddDeadLetter(ctx context.Context, productID int64, letter *SeckillDeadLetter) error {
+	letter.ID = fmt.Sprintf("%d-0", len(r.dlq)+1)
+	r.dlq = append(r.dlq, letter)
+	return nil
+}
+
+func (r *fakeSeckillProductRepo) ListDeadLetters(ctx context.Context, productID int64, after string, limit int64) ([]*SeckillDeadLetter, error) {
+	var out []*SeckillDeadLetter
+	for _, letter := range r.dlq {
+		if letter.ID > after && int64(len(out)) < limit {
+			out = append(out, letter)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeSeckillProductRepo) GetDeadLetter(ctx context.Context, productID int64, id string) (*SeckillDeadLetter, error) {
+	for _, letter := range r.dlq {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+	return nil, ErrSeckillDeadLetterNotFound
+}
+
+func (r *fakeSeckillProductRepo) DeleteDeadLetter(ctx context.Context, productID int64, id string) error {
+	for i, letter := range r.dlq {
+		if letter.ID == id {
+			r.dlq = append(r.dlq[:i], r.dlq[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSeckillDeadLetterNotFound
+}
+
+func (r *fakeSeckillProductRepo) Compensate(ctx context.Context, compensation *SeckillCompensation) (bool, error) {
+	if r.compensated == nil {
+		r.compensated = map[string]*SeckillCompensation{}
+	}
+	if prev, ok := r.compensated[compensation.StreamID]; ok {
+		compensation.StockReturned = prev.StockReturned
+		compensation.SlotReleased = prev.SlotReleased
+		return false, nil
+	}
+	if _, ok := r.stock[compensation.ProductID]; ok && !r.closed[compensation.ProductID] {
+		r.stock[compensation.ProductID]++
+		compensation.StockReturned = true
+	}
+	if _, ok := r.uid2req[compensation.UserID]; ok {
+		delete(r.uid2req, compensation.UserID)
+		compensation.SlotReleased = true
+	}
+	stored := *compensation
+	r.compensated[compensation.StreamID] = &stored
+	return true, nil
+}
+
+func (r *fakeSeckillProductRepo) ScanCompensated(ctx context.Context, productID int64) (map[string]bool, error) {
+	out := make(map[string]bool, len(r.compensated))
+	for streamID, c := range r.compensated {
+		out[streamID] = c.StockReturned
+	}
+	return out, nil
+}
+
+func (r *fakeSeckillProductRepo) Purchase(ctx context.Context, productID int64, userID string) (*SeckillPurchaseResult, error) {
+	stock, ok := r.stock[productID]
+	if !ok {
+		return nil, ErrNoActiveSeckill
+	}
+	if req, ok := r.uid2req[userID]; ok {
+		return &SeckillPurchaseResult{Result: SeckillPurchaseAlreadyBought, ReqID: req}, nil
+	}
+	if stock <= 0 {
+		return &SeckillPurchaseResult{Result: SeckillPurchaseSoldOut}, nil
+	}
+	r.stock[productID] = stock - 1
+	r.reqSeq++
+	r.uid2req[userID] = r.reqSeq
+	return &SeckillPurchaseResult{Result: SeckillPurchaseAccepted, ReqID: r.reqSeq}, nil
+}
+
+type fakeSeckillCompensationRepo struct {
+	records map[string]*SeckillCompensation // 消息ID -> 审计记录
+}
+
+func (r *fakeSeckillCompensationRepo) Create(ctx context.Context, compensation *SeckillCompensation) error {
+	if r.records == nil {
+		r.records = map[string]*SeckillCompensation{}
+	}
+	if _, ok := r.records[compensation.StreamID]; !ok {
+		stored := *compensation
+		r.records[compensation.StreamID] = &stored
+	}
+	return nil
+}
+
+type fakeSeckillCampaignRepo struct {
+	campaigns []*SeckillCampaign
+}
+
+func (r *fakeSeckillCampaignRepo) Create(ctx context.Context, campaign *SeckillCampaign) error {
+	campaign.ID = int64(len(r.campaigns) + 1)
+	stored := *campaign
+	r.campaigns = append(r.campaigns, &stored)
+	return nil
+}
+
+func (r *fakeSeckillCampaignRepo) GetCurrent(ctx context.Context, productID int64) (*SeckillCampaign, error) {
+	for _, c := range r.campaigns {
+		if c.ProductID == productID && (c.Status == SeckillStatusScheduled || c.Status == SeckillStatusActive || c.Status == SeckillStatusEnded) {
+			copied := *c
+			return &copied, nil
+		}
+	}
+	return nil, ErrNoActiveSeckill
+}
+
+func (r *fakeSeckillCampaignRepo) List(ctx context.Context) ([]*SeckillCampaign, error) {
+	return r.filter(func(c *SeckillCampaign) bool {
+		return c.Status != SeckillStatusSettled && c.Status != SeckillStatusCancelled
+	}), nil
+}
+
+func (r *fakeSeckillCampaignRepo) ListToStart(ctx context.Context, now time.Time) ([]*SeckillCampaign, error) {
+	return r.filter(func(c *SeckillCampaign) bool { return c.Status == SeckillStatusScheduled && !c.StartAt.After(now) }), nil
+}
+
+func (r *fakeSeckillCampaignRepo) ListToEnd(ctx context.Context, now time.Time) ([]*SeckillCampaign, error) {
+	return r.filter(func(c *SeckillCampaign) bool {
+		return c.Status == SeckillStatusActive && c.EndAt != nil && !c.EndAt.After(now)
+	}), nil
+}
+
+func (r *fakeSeckillCampaignRepo) ListEnded(ctx context.Context) ([]*SeckillCampaign, error) {
+	return r.filter(func(c *SeckillCampaign) bool { return c.Status == SeckillStatusEnded }), nil
+}
+
+func (r *fakeSeckillCampaignRepo) UpdateStatus(ctx context.Context, campaign *SeckillCampaign, from string) error {
+	for _, c := range r.campaigns {
+		if c.ID == campaign.ID {
+			if c.Status != from {
+				return ErrSeckillCampaignConflict
+			}
+			c.Status = campaign.Status
+			c.Sold = campaign.Sold
+			return nil
+		}
+	}
+	return ErrSeckillCampaignConflict
+}
+
+func (r *fakeSeckillCampaignRepo) filter(match func(c *SeckillCampaign) bool) []*SeckillCampaign {
+	var out []*SeckillCampaign
+	for _, c := range r.campaigns {
+		if match(c) {
+			copied := *c
+			out = append(out, &copied)
+		}
+	}
+	return out
+}
+
+func TestSeckillUsecase_Schedule(t *testing.T) {
+	ctx := context.Background()
+	redis := &fakeSeckillProductRepo{stock: map[int64]int32{}, drained: map[int64]bool{}}
+	campaigns := &fakeSeckillCampaignRepo{}
+	uc := NewSeckillUsecase(redis, campaigns, &fakeSeckillCompensationRepo{}, nil, fakeTx{}, log.DefaultLogger)
+
+	start := time.Now().Add(time.Hour)
+	end := start.Add(time.Hour)
+	campaign, err := uc.InitSeckill(ctx, &SeckillCampaign{ProductID: 1, InitialStock: 10, StartAt: start, EndAt: &end})
+	if err != nil {
+		t.Fatalf("InitSeckill() error = %v", err)
+	}
+	if campaign.Status != SeckillStatusScheduled || campaign.PerUserLimit != 1 {
+		t.Fatalf("InitSeckill() = %+v, want SCHEDULED with per-user limit 1", campaign)
+	}
+	if _, ok := redis.stock[1]; ok {
+		t.Fatalf("stock armed before start time")
+	}
+	if _, err := uc.InitSeckill(ctx, &SeckillCampaign{ProductID: 1, InitialStock: 5}); err != ErrSeckillCampaignExists {
+		t.Fatalf("second InitSeckill() error = %v, want ErrSeckillCampaignExists", err)
+	}
+
+	// 到开始时间但 Redis 开放失败：状态恢复为 SCHEDULED，下一轮重试
+	redis.initErr = errors.New("redis unavailable")
+	res, err := uc.RunSchedule(ctx, start)
+	if err != nil || res.Started != 0 || campaigns.campaigns[0].Status != SeckillStatusScheduled {
+		t.Fatalf("RunSchedule(redis down) = %+v, %v status = %s, want nothing started and SCHEDULED",
+			res, err, campaigns.campaigns[0].Status)
+	}
+
+	// 开放库存
+	redis.initErr = nil
+	res, err = uc.RunSchedule(ctx, start)
+	if err != nil || res.Started != 1 {
+		t.Fatalf("RunSchedule(start) = %+v, %v, want 1 started", res, err)
+	}
+	if redis.stock[1] != 10 {
+		t.Fatalf("stock = %d, want 10", redis.stock[1])
+	}
+
+	// 售出 3 件后到结束时间：停止售卖并记录已售数量，订单流未消费完时不结算
+	redis.stock[1] = 7
+	res, err = uc.RunSchedule(ctx, end)
+	if err != nil || res.Ended != 1 || res.Settled != 0 {
+		t.Fatalf("RunSchedule(end) = %+v, %v, want 1 ended", res, err)
+	}
+	if redis.stock[1] != 0 || campaigns.campaigns[0].Sold != 3 {
+		t.Fatalf("stock = %d sold = %d, want 0 and 3", redis.stock[1], campaigns.campaigns[0].Sold)
+	}
+
+	// 结束活动的事务提交失败后重试：Redis 已关闭（库存为 0），已售数量仍按关闭前的库存计算
+	campaigns.campaigns[0].Status = SeckillStatusActive
+	campaigns.campaigns[0].Sold = 0
+	res, err = uc.RunSchedule(ctx, end)
+	if err != nil || res.Ended != 1 || campaigns.campaigns[0].Sold != 3 {
+		t.Fatalf("RunSchedule(end retry) = %+v, %v sold = %d, want 1 ended with 3 sold", res, err, campaigns.campaigns[0].Sold)
+	}
+
+	// 订单流消费完：结算并清理 Redis
+	redis.drained[1] = true
+	res, err = uc.RunSchedule(ctx, end)
+	if err != nil || res.Settled != 1 {
+		t.Fatalf("RunSchedule(drained) = %+v, %v, want 1 settled", res, err)
+	}
+	if _, ok := redis.stock[1]; ok || campaigns.campaigns[0].Status != SeckillStatusSettled {
+		t.Fatalf("campaign not settled: status=%s", campaigns.campaigns[0].Status)
+	}
+}
+
+func TestSeckillUsecase_Purchase(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSeckillProductRepo{
+		stock:   map[int64]int32{1001: 1},
+		uid2req: map[string]int64{},
+	}
+	uc := NewSeckillUsecase(repo, &fakeSeckillCampaignRepo{}, &fakeSeckillCompensationRepo{}, nil, fakeTx{}, log.DefaultLogger)
+
+	if _, err := uc.Purchase(ctx, 0, "u1"); err != ErrInvalidProductID {
+		t.Fatalf("purchase with invalid product: err = %v, want %v", err, ErrInvalidProductID)
+	}
+	if _, err := uc.Purchase(ctx, 1001, ""); err != ErrInvalidUserID {
+		t.Fatalf("purchase with empty user: err = %v, want %v", err, ErrInvalidUserID)
+	}
+	if _, err := uc.Purchase(ctx, 1002, "u1"); err != ErrNoActiveSeckill {
+		t.Fatalf("purchase without campaign: err = %v, want %v", err, ErrNoActiveSeckill)
+	}
+
+	cases := []struct {
+		userID string
+		result string
+		reqID  int64
+	}{
+		{"u1", SeckillPurchaseAccepted, 1},
+		{"u1", SeckillPurchaseAlreadyBought, 1},
+		{"u2", SeckillPurchaseSoldOut, 0},
+	}
+	for _, tc := range cases {
+		res, err := uc.Purchase(ctx, 1001, tc.userID)
+		if err != nil {
+			t.Fatalf("purchase %s: %v", tc.userID, err)
+		}
+		if res.Result != tc.result || res.ReqID != tc.reqID {
+			t.Errorf("purchase %s = %+v, want result=%s reqID=%d", tc.userID, res, tc.result, tc.reqID)
+		}
+	}
+}
+
+func TestValidateSeckillCampaign(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	tests := []struct {
+		name     string
+		campaign SeckillCampaign
+		want     error
+	}{
+		{"ok", SeckillCampaign{ProductID: 1, InitialStock: 10, EndAt: &future}, nil},
+		{"no product", SeckillCampaign{InitialStock: 10}, ErrInvalidProductID},
+		{"no stock", SeckillCampaign{ProductID: 1}, ErrInvalidStock},
+		{"limit above stock", SeckillCampaign{ProductID: 1, InitialStock: 2, PerUserLimit: 3}, ErrInvalidSeckillLimit},
+		{"negative price", SeckillCampaign{ProductID: 1, InitialStock: 2, Price: -1}, ErrInvalidPrice},
+		{"end in past", SeckillCampaign{ProductID: 1, InitialStock: 2, EndAt: &past}, ErrInvalidSeckillWindow},
+		{"end before start", SeckillCampaign{ProductID: 1, InitialStock: 2, StartAt: future.Add(time.Hour), EndAt: &future}, ErrInvalidSeckillWindow},
+	}
+
+	for _, tt := range tests {
+		if got := validateSeckillCampaign(&tt.campaign, now); got != tt.want {
+			t.Errorf("%s: validateSeckillCampaign() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -79,6 +79,8 @@ type Server struct {
 	Http          *Server_HTTP           `protobuf:"bytes,1,opt,name=http,proto3" json:"http,omitempty"`
 	Grpc          *Server_GRPC           `protobuf:"bytes,2,opt,name=grpc,proto3" json:"grpc,omitempty"`
 	Seckill       *Server_Seckill        `protobuf:"bytes,3,opt,name=seckill,proto3" json:"seckill,omitempty"`
+	Outbox        *Server_Outbox         `protobuf:"bytes,4,opt,name=outbox,proto3" json:"outbox,omitempty"`
+	OrderExpiry   *Server_OrderExpiry    `protobuf:"bytes,5,opt,name=order_expiry,json=orderExpiry,proto3" json:"order_expiry,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -134,11 +136,27 @@ func (x *Server) GetSeckill() *Server_Seckill {
 	return nil
 }
 
+func (x *Server) GetOutbox() *Server_Outbox {
+	if x != nil {
+		return x.Outbox
+	}
+	return nil
+}
+
+func (x *Server) GetOrderExpiry() *Server_OrderExpiry {
+	if x != nil {
+		return x.OrderExpiry
+	}
+	return nil
+}
+
 type Data struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Database      *Data_Database         `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
 	Redis         *Data_Redis            `protobuf:"bytes,2,opt,name=redis,proto3" json:"redis,omitempty"`
 	Rabbitmq      *Data_RabbitMQ         `protobuf:"bytes,3,opt,name=rabbitmq,proto3" json:"rabbitmq,omitempty"`
+	IdGenerator   *Data_IDGenerator      `protobuf:"bytes,4,opt,name=id_generator,json=idGenerator,proto3" json:"id_generator,omitempty"`
+	Payment       *Data_Payment          `protobuf:"bytes,5,opt,name=payment,proto3" json:"payment,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -194,6 +212,20 @@ func (x *Data) GetRabbitmq() *Data_RabbitMQ {
 	return nil
 }
 
+func (x *Data) GetIdGenerator() *Data_IDGenerator {
+	if x != nil {
+		return x.IdGenerator
+	}
+	return nil
+}
+
+func (x *Data) GetPayment() *Data_Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
 type Server_HTTP struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
@@ -315,10 +347,16 @@ func (x *Server_GRPC) GetTimeout() *durationpb.Duration {
 }
 
 type Server_Seckill struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductIds    []int64                `protobuf:"varint,1,rep,packed,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ProductIds       []int64                `protobuf:"varint,1,rep,packed,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
+	ScheduleInterval *durationpb.Duration   `protobuf:"bytes,2,opt,name=schedule_interval,json=scheduleInterval,proto3" json:"schedule_interval,omitempty"` // 活动调度器轮询间隔（到点开启/结束活动）
+	MaxDeliveries    int32                  `protobuf:"varint,3,opt,name=max_deliveries,json=maxDeliveries,proto3" json:"max_deliveries,omitempty"`         // 订单流消息最大投递次数，超过后转入死信队列
+	Workers          int32                  `protobuf:"varint,4,opt,name=workers,proto3" json:"workers,omitempty"`                                          // 每个商品并发处理订单流消息的 worker 数
+	Consumers        int32                  `protobuf:"varint,5,opt,name=consumers,proto3" json:"consumers,omitempty"`                                      // 每个实例在消费者组中注册的消费者数（各自 XREADGROUP 拉取）
+	BatchSize        int32                  `protobuf:"varint,6,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`                     // 每批交给 worker 的消息数（整批写入订单）
+	ClaimIdle        *durationpb.Duration   `protobuf:"bytes,7,opt,name=claim_idle,json=claimIdle,proto3" json:"claim_idle,omitempty"`                      // 消息空闲超过该时长后被重新认领，需大于排队等待加处理一批的最长耗时
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *Server_Seckill) Reset() {
@@ -358,6 +396,202 @@ func (x *Server_Seckill) GetProductIds() []int64 {
 	return nil
 }
 
+func (x *Server_Seckill) GetScheduleInterval() *durationpb.Duration {
+	if x != nil {
+		return x.ScheduleInterval
+	}
+	return nil
+}
+
+func (x *Server_Seckill) GetMaxDeliveries() int32 {
+	if x != nil {
+		return x.MaxDeliveries
+	}
+	return 0
+}
+
+func (x *Server_Seckill) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *Server_Seckill) GetConsumers() int32 {
+	if x != nil {
+		return x.Consumers
+	}
+	return 0
+}
+
+func (x *Server_Seckill) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *Server_Seckill) GetClaimIdle() *durationpb.Duration {
+	if x != nil {
+		return x.ClaimIdle
+	}
+	return nil
+}
+
+// Outbox 发件箱中继配置
+type Server_Outbox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interval      *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`                           // 轮询间隔
+	BatchSize     int32                  `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`       // 单次认领的消息数
+	MaxAttempts   int32                  `protobuf:"varint,3,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"` // 最大投递次数，超过后标记为 DEAD
+	BaseBackoff   *durationpb.Duration   `protobuf:"bytes,4,opt,name=base_backoff,json=baseBackoff,proto3" json:"base_backoff,omitempty"`  // 首次重试退避
+	MaxBackoff    *durationpb.Duration   `protobuf:"bytes,5,opt,name=max_backoff,json=maxBackoff,proto3" json:"max_backoff,omitempty"`     // 最大重试退避
+	Lease         *durationpb.Duration   `protobuf:"bytes,6,opt,name=lease,proto3" json:"lease,omitempty"`                                 // 认领租期（租期内其他副本不会重复投递）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Server_Outbox) Reset() {
+	*x = Server_Outbox{}
+	mi := &file_conf_conf_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Server_Outbox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server_Outbox) ProtoMessage() {}
+
+func (x *Server_Outbox) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server_Outbox.ProtoReflect.Descriptor instead.
+func (*Server_Outbox) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 3}
+}
+
+func (x *Server_Outbox) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *Server_Outbox) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *Server_Outbox) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *Server_Outbox) GetBaseBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.BaseBackoff
+	}
+	return nil
+}
+
+func (x *Server_Outbox) GetMaxBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.MaxBackoff
+	}
+	return nil
+}
+
+func (x *Server_Outbox) GetLease() *durationpb.Duration {
+	if x != nil {
+		return x.Lease
+	}
+	return nil
+}
+
+// OrderExpiry 待支付订单过期任务配置
+type Server_OrderExpiry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interval      *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`                     // 轮询间隔
+	Ttl           *durationpb.Duration   `protobuf:"bytes,2,opt,name=ttl,proto3" json:"ttl,omitempty"`                               // 待支付订单的有效期，超过后取消并归还库存
+	BatchSize     int32                  `protobuf:"varint,3,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"` // 单次认领的订单数
+	Lease         *durationpb.Duration   `protobuf:"bytes,4,opt,name=lease,proto3" json:"lease,omitempty"`                           // 认领租期（租期内其他副本不会重复处理，处理失败的订单租期后重试）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Server_OrderExpiry) Reset() {
+	*x = Server_OrderExpiry{}
+	mi := &file_conf_conf_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Server_OrderExpiry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server_OrderExpiry) ProtoMessage() {}
+
+func (x *Server_OrderExpiry) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server_OrderExpiry.ProtoReflect.Descriptor instead.
+func (*Server_OrderExpiry) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 4}
+}
+
+func (x *Server_OrderExpiry) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *Server_OrderExpiry) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *Server_OrderExpiry) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *Server_OrderExpiry) GetLease() *durationpb.Duration {
+	if x != nil {
+		return x.Lease
+	}
+	return nil
+}
+
 type Data_Database struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Driver        string                 `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
@@ -368,7 +602,7 @@ type Data_Database struct {
 
 func (x *Data_Database) Reset() {
 	*x = Data_Database{}
-	mi := &file_conf_conf_proto_msgTypes[6]
+	mi := &file_conf_conf_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -380,7 +614,7 @@ func (x *Data_Database) String() string {
 func (*Data_Database) ProtoMessage() {}
 
 func (x *Data_Database) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[6]
+	mi := &file_conf_conf_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -424,7 +658,7 @@ type Data_Redis struct {
 
 func (x *Data_Redis) Reset() {
 	*x = Data_Redis{}
-	mi := &file_conf_conf_proto_msgTypes[7]
+	mi := &file_conf_conf_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -436,7 +670,7 @@ func (x *Data_Redis) String() string {
 func (*Data_Redis) ProtoMessage() {}
 
 func (x *Data_Redis) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[7]
+	mi := &file_conf_conf_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -499,13 +733,14 @@ type Data_RabbitMQ struct {
 	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
 	Queue         string                 `protobuf:"bytes,2,opt,name=queue,proto3" json:"queue,omitempty"`
 	Exchange      string                 `protobuf:"bytes,3,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	EventQueue    string                 `protobuf:"bytes,4,opt,name=event_queue,json=eventQueue,proto3" json:"event_queue,omitempty"` // 消费资源域实例事件的队列
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Data_RabbitMQ) Reset() {
 	*x = Data_RabbitMQ{}
-	mi := &file_conf_conf_proto_msgTypes[8]
+	mi := &file_conf_conf_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -517,7 +752,7 @@ func (x *Data_RabbitMQ) String() string {
 func (*Data_RabbitMQ) ProtoMessage() {}
 
 func (x *Data_RabbitMQ) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[8]
+	mi := &file_conf_conf_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -554,6 +789,127 @@ func (x *Data_RabbitMQ) GetExchange() string {
 	return ""
 }
 
+func (x *Data_RabbitMQ) GetEventQueue() string {
+	if x != nil {
+		return x.EventQueue
+	}
+	return ""
+}
+
+// IDGenerator 订单 ID 生成器（Snowflake）配置
+type Data_IDGenerator struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      *int32                 `protobuf:"varint,1,opt,name=worker_id,json=workerId,proto3,oneof" json:"worker_id,omitempty"` // worker ID（0-1023），未配置时通过 Redis 租约分配
+	LeaseTtl      *durationpb.Duration   `protobuf:"bytes,2,opt,name=lease_ttl,json=leaseTtl,proto3" json:"lease_ttl,omitempty"`        // Redis 租约有效期
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_IDGenerator) Reset() {
+	*x = Data_IDGenerator{}
+	mi := &file_conf_conf_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_IDGenerator) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_IDGenerator) ProtoMessage() {}
+
+func (x *Data_IDGenerator) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_IDGenerator.ProtoReflect.Descriptor instead.
+func (*Data_IDGenerator) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 3}
+}
+
+func (x *Data_IDGenerator) GetWorkerId() int32 {
+	if x != nil && x.WorkerId != nil {
+		return *x.WorkerId
+	}
+	return 0
+}
+
+func (x *Data_IDGenerator) GetLeaseTtl() *durationpb.Duration {
+	if x != nil {
+		return x.LeaseTtl
+	}
+	return nil
+}
+
+// Payment 支付网关配置
+type Data_Payment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`                          // 支付网关（必填），目前仅支持 fake（本地模拟，仅用于开发测试）
+	WebhookUrl    string                 `protobuf:"bytes,2,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`    // fake：支付成功后回调的地址（如 http://localhost:8002/v1/payments/webhook）
+	SettleAfter   *durationpb.Duration   `protobuf:"bytes,3,opt,name=settle_after,json=settleAfter,proto3" json:"settle_after,omitempty"` // fake：创建支付意图后自动支付成功的延迟，0 表示只能同步确认
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Payment) Reset() {
+	*x = Data_Payment{}
+	mi := &file_conf_conf_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Payment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Payment) ProtoMessage() {}
+
+func (x *Data_Payment) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Payment.ProtoReflect.Descriptor instead.
+func (*Data_Payment) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 4}
+}
+
+func (x *Data_Payment) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Data_Payment) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+func (x *Data_Payment) GetSettleAfter() *durationpb.Duration {
+	if x != nil {
+		return x.SettleAfter
+	}
+	return nil
+}
+
 var File_conf_conf_proto protoreflect.FileDescriptor
 
 const file_conf_conf_proto_rawDesc = "" +
@@ -562,11 +918,14 @@ const file_conf_conf_proto_rawDesc = "" +
 	"kratos.api\x1a\x1egoogle/protobuf/duration.proto\"]\n" +
 	"\tBootstrap\x12*\n" +
 	"\x06server\x18\x01 \x01(\v2\x12.kratos.api.ServerR\x06server\x12$\n" +
-	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\"\x9a\x03\n" +
+	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\"\x84\n" +
+	"\n" +
 	"\x06Server\x12+\n" +
 	"\x04http\x18\x01 \x01(\v2\x17.kratos.api.Server.HTTPR\x04http\x12+\n" +
 	"\x04grpc\x18\x02 \x01(\v2\x17.kratos.api.Server.GRPCR\x04grpc\x124\n" +
-	"\aseckill\x18\x03 \x01(\v2\x1a.kratos.api.Server.SeckillR\aseckill\x1ai\n" +
+	"\aseckill\x18\x03 \x01(\v2\x1a.kratos.api.Server.SeckillR\aseckill\x121\n" +
+	"\x06outbox\x18\x04 \x01(\v2\x19.kratos.api.Server.OutboxR\x06outbox\x12A\n" +
+	"\forder_expiry\x18\x05 \x01(\v2\x1e.kratos.api.Server.OrderExpiryR\vorderExpiry\x1ai\n" +
 	"\x04HTTP\x12\x18\n" +
 	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
@@ -574,14 +933,39 @@ const file_conf_conf_proto_rawDesc = "" +
 	"\x04GRPC\x12\x18\n" +
 	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
-	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1a*\n" +
+	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1a\xaa\x02\n" +
 	"\aSeckill\x12\x1f\n" +
 	"\vproduct_ids\x18\x01 \x03(\x03R\n" +
-	"productIds\"\x90\x04\n" +
+	"productIds\x12F\n" +
+	"\x11schedule_interval\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x10scheduleInterval\x12%\n" +
+	"\x0emax_deliveries\x18\x03 \x01(\x05R\rmaxDeliveries\x12\x18\n" +
+	"\aworkers\x18\x04 \x01(\x05R\aworkers\x12\x1c\n" +
+	"\tconsumers\x18\x05 \x01(\x05R\tconsumers\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x06 \x01(\x05R\tbatchSize\x128\n" +
+	"\n" +
+	"claim_idle\x18\a \x01(\v2\x19.google.protobuf.DurationR\tclaimIdle\x1a\xac\x02\n" +
+	"\x06Outbox\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x02 \x01(\x05R\tbatchSize\x12!\n" +
+	"\fmax_attempts\x18\x03 \x01(\x05R\vmaxAttempts\x12<\n" +
+	"\fbase_backoff\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\vbaseBackoff\x12:\n" +
+	"\vmax_backoff\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"maxBackoff\x12/\n" +
+	"\x05lease\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x05lease\x1a\xc1\x01\n" +
+	"\vOrderExpiry\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\x12+\n" +
+	"\x03ttl\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x03 \x01(\x05R\tbatchSize\x12/\n" +
+	"\x05lease\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x05lease\"\xa4\a\n" +
 	"\x04Data\x125\n" +
 	"\bdatabase\x18\x01 \x01(\v2\x19.kratos.api.Data.DatabaseR\bdatabase\x12,\n" +
 	"\x05redis\x18\x02 \x01(\v2\x16.kratos.api.Data.RedisR\x05redis\x125\n" +
-	"\brabbitmq\x18\x03 \x01(\v2\x19.kratos.api.Data.RabbitMQR\brabbitmq\x1a:\n" +
+	"\brabbitmq\x18\x03 \x01(\v2\x19.kratos.api.Data.RabbitMQR\brabbitmq\x12?\n" +
+	"\fid_generator\x18\x04 \x01(\v2\x1c.kratos.api.Data.IDGeneratorR\vidGenerator\x122\n" +
+	"\apayment\x18\x05 \x01(\v2\x18.kratos.api.Data.PaymentR\apayment\x1a:\n" +
 	"\bDatabase\x12\x16\n" +
 	"\x06driver\x18\x01 \x01(\tR\x06driver\x12\x16\n" +
 	"\x06source\x18\x02 \x01(\tR\x06source\x1a\xdf\x01\n" +
@@ -591,11 +975,23 @@ const file_conf_conf_proto_rawDesc = "" +
 	"\bpassword\x18\x03 \x01(\tR\bpassword\x12\x0e\n" +
 	"\x02db\x18\x04 \x01(\x05R\x02db\x12<\n" +
 	"\fread_timeout\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\vreadTimeout\x12>\n" +
-	"\rwrite_timeout\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x1aN\n" +
+	"\rwrite_timeout\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x1ao\n" +
 	"\bRabbitMQ\x12\x10\n" +
 	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
 	"\x05queue\x18\x02 \x01(\tR\x05queue\x12\x1a\n" +
-	"\bexchange\x18\x03 \x01(\tR\bexchangeB\x1cZ\x1aproduct/internal/conf;confb\x06proto3"
+	"\bexchange\x18\x03 \x01(\tR\bexchange\x12\x1f\n" +
+	"\vevent_queue\x18\x04 \x01(\tR\n" +
+	"eventQueue\x1au\n" +
+	"\vIDGenerator\x12 \n" +
+	"\tworker_id\x18\x01 \x01(\x05H\x00R\bworkerId\x88\x01\x01\x126\n" +
+	"\tlease_ttl\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\bleaseTtlB\f\n" +
+	"\n" +
+	"_worker_id\x1a\x84\x01\n" +
+	"\aPayment\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x1f\n" +
+	"\vwebhook_url\x18\x02 \x01(\tR\n" +
+	"webhookUrl\x12<\n" +
+	"\fsettle_after\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\vsettleAfterB\x1cZ\x1aproduct/internal/conf;confb\x06proto3"
 
 var (
 	file_conf_conf_proto_rawDescOnce sync.Once
@@ -609,7 +1005,7 @@ func file_conf_conf_proto_rawDescGZIP() []byte {
 	return file_conf_conf_proto_rawDescData
 }
 
-var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_conf_conf_proto_goTypes = []any{
 	(*Bootstrap)(nil),           // 0: kratos.api.Bootstrap
 	(*Server)(nil),              // 1: kratos.api.Server
@@ -617,10 +1013,14 @@ var file_conf_conf_proto_goTypes = []any{
 	(*Server_HTTP)(nil),         // 3: kratos.api.Server.HTTP
 	(*Server_GRPC)(nil),         // 4: kratos.api.Server.GRPC
 	(*Server_Seckill)(nil),      // 5: kratos.api.Server.Seckill
-	(*Data_Database)(nil),       // 6: kratos.api.Data.Database
-	(*Data_Redis)(nil),          // 7: kratos.api.Data.Redis
-	(*Data_RabbitMQ)(nil),       // 8: kratos.api.Data.RabbitMQ
-	(*durationpb.Duration)(nil), // 9: google.protobuf.Duration
+	(*Server_Outbox)(nil),       // 6: kratos.api.Server.Outbox
+	(*Server_OrderExpiry)(nil),  // 7: kratos.api.Server.OrderExpiry
+	(*Data_Database)(nil),       // 8: kratos.api.Data.Database
+	(*Data_Redis)(nil),          // 9: kratos.api.Data.Redis
+	(*Data_RabbitMQ)(nil),       // 10: kratos.api.Data.RabbitMQ
+	(*Data_IDGenerator)(nil),    // 11: kratos.api.Data.IDGenerator
+	(*Data_Payment)(nil),        // 12: kratos.api.Data.Payment
+	(*durationpb.Duration)(nil), // 13: google.protobuf.Duration
 }
 var file_conf_conf_proto_depIdxs = []int32{
 	1,  // 0: kratos.api.Bootstrap.server:type_name -> kratos.api.Server
@@ -628,18 +1028,33 @@ var file_conf_conf_proto_depIdxs = []int32{
 	3,  // 2: kratos.api.Server.http:type_name -> kratos.api.Server.HTTP
 	4,  // 3: kratos.api.Server.grpc:type_name -> kratos.api.Server.GRPC
 	5,  // 4: kratos.api.Server.seckill:type_name -> kratos.api.Server.Seckill
-	6,  // 5: kratos.api.Data.database:type_name -> kratos.api.Data.Database
-	7,  // 6: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
-	8,  // 7: kratos.api.Data.rabbitmq:type_name -> kratos.api.Data.RabbitMQ
-	9,  // 8: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
-	9,  // 9: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
-	9,  // 10: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
-	9,  // 11: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
-	12, // [12:12] is the sub-list for method output_type
-	12, // [12:12] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	6,  // 5: kratos.api.Server.outbox:type_name -> kratos.api.Server.Outbox
+	7,  // 6: kratos.api.Server.order_expiry:type_name -> kratos.api.Server.OrderExpiry
+	8,  // 7: kratos.api.Data.database:type_name -> kratos.api.Data.Database
+	9,  // 8: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
+	10, // 9: kratos.api.Data.rabbitmq:type_name -> kratos.api.Data.RabbitMQ
+	11, // 10: kratos.api.Data.id_generator:type_name -> kratos.api.Data.IDGenerator
+	12, // 11: kratos.api.Data.payment:type_name -> kratos.api.Data.Payment
+	13, // 12: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
+	13, // 13: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
+	13, // 14: kratos.api.Server.Seckill.schedule_interval:type_name -> google.protobuf.Duration
+	13, // 15: kratos.api.Server.Seckill.claim_idle:type_name -> google.protobuf.Duration
+	13, // 16: kratos.api.Server.Outbox.interval:type_name -> google.protobuf.Duration
+	13, // 17: kratos.api.Server.Outbox.base_backoff:type_name -> google.protobuf.Duration
+	13, // 18: kratos.api.Server.Outbox.max_backoff:type_name -> google.protobuf.Duration
+	13, // 19: kratos.api.Server.Outbox.lease:type_name -> google.protobuf.Duration
+	13, // 20: kratos.api.Server.OrderExpiry.interval:type_name -> google.protobuf.Duration
+	13, // 21: kratos.api.Server.OrderExpiry.ttl:type_name -> google.protobuf.Duration
+	13, // 22: kratos.api.Server.OrderExpiry.lease:type_name -> google.protobuf.Duration
+	13, // 23: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
+	13, // 24: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
+	13, // 25: kratos.api.Data.IDGenerator.lease_ttl:type_name -> google.protobuf.Duration
+	13, // 26: kratos.api.Data.Payment.settle_after:type_name -> google.protobuf.Duration
+	27, // [27:27] is the sub-list for method output_type
+	27, // [27:27] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_conf_conf_proto_init() }
@@ -647,13 +1062,14 @@ func file_conf_conf_proto_init() {
 	if File_conf_conf_proto != nil {
 		return
 	}
+	file_conf_conf_proto_msgTypes[11].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_conf_conf_proto_rawDesc), len(file_conf_conf_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -1,11 +1,14 @@
 package data
 
 import (
+	"context"
 	"errors"
+	"product/internal/biz"
 	"product/internal/conf"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/google/wire"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -22,6 +25,13 @@ var ProviderSet = wire.NewSet(
 	NewOrderIDGenerator,
 	NewSeckillProductRepo,
 	NewOrderRepoImpl,
+	NewTransaction,
+	NewOutboxRepo,
+	NewInstanceEventRepo,
+	NewSeckillCampaignRepo,
+	NewSeckillCompensationRepo,
+	NewInventoryRepo,
+	NewPaymentGateway,
 )
 
 // Data .
@@ -84,3 +94,48 @@ func NewData(c *conf.Data, logger log.Logger) (*Data, func(), error) {
 		redis: rdb,
 	}, cleanup, nil
 }
+
+// contextTxKey 事务在 context 中的键
+type contextTxKey struct{}
+
+// NewTransaction 创建事务管理器
+func NewTransaction(d *Data) biz.Transaction {
+	return d
+}
+
+// InTx 在同一个数据库事务中执行 fn，fn 内的仓储调用通过 DB(ctx) 自动加入该事务
+// ctx 中已有事务时直接复用，不再开启新事务
+func (d *Data) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(contextTxKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, contextTxKey{}, tx))
+	})
+}
+
+// DB 返回当前 context 绑定的事务，没有事务时返回普通连接
+func (d *Data) DB(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(contextTxKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return d.db.WithContext(ctx)
+}
+
+// isUniqueViolation 判断是否为唯一约束冲突（PostgreSQL 23505）
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isUniqueViolationOf 判断是否为指定唯一索引/约束的冲突
+func isUniqueViolationOf(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// isInvalidTextRepresentation 判断是否为输入格式错误（PostgreSQL 22P02，如非法的 UUID）
+func isInvalidTextRepresentation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02"
+}
@@ -0,0 +1,288 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+// instancePO 实例持久化对象
+// 下单时与订单同事务写入，固化规格快照；状态由资源域事件更新
+type instancePO struct {
+	InstanceID    int64        `gorm:"column:instance_id;primaryKey"`
+	OrderID       int64        `gorm:"column:order_id;not null;uniqueIndex"`
+	UserID        string       `gorm:"column:user_id;type:uuid;not null"`
+	ProductID     int64        `gorm:"column:product_id;not null"`
+	Name          string       `gorm:"column:name;size:128;not null"`
+	SpecID        int64        `gorm:"column:spec_id;not null"`
+	CPU           int32        `gorm:"column:cpu;not null"`
+	Memory        int32        `gorm:"column:memory;not null"`
+	GPU           int32        `gorm:"column:gpu;default:0"`
+	Image         string       `gorm:"column:image;size:255;not null"`
+	ConfigJSON    []byte       `gorm:"column:config_json;type:jsonb"`
+	Status        string       `gorm:"column:status;type:varchar(20);not null"`
+	ResourceState string       `gorm:"column:resource_state;type:varchar(32)"`
+	Reason        string       `gorm:"column:reason;type:text"`
+	PendingAction string       `gorm:"column:pending_action;type:varchar(20);not null;default:''"`
+	PendingAt     sql.NullTime `gorm:"column:pending_at"`
+	// 变更规格的目标商品与规格，资源域确认后写入上面的规格列
+	PendingProductID  sql.NullInt64  `gorm:"column:pending_product_id"`
+	PendingName       sql.NullString `gorm:"column:pending_name;size:128"`
+	PendingSpecID     sql.NullInt64  `gorm:"column:pending_spec_id"`
+	PendingCPU        sql.NullInt32  `gorm:"column:pending_cpu"`
+	PendingMemory     sql.NullInt32  `gorm:"column:pending_memory"`
+	PendingGPU        sql.NullInt32  `gorm:"column:pending_gpu"`
+	PendingImage      sql.NullString `gorm:"column:pending_image;size:255"`
+	PendingConfigJSON []byte         `gorm:"column:pending_config_json;type:jsonb"`
+	LastEventAt       sql.NullTime   `gorm:"column:last_event_at"`
+	CreatedAt         time.Time      `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (instancePO) TableName() string {
+	return "instances"
+}
+
+func toInstancePO(instance *biz.InstanceInfo) *instancePO {
+	po := &instancePO{
+		InstanceID: instance.InstanceID,
+		OrderID:    instance.OrderID,
+		UserID:     instance.UserID,
+		ProductID:  instance.ProductID,
+		Name:       instance.ProductName,
+		Status:     instance.Status,
+		CreatedAt:  instance.CreatedAt,
+		UpdatedAt:  instance.UpdatedAt,
+	}
+	if instance.Spec != nil {
+		po.SpecID = instance.Spec.ID
+		po.CPU = instance.Spec.CPU
+		po.Memory = instance.Spec.Memory
+		po.GPU = instance.Spec.GPU
+		po.Image = instance.Spec.Image
+		po.ConfigJSON = instance.Spec.ConfigJSON
+	}
+	return po
+}
+
+func toInstanceInfo(po *instancePO) *biz.InstanceInfo {
+	info := &biz.InstanceInfo{
+		InstanceID:  po.InstanceID,
+		UserID:      po.UserID,
+		OrderID:     po.OrderID,
+		ProductID:   po.ProductID,
+		ProductName: po.Name,
+		Spec: &biz.ProductSpec{
+			ID:         po.SpecID,
+			CPU:        po.CPU,
+			Memory:     po.Memory,
+			GPU:        po.GPU,
+			Image:      po.Image,
+			ConfigJSON: po.ConfigJSON,
+		},
+		Status:        po.Status,
+		ResourceState: po.ResourceState,
+		Reason:        po.Reason,
+		PendingAction: po.PendingAction,
+		CreatedAt:     po.CreatedAt,
+		UpdatedAt:     po.UpdatedAt,
+	}
+	if po.PendingAt.Valid {
+		info.PendingAt = &po.PendingAt.Time
+	}
+	if po.LastEventAt.Valid {
+		info.LastEventAt = &po.LastEventAt.Time
+	}
+	if po.PendingSpecID.Valid {
+		info.PendingProductID = po.PendingProductID.Int64
+		info.PendingProductName = po.PendingName.String
+		info.PendingSpec = &biz.ProductSpec{
+			ID:         po.PendingSpecID.Int64,
+			CPU:        po.PendingCPU.Int32,
+			Memory:     po.PendingMemory.Int32,
+			GPU:        po.PendingGPU.Int32,
+			Image:      po.PendingImage.String,
+			ConfigJSON: po.PendingConfigJSON,
+		}
+	}
+	return info
+}
+
+// instancePendingSpecColumns 规格列 -> 变更规格的目标规格列
+var instancePendingSpecColumns = map[string]string{
+	"product_id":  "pending_product_id",
+	"name":        "pending_name",
+	"spec_id":     "pending_spec_id",
+	"cpu":         "pending_cpu",
+	"memory":      "pending_memory",
+	"gpu":         "pending_gpu",
+	"image":       "pending_image",
+	"config_json": "pending_config_json",
+}
+
+type instanceRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewInstanceRepo 创建实例仓储
+func NewInstanceRepo(data *Data, logger log.Logger) biz.InstanceRepo {
+	return &instanceRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// Create 创建实例记录（跟随 ctx 中的事务）
+func (r *instanceRepo) Create(ctx context.Context, instance *biz.InstanceInfo) error {
+	if err := r.data.DB(ctx).Create(toInstancePO(instance)).Error; err != nil {
+		r.log.Errorf("create instance failed: instanceID=%d err=%v", instance.InstanceID, err)
+		return err
+	}
+	return nil
+}
+
+// GetInstanceByID 根据实例ID获取实例信息
+func (r *instanceRepo) GetInstanceByID(ctx context.Context, instanceID int64) (*biz.InstanceInfo, error) {
+	return r.getBy(ctx, "instance_id = ?", instanceID)
+}
+
+// GetInstanceByOrderID 根据订单ID获取实例信息
+func (r *instanceRepo) GetInstanceByOrderID(ctx context.Context, orderID int64) (*biz.InstanceInfo, error) {
+	return r.getBy(ctx, "order_id = ?", orderID)
+}
+
+func (r *instanceRepo) getBy(ctx context.Context, cond string, value int64) (*biz.InstanceInfo, error) {
+	var po instancePO
+	if err := r.data.DB(ctx).Where(cond, value).First(&po).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrInstanceNotFound
+		}
+		r.log.Errorf("get instance failed: %s %d err=%v", cond, value, err)
+		return nil, err
+	}
+	return toInstanceInfo(&po), nil
+}
+
+// ListInstances 查询实例列表
+func (r *instanceRepo) ListInstances(ctx context.Context, filter biz.InstanceFilter) ([]*biz.InstanceInfo, int64, error) {
+	db := r.data.DB(ctx)
+	query := db.Model(&instancePO{})
+
+	// 用户ID过滤
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	// 订单状态过滤
+	if filter.Status != "" {
+		query = query.Where("order_id IN (?)",
+			db.Model(&orderPO{}).Select("order_id").Where("status = ?", filter.Status))
+	}
+
+	// 统计总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		r.log.Errorf("count instances failed: err=%v", err)
+		return nil, 0, err
+	}
+
+	// 分页查询
+	var pos []instancePO
+	offset := int((filter.Page - 1) * filter.PageSize)
+	err := query.
+		Order("created_at DESC").
+		Limit(int(filter.PageSize)).
+		Offset(offset).
+		Find(&pos).Error
+	if err != nil {
+		r.log.Errorf("list instances failed: err=%v", err)
+		return nil, 0, err
+	}
+
+	instances := make([]*biz.InstanceInfo, 0, len(pos))
+	for i := range pos {
+		instances = append(instances, toInstanceInfo(&pos[i]))
+	}
+
+	return instances, total, nil
+}
+
+// UpdateStatus 写入资源域回传的状态，只有事件时间不早于当前记录时才覆盖
+func (r *instanceRepo) UpdateStatus(ctx context.Context, record *biz.InstanceStatusRecord) (bool, error) {
+	eventAt := record.EventAt
+	if eventAt.IsZero() {
+		eventAt = time.Now()
+	}
+
+	updates := map[string]interface{}{
+		"status":         record.Status,
+		"resource_state": record.ResourceState,
+		"reason":         record.Reason,
+		"last_event_at":  eventAt,
+		"updated_at":     time.Now(),
+	}
+	if record.ApplyPending {
+		// 同一条 UPDATE 中右侧取更新前的值，目标规格写入的同时清空；没有目标规格时保持不变
+		for column, pending := range instancePendingSpecColumns {
+			updates[column] = gorm.Expr("CASE WHEN pending_spec_id IS NULL THEN " + column + " ELSE " + pending + " END")
+		}
+	}
+	if record.ClearPending {
+		updates["pending_action"] = ""
+		updates["pending_at"] = nil
+		for _, pending := range instancePendingSpecColumns {
+			updates[pending] = nil
+		}
+	}
+
+	res := r.data.DB(ctx).Model(&instancePO{}).
+		Where("instance_id = ? AND (last_event_at IS NULL OR last_event_at <= ?)", record.InstanceID, eventAt).
+		Updates(updates)
+	if res.Error != nil {
+		r.log.Errorf("update instance status failed: instanceID=%d err=%v", record.InstanceID, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// MarkPending 记录待处理操作及变更规格的目标规格，以“当前无待处理操作或已超时”作为乐观锁条件
+func (r *instanceRepo) MarkPending(ctx context.Context, instance *biz.InstanceInfo) error {
+	updates := map[string]interface{}{
+		"pending_action": instance.PendingAction,
+		"pending_at":     instance.PendingAt,
+		"updated_at":     instance.UpdatedAt,
+	}
+	for _, pending := range instancePendingSpecColumns {
+		updates[pending] = nil
+	}
+	if spec := instance.PendingSpec; spec != nil {
+		updates["pending_product_id"] = instance.PendingProductID
+		updates["pending_name"] = instance.PendingProductName
+		updates["pending_spec_id"] = spec.ID
+		updates["pending_cpu"] = spec.CPU
+		updates["pending_memory"] = spec.Memory
+		updates["pending_gpu"] = spec.GPU
+		updates["pending_image"] = spec.Image
+		updates["pending_config_json"] = spec.ConfigJSON
+	}
+
+	res := r.data.DB(ctx).Model(&instancePO{}).
+		Where("instance_id = ? AND (pending_action = '' OR pending_at < ?)",
+			instance.InstanceID, time.Now().Add(-biz.InstancePendingTimeout)).
+		Updates(updates)
+	if res.Error != nil {
+		r.log.Errorf("mark instance pending failed: instanceID=%d err=%v", instance.InstanceID, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrInstanceBusy
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm/clause"
+)
+
+// processedEventPO 已处理事件持久化对象（消费幂等表）
+type processedEventPO struct {
+	EventID     string    `gorm:"column:event_id;primaryKey;type:varchar(128)"`
+	EventType   string    `gorm:"column:event_type;type:varchar(64);not null"`
+	ProcessedAt time.Time `gorm:"column:processed_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (processedEventPO) TableName() string {
+	return "processed_events"
+}
+
+type instanceEventRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewInstanceEventRepo 创建实例事件仓储
+func NewInstanceEventRepo(data *Data, logger log.Logger) biz.InstanceEventRepo {
+	return &instanceEventRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// MarkProcessed 写入幂等记录，主键冲突说明事件已处理过
+func (r *instanceEventRepo) MarkProcessed(ctx context.Context, eventID, eventType string) (bool, error) {
+	res := r.data.DB(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&processedEventPO{EventID: eventID, EventType: eventType, ProcessedAt: time.Now()})
+	if res.Error != nil {
+		r.log.Errorf("mark event processed failed: eventID=%s err=%v", eventID, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
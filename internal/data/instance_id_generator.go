@@ -2,43 +2,108 @@ package data
 
 import (
 	"context"
-	"sync/atomic"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"product/internal/biz"
+	"product/pkg/random"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
 )
 
 // EpochMsStart 时间起点为 +UTC 2026-01-18 00:00:00
-const EpochMsStart = 1768665600000
+const EpochMsStart = biz.IDEpochMs
+
+// 实例 ID 没有 worker 位，多副本为同一用户在同一毫秒生成的 ID 可能相同，生成后在 Redis 中预留，已被占用时换下一个序号重试
+const (
+	// instanceIDReserveKeyPrefix 实例 ID 预留 Key 前缀
+	instanceIDReserveKeyPrefix = "idgen:instance:"
+	// instanceIDReserveTTL 预留有效期；过了该毫秒后只有时钟落后的副本会再生成同一 ID，有效期覆盖副本间的时钟偏差
+	instanceIDReserveTTL = time.Minute
+	// instanceIDMaxAttempts 预留冲突时的最大尝试次数
+	instanceIDMaxAttempts = 8
+)
+
+var errInstanceIDCollision = errors.New("instance id generator: too many collisions")
 
 type instanceIDGenerator struct {
-	log *log.Helper
+	mu     sync.Mutex
+	rng    *random.XorShift64Star // 非并发安全，由 mu 保护
+	lastMs int64
+	start  int64 // 当前毫秒序号的随机起点，降低多副本同一毫秒内的碰撞概率
+	used   int64 // 当前毫秒已使用的序号数
+	now    func() time.Time
+	rdb    *redis.Client // 为 nil 时不预留（仅适用于单副本部署）
+	log    *log.Helper
 }
 
 // NewInstanceIDGenerator 创建实例 ID 生成器
-func NewInstanceIDGenerator(logger log.Logger) biz.InstanceIDGenerator {
-	return &instanceIDGenerator{
+// Redis 不可用时不做跨副本的唯一性检查（仅适用于单副本部署）
+func NewInstanceIDGenerator(d *Data, logger log.Logger) biz.InstanceIDGenerator {
+	g := &instanceIDGenerator{
+		rng: random.NewXorShift64Star(uint64(time.Now().UnixNano()) ^ uint64(os.Getpid())<<32),
+		now: time.Now,
 		log: log.NewHelper(logger),
 	}
+	if d != nil && d.redis != nil {
+		g.rdb = d.redis
+	} else {
+		g.log.Warn("instance id generator started without redis, ids are unique within this replica only")
+	}
+	return g
 }
 
-// Generate 生成实例 ID
+// Generate 生成实例 ID（结构见 biz.InstanceID）
+// 同一毫秒内的序号从随机起点开始依次递增，8 个序号用尽时借用下一毫秒，同一副本内不会重复；
+// 生成后以 SET NX 在 Redis 中预留，被其他副本占用时取下一个序号重试。Redis 出错时返回错误，不分配未经检查的 ID
 func (g *instanceIDGenerator) Generate(ctx context.Context, uuid string) (int64, error) {
-	nowMs := time.Now().UnixMilli()
-	timeNow := (nowMs - EpochMsStart) << 3
-	// 只取低 15 位作为 uid，确保左移 48 位后不会影响符号位
-	uid := int64(xxhash.Sum64String(uuid)&0x7FFF) << 48
-	random := RandomBit3()
-	id := uid | timeNow | random
-	return id, nil
+	hash := xxhash.Sum64String(uuid)
+	for attempt := 1; attempt <= instanceIDMaxAttempts; attempt++ {
+		ms, seq := g.next()
+		id := int64(biz.NewInstanceID(hash, ms, seq))
+		if g.rdb == nil {
+			return id, nil
+		}
+
+		ok, err := g.rdb.SetNX(ctx, fmt.Sprintf("%s%d", instanceIDReserveKeyPrefix, id), 1, instanceIDReserveTTL).Result()
+		if err != nil {
+			g.log.Errorf("reserve instance id failed: id=%d err=%v", id, err)
+			return 0, err
+		}
+		if ok {
+			return id, nil
+		}
+		g.log.Warnf("instance id taken by another replica, retry: id=%d attempt=%d", id, attempt)
+	}
+	return 0, errInstanceIDCollision
 }
 
-func RandomBit3() int64 {
-	p := atomic.Int64{}
-	// Add returns the new value; subtract 1 to make first result 0.
-	n := p.Add(1) - 1
-	return int64(n & 7)
+// next 分配下一个毫秒数和毫秒内序号
+func (g *instanceIDGenerator) next() (int64, int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().UnixMilli() - EpochMsStart
+	if ms < g.lastMs {
+		// 时钟回拨时沿用上次的毫秒数继续分配序号
+		ms = g.lastMs
+	}
+	if ms == g.lastMs && g.used > biz.InstanceIDMaxSequence {
+		// 序号用尽时借用下一毫秒，不阻塞等待（负载下降后时钟会追上）
+		ms = g.lastMs + 1
+	}
+	if ms != g.lastMs {
+		g.lastMs = ms
+		g.start = int64(g.rng.Rand3Bits())
+		g.used = 0
+	}
+	seq := g.start + g.used
+	g.used++
+
+	return ms, seq
 }
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestInstanceIDGenerator_SameUserSameMillisecond(t *testing.T) {
+	ctx := context.Background()
+	base := time.UnixMilli(EpochMsStart + 1000)
+	g := NewInstanceIDGenerator(nil, log.DefaultLogger).(*instanceIDGenerator)
+	g.now = func() time.Time { return base }
+
+	// 时钟停在同一毫秒：每 8 个序号用尽后借用下一毫秒
+	seen := make(map[int64]bool)
+	for i := 0; i < 3*(biz.InstanceIDMaxSequence+1); i++ {
+		id, err := g.Generate(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Generate() #%d error = %v", i, err)
+		}
+		if seen[id] {
+			t.Fatalf("Generate() #%d = %d, duplicate", i, id)
+		}
+		seen[id] = true
+
+		at, userHash, _ := biz.InstanceID(id).Decode()
+		wantAt := base.Add(time.Duration(i/(biz.InstanceIDMaxSequence+1)) * time.Millisecond)
+		if !at.Equal(wantAt) {
+			t.Errorf("Generate() #%d time = %s, want %s", i, at, wantAt)
+		}
+		if want := uint16(xxhash.Sum64String("u1") & biz.InstanceIDUserHashMask); userHash != want {
+			t.Errorf("Generate() #%d user hash = %d, want %d", i, userHash, want)
+		}
+	}
+
+	// 时钟回拨时沿用上次的毫秒数，不与已生成的 ID 重复
+	g.now = func() time.Time { return base.Add(-time.Second) }
+	id, err := g.Generate(ctx, "u1")
+	if err != nil || seen[id] {
+		t.Errorf("Generate() after rollback = %d, %v, want unused id", id, err)
+	}
+}
+
+func TestInstanceIDGenerator_ReserveAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	mr, d := newTestRedis(t)
+	base := time.UnixMilli(EpochMsStart + 1000)
+
+	// 两个副本时钟相同，为同一用户交替生成：预留保证跨副本不重复
+	replicas := []*instanceIDGenerator{
+		NewInstanceIDGenerator(d, log.DefaultLogger).(*instanceIDGenerator),
+		NewInstanceIDGenerator(d, log.DefaultLogger).(*instanceIDGenerator),
+	}
+	for _, g := range replicas {
+		g.now = func() time.Time { return base }
+	}
+	seen := make(map[int64]bool)
+	for i := 0; i < 20; i++ {
+		id, err := replicas[i%2].Generate(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Generate() #%d error = %v", i, err)
+		}
+		if seen[id] {
+			t.Fatalf("Generate() #%d = %d, duplicate across replicas", i, id)
+		}
+		if !mr.Exists(fmt.Sprintf("%s%d", instanceIDReserveKeyPrefix, id)) {
+			t.Errorf("Generate() #%d = %d, not reserved", i, id)
+		}
+		seen[id] = true
+	}
+
+	// 当前毫秒的全部序号已被其他副本占用：尝试次数用尽后返回错误
+	g := NewInstanceIDGenerator(d, log.DefaultLogger).(*instanceIDGenerator)
+	next := base.Add(time.Hour)
+	g.now = func() time.Time { return next }
+	hash := xxhash.Sum64String("u2")
+	ms := next.UnixMilli() - EpochMsStart
+	for seq := int64(0); seq <= biz.InstanceIDMaxSequence; seq++ {
+		mr.Set(fmt.Sprintf("%s%d", instanceIDReserveKeyPrefix, int64(biz.NewInstanceID(hash, ms, seq))), "1")
+	}
+	if _, err := g.Generate(ctx, "u2"); !errors.Is(err, errInstanceIDCollision) {
+		t.Errorf("Generate() with all sequences taken error = %v, want %v", err, errInstanceIDCollision)
+	}
+	// 下一毫秒有空闲序号
+	id, err := g.Generate(ctx, "u2")
+	if err != nil {
+		t.Fatalf("Generate() after collisions error = %v", err)
+	}
+	if at, _, _ := biz.InstanceID(id).Decode(); !at.Equal(next.Add(time.Millisecond)) {
+		t.Errorf("Generate() after collisions time = %s, want %s", at, next.Add(time.Millisecond))
+	}
+}
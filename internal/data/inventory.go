@@ -0,0 +1,253 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm/clause"
+)
+
+// inventoryPO 商品库存持久化对象（没有记录的商品不限量）
+type inventoryPO struct {
+	ProductID int64     `gorm:"column:product_id;primaryKey"`
+	Stock     int32     `gorm:"column:stock;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (inventoryPO) TableName() string {
+	return "product_inventory"
+}
+
+// inventoryLedgerPO 库存流水持久化对象
+type inventoryLedgerPO struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID  int64     `gorm:"column:product_id;not null"`
+	Delta      int32     `gorm:"column:delta;not null"`
+	StockAfter int32     `gorm:"column:stock_after;not null"`
+	Reason     string    `gorm:"column:reason;type:varchar(32);not null"`
+	OrderID    *int64    `gorm:"column:order_id"`
+	Note       string    `gorm:"column:note;type:text;not null;default:''"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (inventoryLedgerPO) TableName() string {
+	return "inventory_ledger"
+}
+
+type inventoryRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewInventoryRepo 创建库存仓储
+func NewInventoryRepo(data *Data, logger log.Logger) biz.InventoryRepo {
+	return &inventoryRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// Get 查询商品库存
+func (r *inventoryRepo) Get(ctx context.Context, productID int64) (*biz.Inventory, error) {
+	var po inventoryPO
+	res := r.data.DB(ctx).Where("product_id = ?", productID).Limit(1).Find(&po)
+	if res.Error != nil {
+		r.log.Errorf("get inventory failed: productID=%d err=%v", productID, res.Error)
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, biz.ErrInventoryNotTracked
+	}
+	return toInventory(&po), nil
+}
+
+// Reserve 原子扣减库存：UPDATE ... WHERE stock >= quantity，未更新到行时区分库存不足与未启用库存管理
+func (r *inventoryRepo) Reserve(ctx context.Context, productID int64, quantity int32, orderID int64) (*biz.Inventory, error) {
+	var inventory *biz.Inventory
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		var po inventoryPO
+		res := r.data.DB(ctx).Raw(
+			`UPDATE product_inventory SET stock = stock - ?, updated_at = NOW()
+			 WHERE product_id = ? AND stock >= ?
+			 RETURNING product_id, stock, updated_at`,
+			quantity, productID, quantity).Scan(&po)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if _, err := r.Get(ctx, productID); err != nil {
+				return err
+			}
+			return biz.ErrOutOfStock
+		}
+		inventory = toInventory(&po)
+		return r.appendLedger(ctx, inventory, -quantity, biz.InventoryReasonReserve, orderID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// Release 按订单的预占/归还流水计算未归还的数量并归还
+// 先锁定库存行，同一订单的并发归还串行执行，不会重复归还
+func (r *inventoryRepo) Release(ctx context.Context, productID int64, orderID int64) (*biz.Inventory, error) {
+	var inventory *biz.Inventory
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		db := r.data.DB(ctx)
+		var pos []inventoryPO
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", productID).
+			Limit(1).
+			Find(&pos).Error; err != nil {
+			return err
+		}
+		if len(pos) == 0 {
+			return nil
+		}
+
+		var reserved int32
+		if err := db.Model(&inventoryLedgerPO{}).
+			Select("COALESCE(-SUM(delta), 0)").
+			Where("product_id = ? AND order_id = ? AND reason IN ?", productID, orderID,
+				[]string{biz.InventoryReasonReserve, biz.InventoryReasonRelease}).
+			Scan(&reserved).Error; err != nil {
+			return err
+		}
+		if reserved <= 0 {
+			return nil
+		}
+
+		po := pos[0]
+		po.Stock += reserved
+		po.UpdatedAt = time.Now()
+		if err := db.Model(&inventoryPO{}).
+			Where("product_id = ?", productID).
+			Updates(map[string]interface{}{"stock": po.Stock, "updated_at": po.UpdatedAt}).Error; err != nil {
+			return err
+		}
+		inventory = toInventory(&po)
+		return r.appendLedger(ctx, inventory, reserved, biz.InventoryReasonRelease, orderID, "")
+	})
+	if err != nil {
+		r.log.Errorf("release inventory failed: productID=%d orderID=%d err=%v", productID, orderID, err)
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// Restock 补货：INSERT ... ON CONFLICT DO UPDATE 累加库存
+func (r *inventoryRepo) Restock(ctx context.Context, productID int64, quantity int32, note string) (*biz.Inventory, error) {
+	var inventory *biz.Inventory
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		var po inventoryPO
+		err := r.data.DB(ctx).Raw(
+			`INSERT INTO product_inventory (product_id, stock, updated_at) VALUES (?, ?, NOW())
+			 ON CONFLICT (product_id) DO UPDATE SET stock = product_inventory.stock + EXCLUDED.stock, updated_at = NOW()
+			 RETURNING product_id, stock, updated_at`,
+			productID, quantity).Scan(&po).Error
+		if err != nil {
+			return err
+		}
+		inventory = toInventory(&po)
+		return r.appendLedger(ctx, inventory, quantity, biz.InventoryReasonRestock, 0, note)
+	})
+	if err != nil {
+		r.log.Errorf("restock failed: productID=%d quantity=%d err=%v", productID, quantity, err)
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// Adjust 盘点调整：锁定库存行后设置为 stock，流水记录与原库存的差值
+func (r *inventoryRepo) Adjust(ctx context.Context, productID int64, stock int32, note string) (*biz.Inventory, error) {
+	var inventory *biz.Inventory
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		db := r.data.DB(ctx)
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&inventoryPO{ProductID: productID, Stock: 0, UpdatedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+
+		var po inventoryPO
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", productID).
+			First(&po).Error; err != nil {
+			return err
+		}
+		delta := stock - po.Stock
+
+		po.Stock = stock
+		po.UpdatedAt = time.Now()
+		if err := db.Model(&inventoryPO{}).
+			Where("product_id = ?", productID).
+			Updates(map[string]interface{}{"stock": po.Stock, "updated_at": po.UpdatedAt}).Error; err != nil {
+			return err
+		}
+		inventory = toInventory(&po)
+		return r.appendLedger(ctx, inventory, delta, biz.InventoryReasonAdjust, 0, note)
+	})
+	if err != nil {
+		r.log.Errorf("adjust inventory failed: productID=%d stock=%d err=%v", productID, stock, err)
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// ListLedger 按 id 倒序列出最近的库存流水
+func (r *inventoryRepo) ListLedger(ctx context.Context, productID int64, limit int) ([]*biz.InventoryMovement, error) {
+	var pos []*inventoryLedgerPO
+	if err := r.data.DB(ctx).
+		Where("product_id = ?", productID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&pos).Error; err != nil {
+		r.log.Errorf("list inventory ledger failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+
+	movements := make([]*biz.InventoryMovement, 0, len(pos))
+	for _, po := range pos {
+		movement := &biz.InventoryMovement{
+			ID:         po.ID,
+			ProductID:  po.ProductID,
+			Delta:      po.Delta,
+			StockAfter: po.StockAfter,
+			Reason:     po.Reason,
+			Note:       po.Note,
+			CreatedAt:  po.CreatedAt,
+		}
+		if po.OrderID != nil {
+			movement.OrderID = *po.OrderID
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
+
+// appendLedger 在库存变动的同一事务中写入流水
+func (r *inventoryRepo) appendLedger(ctx context.Context, inventory *biz.Inventory, delta int32, reason string, orderID int64, note string) error {
+	po := &inventoryLedgerPO{
+		ProductID:  inventory.ProductID,
+		Delta:      delta,
+		StockAfter: inventory.Stock,
+		Reason:     reason,
+		Note:       note,
+		CreatedAt:  time.Now(),
+	}
+	if orderID != 0 {
+		po.OrderID = &orderID
+	}
+	return r.data.DB(ctx).Create(po).Error
+}
+
+func toInventory(po *inventoryPO) *biz.Inventory {
+	return &biz.Inventory{
+		ProductID: po.ProductID,
+		Stock:     po.Stock,
+		UpdatedAt: po.UpdatedAt,
+	}
+}
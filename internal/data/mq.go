@@ -3,6 +3,9 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"product/api/mq"
@@ -59,15 +62,38 @@ func NewRabbitMQChannel(conn *amqp.Connection, c *conf.Data, logger log.Logger)
 	return ch, nil
 }
 
+// MQ 发布参数
+const (
+	mqConfirmTimeout    = 5 * time.Second  // 等待 Broker 确认的超时时间
+	mqReconnectMinDelay = time.Second      // 首次重连退避
+	mqReconnectMaxDelay = 30 * time.Second // 最大重连退避
+)
+
+// mqReturnBuffer 退回消息通知的缓冲大小
+const mqReturnBuffer = 16
+
 // mqPublisher MQ 发布器实现
+// 连接断开（含启动时 MQ 不可用）后在下一次发布时按指数退避重连；channel 处于 confirm 模式，
+// Broker 确认（ack）后才算发布成功，未确认的消息由发件箱中继重试。
+// 消息以 mandatory 发布，没有队列绑定该 routing key 时 Broker 退回消息，按发布失败处理
 type mqPublisher struct {
-	conn     *amqp.Connection
-	channel  *amqp.Channel
+	url      string
 	exchange string
+	queue    string
 	log      *log.Helper
+
+	pubMu sync.Mutex // 串行发布，确认后据此判断退回的是哪一条消息
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	returns chan amqp.Return // 当前 channel 的退回消息
+	delay   time.Duration    // 当前重连退避
+	retryAt time.Time        // 退避期内不再重连
+	closed  bool
 }
 
-// NewMQPublisher 创建 MQ 发布器
+// NewMQPublisher 创建 MQ 发布器，启动时连接失败不影响服务启动，发布时重连
 func NewMQPublisher(c *conf.Data, logger log.Logger) (biz.MQPublisher, func(), error) {
 	helper := log.NewHelper(logger)
 
@@ -76,98 +102,206 @@ func NewMQPublisher(c *conf.Data, logger log.Logger) (biz.MQPublisher, func(), e
 		return &noopMQPublisher{log: helper}, func() {}, nil
 	}
 
-	// 连接 RabbitMQ
-	conn, err := amqp.Dial(c.Rabbitmq.Url)
+	p := &mqPublisher{
+		url:      c.Rabbitmq.Url,
+		exchange: c.Rabbitmq.Exchange,
+		queue:    c.Rabbitmq.Queue,
+		log:      helper,
+	}
+	if _, _, err := p.ensureChannel(); err != nil {
+		helper.Warnf("rabbitmq unavailable at startup, will reconnect on publish: %v", err)
+	}
+	return p, p.close, nil
+}
+
+// Publish 发布已序列化的消息到 Exchange，等待 Broker 确认；消息无法路由到任何队列时返回错误
+func (p *mqPublisher) Publish(ctx context.Context, routingKey string, body []byte) error {
+	p.pubMu.Lock()
+	defer p.pubMu.Unlock()
+
+	ch, returns, err := p.ensureChannel()
 	if err != nil {
-		helper.Warnf("failed to connect rabbitmq: %v, using noop publisher", err)
-		return &noopMQPublisher{log: helper}, func() {}, nil
+		return err
+	}
+	// 丢弃之前等待确认超时的消息的退回通知
+	for len(returns) > 0 {
+		<-returns
 	}
 
-	// 创建 Channel
+	messageID := fmt.Sprintf("%s-%d", routingKey, time.Now().UnixNano())
+	p.log.Infof("publishing to exchange=%s routingKey=%s size=%d bytes", p.exchange, routingKey, len(body))
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(
+		ctx,
+		p.exchange, // exchange: resource.events
+		routingKey, // routing key
+		true,       // mandatory：无法路由时退回，不会被确认后静默丢弃
+		false,      // immediate
+		amqp.Publishing{
+			MessageId:    messageID,
+			ContentType:  "application/octet-stream",
+			AppId:        biz.EventSourceProduct,
+			Body:         body,
+			DeliveryMode: amqp.Persistent, // 持久化
+			Timestamp:    time.Now(),
+		},
+	)
+	if err != nil {
+		p.log.Errorf("publish message failed: %v", err)
+		p.invalidate(ch)
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, mqConfirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		// 超时未确认时不确定 Broker 状态，重建 channel，消息由中继重试（下游按事件幂等处理）
+		p.log.Errorf("wait publish confirm failed: routingKey=%s err=%v", routingKey, err)
+		p.invalidate(ch)
+		return err
+	}
+	if !acked {
+		p.log.Errorf("publish nacked by broker: routingKey=%s", routingKey)
+		return fmt.Errorf("publish nacked by broker: routingKey=%s", routingKey)
+	}
+
+	// Broker 先发送退回再发送确认，确认到达时退回通知已在缓冲中
+	for len(returns) > 0 {
+		if ret := <-returns; ret.MessageId == messageID {
+			p.log.Errorf("publish returned as unroutable: routingKey=%s reply=%d %s", routingKey, ret.ReplyCode, ret.ReplyText)
+			return fmt.Errorf("publish returned as unroutable: routingKey=%s reply=%d %s", routingKey, ret.ReplyCode, ret.ReplyText)
+		}
+	}
+	return nil
+}
+
+// ensureChannel 返回可用的 channel 及其退回通知，连接已断开时重连；退避期内直接返回 ErrMQUnavailable
+func (p *mqPublisher) ensureChannel() (*amqp.Channel, chan amqp.Return, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, nil, fmt.Errorf("%w: publisher closed", biz.ErrMQUnavailable)
+	}
+	if p.channel != nil && !p.channel.IsClosed() {
+		return p.channel, p.returns, nil
+	}
+	p.reset()
+
+	now := time.Now()
+	if now.Before(p.retryAt) {
+		return nil, nil, fmt.Errorf("%w: reconnect after %s", biz.ErrMQUnavailable, p.retryAt.Format(time.RFC3339))
+	}
+	if err := p.dial(); err != nil {
+		p.reset()
+		if p.delay == 0 {
+			p.delay = mqReconnectMinDelay
+		} else if p.delay *= 2; p.delay > mqReconnectMaxDelay {
+			p.delay = mqReconnectMaxDelay
+		}
+		p.retryAt = now.Add(p.delay)
+		p.log.Warnf("rabbitmq connect failed, retry in %s: %v", p.delay, err)
+		return nil, nil, fmt.Errorf("%w: %v", biz.ErrMQUnavailable, err)
+	}
+	p.delay = 0
+	p.retryAt = time.Time{}
+	return p.channel, p.returns, nil
+}
+
+// dial 建立连接和 confirm 模式的 channel，并声明 Exchange、队列和绑定
+func (p *mqPublisher) dial() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+
 	ch, err := conn.Channel()
 	if err != nil {
-		conn.Close()
-		helper.Warnf("failed to open channel: %v, using noop publisher", err)
-		return &noopMQPublisher{log: helper}, func() {}, nil
+		return fmt.Errorf("open channel: %w", err)
+	}
+	p.channel = ch
+
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("enable publisher confirms: %w", err)
 	}
+	p.returns = ch.NotifyReturn(make(chan amqp.Return, mqReturnBuffer))
 
 	// 声明 Direct Exchange（与现有 Exchange 类型保持一致）
 	err = ch.ExchangeDeclare(
-		c.Rabbitmq.Exchange, // exchange name: resource.events
-		"direct",            // type: direct（与现有 Exchange 一致）
-		true,                // durable
-		false,               // auto-deleted
-		false,               // internal
-		false,               // no-wait
-		nil,                 // arguments
+		p.exchange, // exchange name: resource.events
+		"direct",   // type: direct（与现有 Exchange 一致）
+		true,       // durable
+		false,      // auto-deleted
+		false,      // internal
+		false,      // no-wait
+		nil,        // arguments
 	)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		helper.Warnf("failed to declare exchange: %v, using noop publisher", err)
-		return &noopMQPublisher{log: helper}, func() {}, nil
+		return fmt.Errorf("declare exchange: %w", err)
 	}
 
 	// 声明队列（确保队列存在）
 	_, err = ch.QueueDeclare(
-		c.Rabbitmq.Queue, // queue name: resource.instance.created
-		true,             // durable
-		false,            // delete when unused
-		false,            // exclusive
-		false,            // no-wait
-		nil,              // arguments
+		p.queue, // queue name: resource.instance.created
+		true,    // durable
+		false,   // delete when unused
+		false,   // exclusive
+		false,   // no-wait
+		nil,     // arguments
 	)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		helper.Warnf("failed to declare queue: %v, using noop publisher", err)
-		return &noopMQPublisher{log: helper}, func() {}, nil
+		return fmt.Errorf("declare queue: %w", err)
 	}
 
 	// 绑定队列到 Exchange（关键步骤！）
-	err = ch.QueueBind(
-		c.Rabbitmq.Queue,    // queue name
-		"instance.created",  // routing key
-		c.Rabbitmq.Exchange, // exchange
-		false,
-		nil,
-	)
-	if err != nil {
-		ch.Close()
-		conn.Close()
-		helper.Warnf("failed to bind queue: %v, using noop publisher", err)
-		return &noopMQPublisher{log: helper}, func() {}, nil
+	if err := ch.QueueBind(p.queue, "instance.created", p.exchange, false, nil); err != nil {
+		return fmt.Errorf("bind queue: %w", err)
 	}
 
-	helper.Infof("rabbitmq connected: exchange=%s queue=%s binding=instance.created",
-		c.Rabbitmq.Exchange, c.Rabbitmq.Queue)
+	p.log.Infof("rabbitmq connected: exchange=%s queue=%s binding=instance.created confirm=on mandatory=on", p.exchange, p.queue)
+	return nil
+}
 
-	cleanup := func() {
-		if err := ch.Close(); err != nil {
-			helper.Errorf("failed to close channel: %v", err)
-		}
-		if err := conn.Close(); err != nil {
-			helper.Errorf("failed to close connection: %v", err)
-		}
-		helper.Info("rabbitmq connection closed")
+// invalidate 发布失败后丢弃出错的 channel，下一次发布时重连
+func (p *mqPublisher) invalidate(ch *amqp.Channel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.channel == ch {
+		p.reset()
 	}
+}
 
-	return &mqPublisher{
-		conn:     conn,
-		channel:  ch,
-		exchange: c.Rabbitmq.Exchange,
-		log:      helper,
-	}, cleanup, nil
+// reset 关闭当前连接（调用方持有 mu）
+func (p *mqPublisher) reset() {
+	if p.channel != nil {
+		_ = p.channel.Close()
+		p.channel = nil
+		p.returns = nil
+	}
+	if p.conn != nil {
+		_ = p.conn.Close()
+		p.conn = nil
+	}
 }
 
-// PublishInstanceCreated 发布实例创建事件
-func (p *mqPublisher) PublishInstanceCreated(ctx context.Context, spec biz.InstanceSpec) error {
-	p.log.Infof("preparing to publish event: instanceID=%d userID=%s", spec.InstanceID, spec.UserID)
+// close 关闭连接，之后不再重连
+func (p *mqPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.reset()
+	p.log.Info("rabbitmq connection closed")
+}
 
+// marshalInstanceEvent 构造并序列化实例事件
+func marshalInstanceEvent(eventType string, spec biz.InstanceSpec) ([]byte, error) {
+	now := time.Now()
 	event := &mq.Event{
-		EventType:  mq.EventType_INSTANCE_CREATED.String(),
+		EventId:    fmt.Sprintf("%s-%d-%d", eventType, spec.InstanceID, now.UnixNano()),
+		EventType:  eventType,
 		InstanceId: spec.InstanceID,
-		Timestamp:  timestamppb.Now(),
+		Timestamp:  timestamppb.New(now),
 		UserId:     spec.UserID,
 		Name:       spec.Name,
 		Spec: &mq.InstanceSpec{
@@ -179,44 +313,23 @@ func (p *mqPublisher) PublishInstanceCreated(ctx context.Context, spec biz.Insta
 	}
 
 	// 序列化为 Protobuf
-	body, err := proto.Marshal(event)
-	if err != nil {
-		p.log.Errorf("marshal event failed: %v", err)
-		return err
-	}
-	p.log.Infof("event marshaled: size=%d bytes", len(body))
-
-	// 发布消息到 Exchange，使用 routing key: instance.created
-	p.log.Infof("publishing to exchange=%s routingKey=instance.created", p.exchange)
-	err = p.channel.PublishWithContext(
-		ctx,
-		p.exchange,         // exchange: resource.events
-		"instance.created", // routing key
-		false,              // mandatory
-		false,              // immediate
-		amqp.Publishing{
-			ContentType:  "application/octet-stream",
-			Body:         body,
-			DeliveryMode: amqp.Persistent, // 持久化
-			Timestamp:    time.Now(),
-		},
-	)
+	return proto.Marshal(event)
+}
 
-	if err != nil {
-		p.log.Errorf("publish message failed: %v", err)
-		return err
-	}
-	return nil
+// routingKeyFor 事件类型对应的 routing key（INSTANCE_CREATED -> instance.created）
+func routingKeyFor(eventType string) string {
+	return strings.ToLower(strings.ReplaceAll(eventType, "_", "."))
 }
 
-// noopMQPublisher 空实现（当 RabbitMQ 未配置或连接失败时使用）
+// noopMQPublisher 空实现（当 RabbitMQ 未配置时使用）
 type noopMQPublisher struct {
 	log *log.Helper
 }
 
-func (p *noopMQPublisher) PublishInstanceCreated(ctx context.Context, spec biz.InstanceSpec) error {
+// Publish 返回 ErrMQUnavailable，让发件箱消息保持待投递状态，配置 MQ 并重启后由中继补发
+func (p *noopMQPublisher) Publish(ctx context.Context, routingKey string, body []byte) error {
 	if p.log != nil {
-		p.log.Warnf("mq publisher not available, skipping event: instanceID=%d userID=%s", spec.InstanceID, spec.UserID)
+		p.log.Warnf("mq publisher not available, keep message pending: routingKey=%s", routingKey)
 	}
-	return nil // 返回 nil 而不是错误，允许业务继续
+	return biz.ErrMQUnavailable
 }
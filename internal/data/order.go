@@ -3,31 +3,99 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"product/internal/biz"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // orderPO 订单持久化对象（与 DDL 严格对应）
 type orderPO struct {
-	OrderID     int64         `gorm:"column:order_id;primaryKey"`
-	ProductID   int64         `gorm:"column:product_id;not null"`
-	Amount      int64         `gorm:"column:amount;not null"`
-	InstanceID  sql.NullInt64 `gorm:"column:instance_id"`
-	Status      string        `gorm:"column:status;not null;default:PENDING"`
-	CreatedAt   time.Time     `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
-	PaidAt      sql.NullTime  `gorm:"column:paid_at"`
-	CompletedAt sql.NullTime  `gorm:"column:completed_at"`
-	UserID      string        `gorm:"column:user_id;type:uuid"` // UUID 类型
-	ReqID       int64         `gorm:"column:req_id;not null;default:0"`
+	OrderID     int64          `gorm:"column:order_id;primaryKey"`
+	ProductID   int64          `gorm:"column:product_id;not null"`
+	Amount      int64          `gorm:"column:amount;not null"`
+	InstanceID  sql.NullInt64  `gorm:"column:instance_id"`
+	Status      string         `gorm:"column:status;not null;default:PENDING"`
+	CreatedAt   time.Time      `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	PaidAt      sql.NullTime   `gorm:"column:paid_at"`
+	CompletedAt sql.NullTime   `gorm:"column:completed_at"`
+	UserID      string         `gorm:"column:user_id;type:uuid"` // UUID 类型
+	ReqID       int64          `gorm:"column:req_id;not null;default:0"`
+	PaymentID   sql.NullString `gorm:"column:payment_id;type:varchar(64)"`
+
+	// 退款信息（退款取消的订单）
+	RefundAmount sql.NullInt64  `gorm:"column:refund_amount"`
+	RefundReason sql.NullString `gorm:"column:refund_reason;type:varchar(256)"`
+	RefundID     sql.NullString `gorm:"column:refund_id;type:varchar(64)"`
+	RefundedAt   sql.NullTime   `gorm:"column:refunded_at"`
+	RefundManual bool           `gorm:"column:refund_manual;not null;default:false"` // 需要人工退款（没有支付意图）
+
+	// 过期任务认领租期：租期内其他副本不会处理，处理失败的订单在租期后排在新过期的订单之后重试
+	ExpiryAttemptAt sql.NullTime `gorm:"column:expiry_attempt_at"`
 }
 
 func (orderPO) TableName() string {
 	return "orders"
 }
 
+// orderSnapshotPO 订单商品快照持久化对象
+// 下单时记录商品名称、价格和完整规格，商品后续变更不影响历史订单
+type orderSnapshotPO struct {
+	OrderID    int64     `gorm:"column:order_id;primaryKey"`
+	ProductID  int64     `gorm:"column:product_id;not null"`
+	Name       string    `gorm:"column:name;size:128;not null"`
+	Price      int64     `gorm:"column:price;not null"`
+	SpecID     int64     `gorm:"column:spec_id;not null"`
+	CPU        int32     `gorm:"column:cpu;not null"`
+	Memory     int32     `gorm:"column:memory;not null"`
+	GPU        int32     `gorm:"column:gpu;default:0"`
+	Image      string    `gorm:"column:image;size:255;not null"`
+	ConfigJSON []byte    `gorm:"column:config_json;type:jsonb"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (orderSnapshotPO) TableName() string {
+	return "order_snapshots"
+}
+
+func toOrderSnapshotPO(orderID int64, snapshot *biz.ProductSnapshot) *orderSnapshotPO {
+	po := &orderSnapshotPO{
+		OrderID:   orderID,
+		ProductID: snapshot.ProductID,
+		Name:      snapshot.Name,
+		Price:     snapshot.Price,
+	}
+	if snapshot.Spec != nil {
+		po.SpecID = snapshot.Spec.ID
+		po.CPU = snapshot.Spec.CPU
+		po.Memory = snapshot.Spec.Memory
+		po.GPU = snapshot.Spec.GPU
+		po.Image = snapshot.Spec.Image
+		po.ConfigJSON = snapshot.Spec.ConfigJSON
+	}
+	return po
+}
+
+func toProductSnapshot(po *orderSnapshotPO) *biz.ProductSnapshot {
+	return &biz.ProductSnapshot{
+		ProductID: po.ProductID,
+		Name:      po.Name,
+		Price:     po.Price,
+		Spec: &biz.ProductSpec{
+			ID:         po.SpecID,
+			CPU:        po.CPU,
+			Memory:     po.Memory,
+			GPU:        po.GPU,
+			Image:      po.Image,
+			ConfigJSON: po.ConfigJSON,
+		},
+	}
+}
+
 type orderRepo struct {
 	data *Data
 	log  *log.Helper
@@ -49,16 +117,170 @@ func NewOrderRepoImpl(data *Data, logger log.Logger) biz.OrderRepo {
 	}
 }
 
-// NewInstanceRepo 创建实例仓储（实际返回 orderRepo，因为实例查询基于订单）
-func NewInstanceRepo(data *Data, logger log.Logger) biz.InstanceRepo {
-	return &orderRepo{
-		data: data,
-		log:  log.NewHelper(logger),
+// Create 创建订单
+func (r *orderRepo) Create(ctx context.Context, order *biz.Order) error {
+	po := toOrderPO(order)
+
+	// 订单与商品快照原子写入
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		if err := r.data.DB(ctx).Create(po).Error; err != nil {
+			// user_id 列为 uuid 类型，非法的用户ID无法写入
+			if isInvalidTextRepresentation(err) {
+				return biz.ErrInvalidUserID
+			}
+			// 同一商品的 req_id 已被使用，由调用方判断是重放还是冲突
+			if isUniqueViolationOf(err, "uk_orders_product_req") {
+				return biz.ErrOrderReqIDExists
+			}
+			r.log.Errorf("create order failed: %v", err)
+			return err
+		}
+
+		if order.ProductSnapshot != nil {
+			if err := r.data.DB(ctx).Create(toOrderSnapshotPO(order.ID, order.ProductSnapshot)).Error; err != nil {
+				r.log.Errorf("create order snapshot failed: orderID=%d err=%v", order.ID, err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBatch 批量创建订单：一条多行 INSERT ... ON CONFLICT (product_id, req_id) DO NOTHING
+// 订单ID是新生成的，写入后按订单ID回查即可知道哪些订单没有因 req_id 冲突被跳过
+func (r *orderRepo) CreateBatch(ctx context.Context, orders []*biz.Order) ([]bool, error) {
+	inserted := make([]bool, len(orders))
+	if len(orders) == 0 {
+		return inserted, nil
+	}
+
+	pos := make([]*orderPO, len(orders))
+	orderIDs := make([]int64, len(orders))
+	for i, order := range orders {
+		pos[i] = toOrderPO(order)
+		orderIDs[i] = order.ID
+	}
+
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		if err := r.data.DB(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "product_id"}, {Name: "req_id"}},
+				DoNothing: true,
+			}).
+			Create(&pos).Error; err != nil {
+			// user_id 列为 uuid 类型，任何一个非法的用户ID都会使整条语句失败
+			if isInvalidTextRepresentation(err) {
+				return biz.ErrInvalidUserID
+			}
+			r.log.Errorf("create order batch failed: size=%d err=%v", len(pos), err)
+			return err
+		}
+
+		var createdIDs []int64
+		if err := r.data.DB(ctx).Model(&orderPO{}).
+			Where("order_id IN ?", orderIDs).
+			Pluck("order_id", &createdIDs).Error; err != nil {
+			r.log.Errorf("query created orders failed: %v", err)
+			return err
+		}
+		created := make(map[int64]struct{}, len(createdIDs))
+		for _, id := range createdIDs {
+			created[id] = struct{}{}
+		}
+
+		snapshots := make([]*orderSnapshotPO, 0, len(createdIDs))
+		for i, order := range orders {
+			if _, ok := created[order.ID]; !ok {
+				continue
+			}
+			inserted[i] = true
+			if order.ProductSnapshot != nil {
+				snapshots = append(snapshots, toOrderSnapshotPO(order.ID, order.ProductSnapshot))
+			}
+		}
+		if len(snapshots) > 0 {
+			if err := r.data.DB(ctx).Create(&snapshots).Error; err != nil {
+				r.log.Errorf("create order snapshots failed: size=%d err=%v", len(snapshots), err)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return inserted, nil
 }
 
-// Create 创建订单
-func (r *orderRepo) Create(ctx context.Context, order *biz.Order) error {
+// GetByID 根据订单ID获取订单
+func (r *orderRepo) GetByID(ctx context.Context, orderID int64) (*biz.Order, error) {
+	var po orderPO
+	if err := r.data.DB(ctx).Where("order_id = ?", orderID).First(&po).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrOrderNotFound
+		}
+		r.log.Errorf("get order failed: %v", err)
+		return nil, err
+	}
+
+	order := toOrder(&po)
+	snapshot, err := r.getSnapshot(ctx, po.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	order.ProductSnapshot = snapshot
+
+	return order, nil
+}
+
+// LockByID 以 SELECT ... FOR UPDATE 锁定订单行（不含商品快照），锁持有到事务结束
+func (r *orderRepo) LockByID(ctx context.Context, orderID int64) (*biz.Order, error) {
+	var po orderPO
+	if err := r.data.DB(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).
+		First(&po).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrOrderNotFound
+		}
+		r.log.Errorf("lock order failed: orderID=%d err=%v", orderID, err)
+		return nil, err
+	}
+	return toOrder(&po), nil
+}
+
+// ListByProduct 列出商品在 since 之后创建的订单（不含商品快照），按创建时间排序
+func (r *orderRepo) ListByProduct(ctx context.Context, productID int64, since time.Time) ([]*biz.Order, error) {
+	var pos []orderPO
+	if err := r.data.DB(ctx).
+		Where("product_id = ? AND created_at >= ?", productID, since).
+		Order("created_at ASC, order_id ASC").
+		Find(&pos).Error; err != nil {
+		r.log.Errorf("list orders by product failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+
+	orders := make([]*biz.Order, 0, len(pos))
+	for i := range pos {
+		orders = append(orders, toOrder(&pos[i]))
+	}
+	return orders, nil
+}
+
+// GetByReqID 按 (product_id, req_id) 查询订单（不含商品快照）
+func (r *orderRepo) GetByReqID(ctx context.Context, productID int64, reqID int64) (*biz.Order, error) {
+	var po orderPO
+	if err := r.data.DB(ctx).Where("product_id = ? AND req_id = ?", productID, reqID).First(&po).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrOrderNotFound
+		}
+		r.log.Errorf("get order by req_id failed: productID=%d reqID=%d err=%v", productID, reqID, err)
+		return nil, err
+	}
+	return toOrder(&po), nil
+}
+
+func toOrderPO(order *biz.Order) *orderPO {
 	po := &orderPO{
 		OrderID:   order.ID,
 		UserID:    order.UserID,
@@ -79,23 +301,20 @@ func (r *orderRepo) Create(ctx context.Context, order *biz.Order) error {
 	if order.CompletedAt != nil {
 		po.CompletedAt = sql.NullTime{Time: *order.CompletedAt, Valid: true}
 	}
-
-	if err := r.data.db.WithContext(ctx).Create(po).Error; err != nil {
-		r.log.Errorf("create order failed: %v", err)
-		return err
+	if order.PaymentID != "" {
+		po.PaymentID = sql.NullString{String: order.PaymentID, Valid: true}
 	}
-
-	return nil
-}
-
-// GetByID 根据订单ID获取订单
-func (r *orderRepo) GetByID(ctx context.Context, orderID int64) (*biz.Order, error) {
-	var po orderPO
-	if err := r.data.db.WithContext(ctx).Where("order_id = ?", orderID).First(&po).Error; err != nil {
-		r.log.Errorf("get order failed: %v", err)
-		return nil, err
+	if order.Refund != nil {
+		po.RefundAmount = sql.NullInt64{Int64: order.Refund.Amount, Valid: true}
+		po.RefundReason = sql.NullString{String: order.Refund.Reason, Valid: true}
+		po.RefundID = sql.NullString{String: order.Refund.RefundID, Valid: order.Refund.RefundID != ""}
+		po.RefundedAt = sql.NullTime{Time: order.Refund.RefundedAt, Valid: true}
+		po.RefundManual = order.Refund.Manual
 	}
+	return po
+}
 
+func toOrder(po *orderPO) *biz.Order {
 	order := &biz.Order{
 		ID:        po.OrderID,
 		UserID:    po.UserID,
@@ -116,204 +335,142 @@ func (r *orderRepo) GetByID(ctx context.Context, orderID int64) (*biz.Order, err
 	if po.CompletedAt.Valid {
 		order.CompletedAt = &po.CompletedAt.Time
 	}
+	if po.PaymentID.Valid {
+		order.PaymentID = po.PaymentID.String
+	}
+	if po.RefundedAt.Valid {
+		order.Refund = &biz.OrderRefund{
+			Amount:     po.RefundAmount.Int64,
+			Reason:     po.RefundReason.String,
+			RefundID:   po.RefundID.String,
+			Manual:     po.RefundManual,
+			RefundedAt: po.RefundedAt.Time,
+		}
+	}
+	return order
+}
 
-	return order, nil
+// getSnapshot 查询订单商品快照（快照功能上线前的历史订单没有快照，返回 nil）
+func (r *orderRepo) getSnapshot(ctx context.Context, orderID int64) (*biz.ProductSnapshot, error) {
+	var pos []orderSnapshotPO
+	if err := r.data.DB(ctx).Where("order_id = ?", orderID).Limit(1).Find(&pos).Error; err != nil {
+		r.log.Errorf("get order snapshot failed: orderID=%d err=%v", orderID, err)
+		return nil, err
+	}
+	if len(pos) == 0 {
+		return nil, nil
+	}
+	return toProductSnapshot(&pos[0]), nil
 }
 
-// UpdateStatus 更新订单状态
-func (r *orderRepo) UpdateStatus(ctx context.Context, orderID int64, status string) error {
+// UpdateStatus 更新订单状态（以当前状态作为乐观锁条件）
+func (r *orderRepo) UpdateStatus(ctx context.Context, orderID int64, from, to string) error {
 	updates := map[string]interface{}{
-		"status": status,
+		"status": to,
 	}
 
 	// 如果状态是已支付，更新支付时间
-	if status == "PAID" {
+	if to == biz.OrderStatusPaid {
 		now := time.Now()
 		updates["paid_at"] = sql.NullTime{Time: now, Valid: true}
 	}
 
 	// 如果状态是已完成，更新完成时间
-	if status == "COMPLETED" {
+	if to == biz.OrderStatusCompleted {
 		now := time.Now()
 		updates["completed_at"] = sql.NullTime{Time: now, Valid: true}
 	}
 
-	if err := r.data.db.WithContext(ctx).Model(&orderPO{}).Where("order_id = ?", orderID).Updates(updates).Error; err != nil {
-		r.log.Errorf("update order status failed: %v", err)
-		return err
+	res := r.data.DB(ctx).Model(&orderPO{}).
+		Where("order_id = ? AND status = ?", orderID, from).
+		Updates(updates)
+	if res.Error != nil {
+		r.log.Errorf("update order status failed: %v", res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrOrderStatusConflict
 	}
 
 	return nil
 }
 
-// 以下是旧接口方法，保持兼容性
-
-// CreateOrder 创建订单（旧方法）
-func (r *orderRepo) CreateOrder(ctx context.Context, order *biz.Order) error {
-	return r.Create(ctx, order)
-}
-
-// GetOrderByID 根据订单ID获取订单（旧方法）
-func (r *orderRepo) GetOrderByID(ctx context.Context, orderID int64) (*biz.Order, error) {
-	return r.GetByID(ctx, orderID)
-}
-
-// UpdateOrderStatus 更新订单状态（旧方法）
-func (r *orderRepo) UpdateOrderStatus(ctx context.Context, orderID int64, status string) error {
-	return r.UpdateStatus(ctx, orderID, status)
-}
-
-// GetProductByID 获取商品信息（包含规格）
-func (r *orderRepo) GetProductByID(ctx context.Context, productID int64) (*biz.Product, error) {
-	var productPo productPO
-	if err := r.data.db.WithContext(ctx).First(&productPo, productID).Error; err != nil {
-		r.log.Errorf("get product failed: productID=%d err=%v", productID, err)
-		return nil, err
-	}
-
-	// 查询关联的规格
-	var specPo productSpecPO
-	if err := r.data.db.WithContext(ctx).First(&specPo, productPo.SpecID).Error; err != nil {
-		r.log.Errorf("get product spec failed: specID=%d err=%v", productPo.SpecID, err)
-		return nil, err
+// SetPaymentID 记录订单的支付意图ID（重复写入同一ID幂等）
+func (r *orderRepo) SetPaymentID(ctx context.Context, orderID int64, paymentID string) error {
+	res := r.data.DB(ctx).Model(&orderPO{}).
+		Where("order_id = ? AND (payment_id IS NULL OR payment_id = ?)", orderID, paymentID).
+		Update("payment_id", paymentID)
+	if res.Error != nil {
+		r.log.Errorf("set order payment id failed: orderID=%d paymentID=%s err=%v", orderID, paymentID, res.Error)
+		return res.Error
 	}
-
-	product := &biz.Product{
-		ID:          productPo.ID,
-		Name:        productPo.Name,
-		Description: productPo.Description,
-		Status:      productPo.Status,
-		Price:       productPo.Price,
-		SpecID:      productPo.SpecID,
-		Spec: &biz.ProductSpec{
-			ID:         specPo.ID,
-			CPU:        specPo.CPU,
-			Memory:     specPo.Memory,
-			GPU:        specPo.GPU,
-			Image:      specPo.Image,
-			ConfigJSON: specPo.ConfigJSON,
-			CreatedAt:  specPo.CreatedAt,
-		},
-		CreatedAt: productPo.CreatedAt,
-		UpdatedAt: productPo.UpdatedAt,
+	if res.RowsAffected == 0 {
+		return biz.ErrOrderStatusConflict
 	}
-
-	return product, nil
+	return nil
 }
 
-// GetInstanceByID 根据实例ID获取实例信息
-func (r *orderRepo) GetInstanceByID(ctx context.Context, instanceID int64) (*biz.InstanceInfo, error) {
-	var order orderPO
-	err := r.data.db.WithContext(ctx).
-		Where("instance_id = ?", instanceID).
-		First(&order).Error
-	if err != nil {
-		r.log.Errorf("get order by instance_id failed: instanceID=%d err=%v", instanceID, err)
-		return nil, err
+// MarkRefunded 记录退款并取消订单（以当前状态作为乐观锁条件）
+func (r *orderRepo) MarkRefunded(ctx context.Context, orderID int64, from string, refund *biz.OrderRefund) error {
+	res := r.data.DB(ctx).Model(&orderPO{}).
+		Where("order_id = ? AND status = ?", orderID, from).
+		Updates(map[string]interface{}{
+			"status":        biz.OrderStatusCancelled,
+			"refund_amount": refund.Amount,
+			"refund_reason": refund.Reason,
+			"refund_id":     sql.NullString{String: refund.RefundID, Valid: refund.RefundID != ""},
+			"refund_manual": refund.Manual,
+			"refunded_at":   refund.RefundedAt,
+		})
+	if res.Error != nil {
+		r.log.Errorf("mark order refunded failed: orderID=%d err=%v", orderID, res.Error)
+		return res.Error
 	}
-
-	return r.buildInstanceInfo(ctx, &order)
+	if res.RowsAffected == 0 {
+		return biz.ErrOrderStatusConflict
+	}
+	return nil
 }
 
-// GetInstanceByOrderID 根据订单ID获取实例信息
-func (r *orderRepo) GetInstanceByOrderID(ctx context.Context, orderID int64) (*biz.InstanceInfo, error) {
-	var order orderPO
-	err := r.data.db.WithContext(ctx).
-		Where("order_id = ?", orderID).
-		First(&order).Error
+// ClaimExpiredPending 认领过期的待支付订单
+// 使用 FOR UPDATE SKIP LOCKED 选出订单并把 expiry_attempt_at 推迟 lease，多个副本不会认领同一个订单；
+// 认领语句提交后即释放行锁，查询支付网关期间不持有锁。按 COALESCE(expiry_attempt_at, created_at) 排序，
+// 处理失败的订单租期过后排在新过期的订单之后，不会占满每一批
+func (r *orderRepo) ClaimExpiredPending(ctx context.Context, before time.Time, limit int, lease time.Duration) ([]*biz.Order, error) {
+	now := time.Now()
+	var pos []orderPO
+	err := r.data.DB(ctx).Raw(`
+		UPDATE orders SET expiry_attempt_at = ?
+		WHERE order_id IN (
+			SELECT order_id FROM orders
+			WHERE status = ? AND created_at < ? AND (expiry_attempt_at IS NULL OR expiry_attempt_at <= ?)
+			ORDER BY COALESCE(expiry_attempt_at, created_at)
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), biz.OrderStatusPending, before, now, limit,
+	).Scan(&pos).Error
 	if err != nil {
-		r.log.Errorf("get order by order_id failed: orderID=%d err=%v", orderID, err)
+		r.log.Errorf("claim expired pending orders failed: err=%v", err)
 		return nil, err
 	}
 
-	if !order.InstanceID.Valid {
-		r.log.Errorf("order has no instance: orderID=%d", orderID)
-		return nil, sql.ErrNoRows
+	orders := make([]*biz.Order, 0, len(pos))
+	for i := range pos {
+		orders = append(orders, toOrder(&pos[i]))
 	}
-
-	return r.buildInstanceInfo(ctx, &order)
+	return orders, nil
 }
 
-// ListInstances 查询实例列表
-func (r *orderRepo) ListInstances(ctx context.Context, filter biz.InstanceFilter) ([]*biz.InstanceInfo, int64, error) {
-	query := r.data.db.WithContext(ctx).Model(&orderPO{}).
-		Where("instance_id IS NOT NULL")
-
-	// 用户ID过滤
-	if filter.UserID != "" {
-		query = query.Where("user_id = ?", filter.UserID)
-	}
-
-	// 状态过滤
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
-	}
-
-	// 统计总数
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		r.log.Errorf("count instances failed: err=%v", err)
-		return nil, 0, err
-	}
-
-	// 分页查询
-	var orders []orderPO
-	offset := int((filter.Page - 1) * filter.PageSize)
-	err := query.
-		Order("created_at DESC").
-		Limit(int(filter.PageSize)).
-		Offset(offset).
-		Find(&orders).Error
-	if err != nil {
-		r.log.Errorf("list instances failed: err=%v", err)
-		return nil, 0, err
-	}
-
-	// 构建实例信息列表
-	instances := make([]*biz.InstanceInfo, 0, len(orders))
-	for i := range orders {
-		instance, err := r.buildInstanceInfo(ctx, &orders[i])
-		if err != nil {
-			r.log.Warnf("build instance info failed: orderID=%d err=%v", orders[i].OrderID, err)
-			continue
-		}
-		instances = append(instances, instance)
-	}
+// 以下是旧接口方法，保持兼容性
 
-	return instances, total, nil
+// CreateOrder 创建订单（旧方法）
+func (r *orderRepo) CreateOrder(ctx context.Context, order *biz.Order) error {
+	return r.Create(ctx, order)
 }
 
-// buildInstanceInfo 构建实例信息
-func (r *orderRepo) buildInstanceInfo(ctx context.Context, order *orderPO) (*biz.InstanceInfo, error) {
-	if !order.InstanceID.Valid {
-		return nil, sql.ErrNoRows
-	}
-
-	// 查询商品信息
-	product, err := r.GetProductByID(ctx, order.ProductID)
-	if err != nil {
-		return nil, err
-	}
-
-	// 从订单状态推断实例状态
-	status := "UNKNOWN"
-	switch order.Status {
-	case "PAID":
-		status = "CREATING"
-	case "COMPLETED":
-		status = "RUNNING"
-	case "CANCELLED":
-		status = "DELETED"
-	}
-
-	return &biz.InstanceInfo{
-		InstanceID:  order.InstanceID.Int64,
-		UserID:      order.UserID,
-		OrderID:     order.OrderID,
-		ProductID:   order.ProductID,
-		ProductName: product.Name,
-		Spec:        product.Spec,
-		Status:      status,
-		CreatedAt:   order.CreatedAt,
-	}, nil
+// GetOrderByID 根据订单ID获取订单（旧方法）
+func (r *orderRepo) GetOrderByID(ctx context.Context, orderID int64) (*biz.Order, error) {
+	return r.GetByID(ctx, orderID)
 }
@@ -2,42 +2,250 @@ package data
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"product/internal/biz"
+	"product/internal/conf"
 
-	"github.com/cespare/xxhash/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
 )
 
+// 订单 ID 结构见 biz.OrderID：41 位毫秒时间戳 + 10 位 worker ID + 12 位毫秒内序号，
+// worker ID 每个副本唯一（配置指定或通过 Redis 租约分配），同一毫秒的序号用尽时等待下一毫秒
+const (
+	// orderIDMaxBackward 时钟回拨在该范围内时等待追上，超过则拒绝生成
+	orderIDMaxBackward = 5 * time.Millisecond
+	// defaultOrderIDLeaseTTL Redis worker 租约默认有效期，每 1/3 有效期续约一次
+	defaultOrderIDLeaseTTL = 30 * time.Second
+	// orderIDReacquireMinDelay 租约丢失后重新抢占的首次退避，之后加倍直到租约有效期
+	orderIDReacquireMinDelay = time.Second
+	// orderIDWorkerKeyPrefix worker 租约 Key 前缀，值为持有者标识
+	orderIDWorkerKeyPrefix = "idgen:order:worker:"
+)
+
+var (
+	errOrderIDClockBackward = errors.New("order id generator: clock moved backwards")
+	errOrderIDLeaseExpired  = errors.New("order id generator: worker lease expired")
+)
+
+// renewOrderIDLeaseScript 仅当租约仍由自己持有时续约
+var renewOrderIDLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseOrderIDLeaseScript 仅当租约仍由自己持有时释放
+var releaseOrderIDLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
 type orderIDGenerator struct {
+	workerID int64
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lastMs   int64
+	sequence int64
+
+	// Redis 租约分配 worker ID 时使用，validUntil 之后未续约成功则拒绝生成
+	rdb        *redis.Client
+	leaseKey   string
+	leaseOwner string
+	leaseTTL   time.Duration
+	validUntil time.Time
+	stop       chan struct{}
+	wg         sync.WaitGroup
+
 	log *log.Helper
 }
 
 // NewOrderIDGenerator 创建订单 ID 生成器
-func NewOrderIDGenerator(logger log.Logger) biz.OrderIDGenerator {
-	return &orderIDGenerator{
-		log: log.NewHelper(logger),
+// worker ID 优先取配置 data.id_generator.worker_id（可固定为 0）；未配置时通过 Redis 租约在 0-1023 中抢占一个，
+// 后台定期续约，租约丢失后重新抢占，退出时释放。Redis 不可用时退化为 worker 0（仅适用于单副本部署）
+func NewOrderIDGenerator(c *conf.Data, d *Data, logger log.Logger) (biz.OrderIDGenerator, func(), error) {
+	helper := log.NewHelper(logger)
+	g := &orderIDGenerator{
+		now: time.Now,
+		log: helper,
+	}
+
+	if ic := c.GetIdGenerator(); ic != nil && ic.WorkerId != nil {
+		workerID := int64(ic.GetWorkerId())
+		if workerID < 0 || workerID > biz.OrderIDMaxWorker {
+			return nil, nil, fmt.Errorf("order id worker id %d out of range [0, %d]", workerID, biz.OrderIDMaxWorker)
+		}
+		g.workerID = workerID
+		helper.Infof("order id generator started: workerID=%d (config)", workerID)
+		return g, func() {}, nil
+	}
+
+	if d == nil || d.redis == nil {
+		helper.Warn("order id worker id not configured and redis unavailable, using worker 0 (single replica only)")
+		return g, func() {}, nil
+	}
+
+	g.rdb = d.redis
+	g.leaseTTL = c.GetIdGenerator().GetLeaseTtl().AsDuration()
+	if g.leaseTTL <= 0 {
+		g.leaseTTL = defaultOrderIDLeaseTTL
+	}
+	host, _ := os.Hostname()
+	g.leaseOwner = fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+	if err := g.acquireLease(context.Background()); err != nil {
+		return nil, nil, err
+	}
+
+	g.stop = make(chan struct{})
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.renewLoop()
+	}()
+
+	helper.Infof("order id generator started: workerID=%d (redis lease %s)", g.workerID, g.leaseKey)
+	cleanup := func() {
+		close(g.stop)
+		g.wg.Wait()
+		if err := releaseOrderIDLeaseScript.Run(context.Background(), g.rdb, []string{g.leaseKey}, g.leaseOwner).Err(); err != nil {
+			helper.Errorf("release order id worker lease failed: key=%s err=%v", g.leaseKey, err)
+		}
 	}
+	return g, cleanup, nil
 }
 
-// Generate 生成订单 ID
-// 结构：[0(1位)][timestamp(46位)][uid_hash(15位)]
-// - 最高位为0，保证为正数
-// - 接下来46位为时间戳（从 EpochMsStart 开始的毫秒数）
-// - 最后15位为用户ID的哈希值
+// acquireLease 依次尝试 SET NX 每个 worker ID 的租约 Key
+func (g *orderIDGenerator) acquireLease(ctx context.Context) error {
+	for workerID := int64(0); workerID <= biz.OrderIDMaxWorker; workerID++ {
+		key := fmt.Sprintf("%s%d", orderIDWorkerKeyPrefix, workerID)
+		ok, err := g.rdb.SetNX(ctx, key, g.leaseOwner, g.leaseTTL).Result()
+		if err != nil {
+			return fmt.Errorf("acquire order id worker lease: %w", err)
+		}
+		if ok {
+			g.mu.Lock()
+			g.workerID = workerID
+			g.leaseKey = key
+			g.validUntil = time.Now().Add(g.leaseTTL)
+			g.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("acquire order id worker lease: all %d worker ids are taken", biz.OrderIDMaxWorker+1)
+}
+
+// renewLoop 每 1/3 租约有效期续约一次；Redis 不可用时生成器在租约到期后拒绝生成，
+// 租约丢失（已过期或被他人持有）时立即停止生成并重新抢占 worker ID
+func (g *orderIDGenerator) renewLoop() {
+	ticker := time.NewTicker(g.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		renewed, err := renewOrderIDLeaseScript.Run(context.Background(), g.rdb, []string{g.leaseKey},
+			g.leaseOwner, g.leaseTTL.Milliseconds()).Int()
+		if err != nil {
+			g.log.Errorf("renew order id worker lease failed: key=%s err=%v", g.leaseKey, err)
+			continue
+		}
+		if renewed == 0 {
+			g.log.Errorf("order id worker lease lost: key=%s, reacquiring", g.leaseKey)
+			g.mu.Lock()
+			g.validUntil = time.Time{}
+			g.mu.Unlock()
+			if !g.reacquireLease() {
+				return
+			}
+			continue
+		}
+		g.mu.Lock()
+		g.validUntil = start.Add(g.leaseTTL)
+		g.mu.Unlock()
+	}
+}
+
+// reacquireLease 按指数退避重新抢占 worker ID，直到成功或生成器关闭（返回 false）
+func (g *orderIDGenerator) reacquireLease() bool {
+	delay := orderIDReacquireMinDelay
+	for {
+		err := g.acquireLease(context.Background())
+		if err == nil {
+			g.log.Infof("order id worker lease reacquired: workerID=%d key=%s", g.workerID, g.leaseKey)
+			return true
+		}
+		g.log.Errorf("reacquire order id worker lease failed, retry in %s: %v", delay, err)
+
+		select {
+		case <-g.stop:
+			return false
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > g.leaseTTL {
+			delay = g.leaseTTL
+		}
+	}
+}
+
+// Generate 生成订单 ID，同一副本内严格递增
+// 时钟回拨不超过 orderIDMaxBackward 时等待追上，超过时返回错误，避免生成重复 ID
 func (g *orderIDGenerator) Generate(ctx context.Context, userID string) (int64, error) {
-	nowMs := time.Now().UnixMilli()
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	// 计算相对时间戳（46位）
-	relativeTime := (nowMs - EpochMsStart) & 0x3FFFFFFFFFFF // 取低46位
+	now := g.now()
+	if g.rdb != nil && now.After(g.validUntil) {
+		return 0, errOrderIDLeaseExpired
+	}
 
-	// 计算用户ID哈希（15位）
-	uidHash := int64(xxhash.Sum64String(userID) & 0x7FFF) // 取低15位
+	ms := now.UnixMilli() - EpochMsStart
+	if ms < g.lastMs {
+		backward := time.Duration(g.lastMs-ms) * time.Millisecond
+		if backward > orderIDMaxBackward {
+			g.log.Errorf("clock moved backwards by %s, refusing to generate order id", backward)
+			return 0, errOrderIDClockBackward
+		}
+		ms = g.waitUntil(g.lastMs)
+	}
 
-	// 组合：timestamp左移15位 | uid_hash
-	// 最高位自动为0（因为我们只使用了61位：46+15）
-	orderID := (relativeTime << 15) | uidHash
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & biz.OrderIDMaxSequence
+		if g.sequence == 0 {
+			// 当前毫秒的序号用尽
+			ms = g.waitUntil(g.lastMs + 1)
+		}
+	} else {
+		g.sequence = 0
+	}
+	if ms > biz.OrderIDMaxTimestamp {
+		return 0, fmt.Errorf("order id generator: timestamp overflow")
+	}
+	g.lastMs = ms
+
+	return int64(biz.NewOrderID(ms, g.workerID, g.sequence)), nil
+}
 
-	return orderID, nil
+// waitUntil 自旋等待到相对毫秒数不小于 target
+func (g *orderIDGenerator) waitUntil(target int64) int64 {
+	for {
+		ms := g.now().UnixMilli() - EpochMsStart
+		if ms >= target {
+			return ms
+		}
+		time.Sleep(time.Duration(target-ms) * time.Millisecond)
+	}
 }
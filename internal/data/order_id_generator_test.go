@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// sequenceClock 依次返回 times 中的时间，用完后一直返回最后一个
+func sequenceClock(times ...time.Time) func() time.Time {
+	i := 0
+	return func() time.Time {
+		t := times[min(i, len(times)-1)]
+		i++
+		return t
+	}
+}
+
+func newPinnedOrderIDGenerator(t *testing.T, workerID int32) *orderIDGenerator {
+	t.Helper()
+	c := &conf.Data{IdGenerator: &conf.Data_IDGenerator{WorkerId: proto.Int32(workerID)}}
+	g, cleanup, err := NewOrderIDGenerator(c, nil, log.DefaultLogger)
+	if err != nil {
+		t.Fatalf("NewOrderIDGenerator() error = %v", err)
+	}
+	t.Cleanup(cleanup)
+	return g.(*orderIDGenerator)
+}
+
+func TestOrderIDGenerator_SequenceRollover(t *testing.T) {
+	ctx := context.Background()
+	g := newPinnedOrderIDGenerator(t, 0)
+	base := time.UnixMilli(EpochMsStart + 1000)
+
+	// 同一毫秒内生成 4096 个 ID 用尽序号，第 4097 个等到下一毫秒
+	times := make([]time.Time, biz.OrderIDMaxSequence+2)
+	for i := range times {
+		times[i] = base
+	}
+	g.now = sequenceClock(append(times, base.Add(time.Millisecond))...)
+
+	seen := make(map[int64]bool)
+	var last int64
+	for i := 0; i <= biz.OrderIDMaxSequence+1; i++ {
+		id, err := g.Generate(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Generate() #%d error = %v", i, err)
+		}
+		if seen[id] || id <= last {
+			t.Fatalf("Generate() #%d = %d, want unique and increasing (last %d)", i, id, last)
+		}
+		seen[id] = true
+		last = id
+
+		at, workerID, seq := biz.OrderID(id).Decode()
+		wantAt, wantSeq := base, int64(i)
+		if i > biz.OrderIDMaxSequence {
+			wantAt, wantSeq = base.Add(time.Millisecond), 0
+		}
+		if !at.Equal(wantAt) || workerID != 0 || seq != wantSeq {
+			t.Fatalf("Generate() #%d decodes to (%s, %d, %d), want (%s, 0, %d)", i, at, workerID, seq, wantAt, wantSeq)
+		}
+	}
+}
+
+func TestOrderIDGenerator_ClockBackward(t *testing.T) {
+	ctx := context.Background()
+	g := newPinnedOrderIDGenerator(t, 7)
+	base := time.UnixMilli(EpochMsStart + 1000)
+
+	g.now = sequenceClock(base)
+	first, err := g.Generate(ctx, "u1")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// 回拨 2ms（不超过 orderIDMaxBackward）：等待时钟追上后继续递增
+	g.now = sequenceClock(base.Add(-2*time.Millisecond), base.Add(-time.Millisecond), base)
+	second, err := g.Generate(ctx, "u1")
+	if err != nil || second <= first {
+		t.Fatalf("Generate() after small rollback = %d, %v, want > %d", second, err, first)
+	}
+	if at, workerID, seq := biz.OrderID(second).Decode(); !at.Equal(base) || workerID != 7 || seq != 1 {
+		t.Errorf("Generate() after small rollback decodes to (%s, %d, %d), want (%s, 7, 1)", at, workerID, seq, base)
+	}
+
+	// 回拨超过 orderIDMaxBackward：拒绝生成
+	g.now = sequenceClock(base.Add(-orderIDMaxBackward - time.Millisecond))
+	if _, err := g.Generate(ctx, "u1"); !errors.Is(err, errOrderIDClockBackward) {
+		t.Errorf("Generate() after large rollback error = %v, want %v", err, errOrderIDClockBackward)
+	}
+}
+
+func TestNewOrderIDGenerator_WorkerIDOutOfRange(t *testing.T) {
+	for _, workerID := range []int32{-1, biz.OrderIDMaxWorker + 1} {
+		c := &conf.Data{IdGenerator: &conf.Data_IDGenerator{WorkerId: proto.Int32(workerID)}}
+		if _, _, err := NewOrderIDGenerator(c, nil, log.DefaultLogger); err == nil {
+			t.Errorf("NewOrderIDGenerator(worker %d) error = nil, want out of range", workerID)
+		}
+	}
+}
+
+func TestOrderIDGenerator_Lease(t *testing.T) {
+	ctx := context.Background()
+	mr, d := newTestRedis(t)
+	c := &conf.Data{IdGenerator: &conf.Data_IDGenerator{LeaseTtl: durationpb.New(300 * time.Millisecond)}}
+
+	newGenerator := func() *orderIDGenerator {
+		g, cleanup, err := NewOrderIDGenerator(c, d, log.DefaultLogger)
+		if err != nil {
+			t.Fatalf("NewOrderIDGenerator() error = %v", err)
+		}
+		t.Cleanup(cleanup)
+		return g.(*orderIDGenerator)
+	}
+	first, second := newGenerator(), newGenerator()
+	if first.workerID != 0 || second.workerID != 1 {
+		t.Fatalf("worker ids = %d, %d, want 0 and 1", first.workerID, second.workerID)
+	}
+	if _, err := first.Generate(ctx, "u1"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// 租约到期未续约：拒绝生成
+	first.mu.Lock()
+	validUntil := first.validUntil
+	first.mu.Unlock()
+	now := first.now
+	first.now = func() time.Time { return validUntil.Add(time.Millisecond) }
+	if _, err := first.Generate(ctx, "u1"); !errors.Is(err, errOrderIDLeaseExpired) {
+		t.Errorf("Generate() after lease expiry error = %v, want %v", err, errOrderIDLeaseExpired)
+	}
+	first.now = now
+
+	// 租约被他人占用：续约失败后重新抢占空闲的 worker ID
+	mr.Set(fmt.Sprintf("%s%d", orderIDWorkerKeyPrefix, 0), "someone-else")
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		first.mu.Lock()
+		workerID, valid := first.workerID, time.Now().Before(first.validUntil)
+		first.mu.Unlock()
+		if workerID == 2 && valid {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lease not reacquired: workerID = %d valid = %v, want worker 2", workerID, valid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	id, err := first.Generate(ctx, "u1")
+	if err != nil {
+		t.Fatalf("Generate() after reacquire error = %v", err)
+	}
+	if _, workerID, _ := biz.OrderID(id).Decode(); workerID != 2 {
+		t.Errorf("Generate() after reacquire worker = %d, want 2", workerID)
+	}
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// outboxPO 发件箱持久化对象
+type outboxPO struct {
+	ID            int64        `gorm:"column:id;primaryKey;autoIncrement"`
+	EventType     string       `gorm:"column:event_type;type:varchar(64);not null"`
+	RoutingKey    string       `gorm:"column:routing_key;type:varchar(128);not null"`
+	AggregateID   int64        `gorm:"column:aggregate_id;not null"` // 实例 ID
+	Payload       []byte       `gorm:"column:payload;type:bytea;not null"`
+	Status        string       `gorm:"column:status;type:varchar(20);not null;default:PENDING"`
+	Attempts      int32        `gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt time.Time    `gorm:"column:next_attempt_at;not null"`
+	LastError     string       `gorm:"column:last_error;type:text"`
+	CreatedAt     time.Time    `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	SentAt        sql.NullTime `gorm:"column:sent_at"`
+}
+
+func (outboxPO) TableName() string {
+	return "order_outbox"
+}
+
+type outboxRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewOutboxRepo 创建发件箱仓储
+func NewOutboxRepo(data *Data, logger log.Logger) biz.OutboxRepo {
+	return &outboxRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// EnqueueInstanceEvent 写入实例事件（跟随 ctx 中的事务）
+func (r *outboxRepo) EnqueueInstanceEvent(ctx context.Context, eventType string, spec biz.InstanceSpec) error {
+	body, err := marshalInstanceEvent(eventType, spec)
+	if err != nil {
+		r.log.Errorf("marshal event failed: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	po := &outboxPO{
+		EventType:     eventType,
+		RoutingKey:    routingKeyFor(eventType),
+		AggregateID:   spec.InstanceID,
+		Payload:       body,
+		Status:        biz.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := r.data.DB(ctx).Create(po).Error; err != nil {
+		r.log.Errorf("enqueue outbox message failed: eventType=%s instanceID=%d err=%v", eventType, spec.InstanceID, err)
+		return err
+	}
+	return nil
+}
+
+// ClaimPending 认领到期的待投递消息
+// 使用 FOR UPDATE SKIP LOCKED 选出消息并把 next_attempt_at 推迟 lease，多个副本不会认领同一条消息
+func (r *outboxRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*biz.OutboxMessage, error) {
+	now := time.Now()
+	var rows []outboxPO
+	err := r.data.DB(ctx).Raw(`
+		UPDATE order_outbox SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM order_outbox
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), biz.OutboxStatusPending, now, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*biz.OutboxMessage, 0, len(rows))
+	for i := range rows {
+		msgs = append(msgs, toOutboxMessage(&rows[i]))
+	}
+	return msgs, nil
+}
+
+// MarkSent 标记消息已投递
+func (r *outboxRepo) MarkSent(ctx context.Context, id int64) error {
+	return r.data.DB(ctx).Model(&outboxPO{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":  biz.OutboxStatusSent,
+			"sent_at": sql.NullTime{Time: time.Now(), Valid: true},
+		}).Error
+}
+
+// MarkRetry 记录投递失败并安排下次重试
+func (r *outboxRepo) MarkRetry(ctx context.Context, id int64, attempts int32, nextAttemptAt time.Time, lastErr string) error {
+	return r.data.DB(ctx).Model(&outboxPO{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastErr,
+		}).Error
+}
+
+// MarkDead 记录投递失败并停止重试
+func (r *outboxRepo) MarkDead(ctx context.Context, id int64, attempts int32, lastErr string) error {
+	return r.data.DB(ctx).Model(&outboxPO{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     biz.OutboxStatusDead,
+			"attempts":   attempts,
+			"last_error": lastErr,
+		}).Error
+}
+
+// CountPending 统计待投递消息数
+func (r *outboxRepo) CountPending(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.data.DB(ctx).Model(&outboxPO{}).
+		Where("status = ?", biz.OutboxStatusPending).
+		Count(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func toOutboxMessage(po *outboxPO) *biz.OutboxMessage {
+	msg := &biz.OutboxMessage{
+		ID:            po.ID,
+		EventType:     po.EventType,
+		RoutingKey:    po.RoutingKey,
+		AggregateID:   po.AggregateID,
+		Payload:       po.Payload,
+		Status:        po.Status,
+		Attempts:      po.Attempts,
+		NextAttemptAt: po.NextAttemptAt,
+		LastError:     po.LastError,
+		CreatedAt:     po.CreatedAt,
+	}
+	if po.SentAt.Valid {
+		msg.SentAt = &po.SentAt.Time
+	}
+	return msg
+}
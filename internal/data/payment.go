@@ -0,0 +1,195 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// paymentWebhookTimeout fake 网关回调的超时时间
+const paymentWebhookTimeout = 5 * time.Second
+
+// NewPaymentGateway 按配置创建支付网关
+// 必须显式配置 provider：fake 网关的 Confirm 总是成功，缺少支付配置的部署不能默认使用它
+func NewPaymentGateway(c *conf.Data, logger log.Logger) (biz.PaymentGateway, func(), error) {
+	switch provider := c.GetPayment().GetProvider(); provider {
+	case "":
+		return nil, nil, fmt.Errorf("payment provider not configured: set data.payment.provider (supported: fake)")
+	case "fake":
+		g := newFakePaymentGateway(c.GetPayment(), logger)
+		return g, g.close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported payment provider: %s", provider)
+	}
+}
+
+// fakePaymentGateway 本地内存支付网关，用于离线开发和测试（进程重启后支付意图丢失）
+// 支付意图可通过 Confirm 同步确认；配置了 settle_after 时创建后自动支付成功，
+// 并在配置了 webhook_url 时像真实网关一样回调通知
+type fakePaymentGateway struct {
+	mu      sync.Mutex
+	intents map[string]*biz.PaymentIntent
+	refunds int64
+
+	settleAfter time.Duration
+	webhookURL  string
+	client      *http.Client
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	log         *log.Helper
+}
+
+func newFakePaymentGateway(c *conf.Data_Payment, logger log.Logger) *fakePaymentGateway {
+	g := &fakePaymentGateway{
+		intents:     make(map[string]*biz.PaymentIntent),
+		settleAfter: c.GetSettleAfter().AsDuration(),
+		webhookURL:  c.GetWebhookUrl(),
+		client:      &http.Client{Timeout: paymentWebhookTimeout},
+		stop:        make(chan struct{}),
+		log:         log.NewHelper(logger),
+	}
+	g.log.Warnf("using fake payment gateway (local testing only): settleAfter=%s webhookURL=%q", g.settleAfter, g.webhookURL)
+	return g
+}
+
+// close 停止尚未触发的自动支付
+func (g *fakePaymentGateway) close() {
+	close(g.stop)
+	g.wg.Wait()
+}
+
+// CreateIntent 为订单创建支付意图，支付意图ID由订单ID确定，同一订单重复调用返回同一意图
+func (g *fakePaymentGateway) CreateIntent(ctx context.Context, order *biz.Order) (*biz.PaymentIntent, error) {
+	id := fmt.Sprintf("pi_fake_%d", order.ID)
+
+	g.mu.Lock()
+	intent, ok := g.intents[id]
+	if !ok {
+		intent = &biz.PaymentIntent{
+			ID:        id,
+			OrderID:   order.ID,
+			UserID:    order.UserID,
+			Amount:    order.Amount,
+			Status:    biz.PaymentStatusRequiresConfirmation,
+			CreatedAt: time.Now(),
+		}
+		g.intents[id] = intent
+	}
+	copied := *intent
+	g.mu.Unlock()
+
+	if !ok && g.settleAfter > 0 {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.settleLater(id)
+		}()
+	}
+	return &copied, nil
+}
+
+// Confirm 同步确认支付，fake 网关总是支付成功
+func (g *fakePaymentGateway) Confirm(ctx context.Context, paymentID string) (*biz.PaymentIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, biz.ErrPaymentNotFound
+	}
+	if intent.Status == biz.PaymentStatusRequiresConfirmation || intent.Status == biz.PaymentStatusFailed {
+		intent.Status = biz.PaymentStatusSucceeded
+		intent.FailureReason = ""
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+// Refund 退款，仅支付成功的意图可以退款，累计退款不能超过支付金额
+func (g *fakePaymentGateway) Refund(ctx context.Context, paymentID string, amount int64) (*biz.PaymentRefund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, biz.ErrPaymentNotFound
+	}
+	if intent.Status != biz.PaymentStatusSucceeded {
+		return nil, biz.ErrPaymentNotSettled
+	}
+	if amount <= 0 || amount > intent.Amount-intent.Refunded {
+		return nil, biz.ErrInvalidRefund
+	}
+	intent.Refunded += amount
+	g.refunds++
+	return &biz.PaymentRefund{
+		ID:        fmt.Sprintf("re_fake_%d", g.refunds),
+		PaymentID: paymentID,
+		Amount:    amount,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Query 查询支付意图
+func (g *fakePaymentGateway) Query(ctx context.Context, paymentID string) (*biz.PaymentIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil, biz.ErrPaymentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+// settleLater 延迟 settleAfter 后自动支付成功并回调
+func (g *fakePaymentGateway) settleLater(paymentID string) {
+	timer := time.NewTimer(g.settleAfter)
+	defer timer.Stop()
+	select {
+	case <-g.stop:
+		return
+	case <-timer.C:
+	}
+
+	if _, err := g.Confirm(context.Background(), paymentID); err != nil {
+		g.log.Errorf("fake payment settle failed: paymentID=%s err=%v", paymentID, err)
+		return
+	}
+	if g.webhookURL == "" {
+		return
+	}
+	if err := g.notify(paymentID); err != nil {
+		g.log.Errorf("fake payment webhook failed: paymentID=%s url=%s err=%v", paymentID, g.webhookURL, err)
+	}
+}
+
+// notify 回调 webhook（不重试，回调丢失时可调用 ConfirmPayment 同步确认）
+func (g *fakePaymentGateway) notify(paymentID string) error {
+	body, err := json.Marshal(map[string]string{
+		"payment_id": paymentID,
+		"event":      "payment.succeeded",
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Post(g.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
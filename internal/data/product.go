@@ -9,19 +9,21 @@ import (
 
 	"github.com/go-kratos/kratos/v2/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // productPO 商品持久化对象
 // 商品是可售卖的套餐/SKU
 type productPO struct {
-	ID          int64     `gorm:"primaryKey;autoIncrement;column:product_id"`
-	Name        string    `gorm:"column:name;size:128;not null"`
-	Description string    `gorm:"column:description;type:text"`
-	Status      string    `gorm:"column:status;type:varchar(20);default:'ENABLED'"` // ENABLED=上架, DISABLED=下架
-	Price       int64     `gorm:"column:price;not null"`                            // 单位：分
-	SpecID      int64     `gorm:"column:spec_id;not null"`                          // 关联规格ID
-	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	ID          int64          `gorm:"primaryKey;autoIncrement;column:product_id"`
+	Name        string         `gorm:"column:name;size:128;not null"`
+	Description string         `gorm:"column:description;type:text"`
+	Status      string         `gorm:"column:status;type:varchar(20);default:'ENABLED'"` // ENABLED=上架, DISABLED=下架
+	Price       int64          `gorm:"column:price;not null"`                            // 单位：分
+	SpecID      int64          `gorm:"column:spec_id;not null"`                          // 关联规格ID
+	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at;autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;index"` // 软删除
 }
 
 func (productPO) TableName() string {
@@ -44,6 +46,20 @@ func (productSpecPO) TableName() string {
 	return "product_specs"
 }
 
+// productSpecVersionPO 商品规格版本持久化对象
+// 记录商品在每个时间段使用的规格，规格本身不可变
+type productSpecVersionPO struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement;column:id"`
+	ProductID int64     `gorm:"column:product_id;not null;uniqueIndex:uk_product_spec_version"`
+	Version   int32     `gorm:"column:version;not null;uniqueIndex:uk_product_spec_version"`
+	SpecID    int64     `gorm:"column:spec_id;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"` // 生效时间
+}
+
+func (productSpecVersionPO) TableName() string {
+	return "product_spec_versions"
+}
+
 type productRepo struct {
 	data *Data
 	log  *log.Helper
@@ -60,18 +76,22 @@ func NewProductRepo(data *Data, logger log.Logger) biz.ProductRepo {
 // GetByID returns a product by ID with its spec.
 func (r *productRepo) GetByID(ctx context.Context, productID int64) (*biz.Product, error) {
 	var row productListRow
-	err := r.data.db.WithContext(ctx).
+	res := r.data.DB(ctx).
 		Model(&productPO{}).
 		Joins("JOIN product_specs ON product_specs.spec_id = products.spec_id").
 		Where("products.product_id = ?", productID).
 		Select(selectProductListColumns()).
-		Scan(&row).Error
+		Scan(&row)
 
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+	if res.Error != nil {
+		if res.Error == gorm.ErrRecordNotFound {
 			return nil, biz.ErrProductNotFound
 		}
-		return nil, err
+		return nil, res.Error
+	}
+	// Scan 查不到数据时不返回错误
+	if res.RowsAffected == 0 {
+		return nil, biz.ErrProductNotFound
 	}
 
 	product := &biz.Product{
@@ -164,7 +184,7 @@ func (r *productRepo) List(ctx context.Context, filter biz.ProductFilter) ([]*bi
 
 // Create creates a new product with spec.
 func (r *productRepo) Create(ctx context.Context, product *biz.Product) error {
-	return r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.data.DB(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. 创建规格（spec_id 由数据库自增生成）
 		specPO := &productSpecPO{
 			CPU:        product.Spec.CPU,
@@ -192,7 +212,18 @@ func (r *productRepo) Create(ctx context.Context, product *biz.Product) error {
 			return err
 		}
 
-		// 3. 更新返回值（使用数据库生成的 ID）
+		// 3. 记录规格版本 1
+		versionPO := &productSpecVersionPO{
+			ProductID: productPO.ID,
+			Version:   1,
+			SpecID:    specPO.ID,
+		}
+		if err := tx.Create(versionPO).Error; err != nil {
+			r.log.Errorf("create product spec version failed: %v", err)
+			return err
+		}
+
+		// 4. 更新返回值（使用数据库生成的 ID）
 		product.ID = productPO.ID
 		product.SpecID = specPO.ID
 		product.Spec.ID = specPO.ID
@@ -204,6 +235,225 @@ func (r *productRepo) Create(ctx context.Context, product *biz.Product) error {
 	})
 }
 
+// Update writes only the given fields of product.
+func (r *productRepo) Update(ctx context.Context, product *biz.Product, fields []string) error {
+	updates := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case biz.ProductFieldName:
+			updates["name"] = product.Name
+		case biz.ProductFieldDescription:
+			updates["description"] = product.Description
+		case biz.ProductFieldPrice:
+			updates["price"] = product.Price
+		default:
+			return fmt.Errorf("%w: %s", biz.ErrUnsupportedField, field)
+		}
+	}
+
+	res := r.data.DB(ctx).Model(&productPO{}).
+		Where("product_id = ?", product.ID).
+		Updates(updates)
+	if res.Error != nil {
+		r.log.Errorf("update product failed: productID=%d err=%v", product.ID, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrProductNotFound
+	}
+	return nil
+}
+
+// UpdateStatus changes the product status.
+func (r *productRepo) UpdateStatus(ctx context.Context, productID int64, status string) error {
+	res := r.data.DB(ctx).Model(&productPO{}).
+		Where("product_id = ?", productID).
+		Update("status", status)
+	if res.Error != nil {
+		r.log.Errorf("update product status failed: productID=%d err=%v", productID, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrProductNotFound
+	}
+	return nil
+}
+
+// Delete soft deletes a product and disables it, so it can no longer be purchased.
+func (r *productRepo) Delete(ctx context.Context, productID int64) error {
+	return r.data.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&productPO{}).
+			Where("product_id = ?", productID).
+			Update("status", biz.ProductStatusDisabled)
+		if res.Error != nil {
+			r.log.Errorf("disable product failed: productID=%d err=%v", productID, res.Error)
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return biz.ErrProductNotFound
+		}
+
+		if err := tx.Where("product_id = ?", productID).Delete(&productPO{}).Error; err != nil {
+			r.log.Errorf("delete product failed: productID=%d err=%v", productID, err)
+			return err
+		}
+		return nil
+	})
+}
+
+// ChangeSpec creates a new spec and repoints the product to it in one transaction.
+func (r *productRepo) ChangeSpec(ctx context.Context, productID int64, spec *biz.ProductSpec) (*biz.ProductSpecVersion, error) {
+	var version *biz.ProductSpecVersion
+	err := r.data.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定商品行，串行化同一商品的并发规格变更
+		var product productPO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", productID).
+			First(&product).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return biz.ErrProductNotFound
+			}
+			return err
+		}
+
+		// 2. 计算下一个版本号
+		var maxVersion int32
+		if err := tx.Model(&productSpecVersionPO{}).
+			Where("product_id = ?", productID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		if maxVersion == 0 {
+			// 没有版本记录的旧商品：先把当前规格补记为版本 1
+			legacy := &productSpecVersionPO{
+				ProductID: productID,
+				Version:   1,
+				SpecID:    product.SpecID,
+				CreatedAt: product.CreatedAt,
+			}
+			if err := tx.Create(legacy).Error; err != nil {
+				r.log.Errorf("backfill product spec version failed: %v", err)
+				return err
+			}
+			maxVersion = 1
+		}
+
+		// 3. 创建新规格（旧规格保持不变）
+		specPO := &productSpecPO{
+			CPU:        spec.CPU,
+			Memory:     spec.Memory,
+			GPU:        spec.GPU,
+			Image:      spec.Image,
+			ConfigJSON: spec.ConfigJSON,
+		}
+		if err := tx.Create(specPO).Error; err != nil {
+			r.log.Errorf("create product spec failed: %v", err)
+			return err
+		}
+
+		// 4. 记录新版本
+		versionPO := &productSpecVersionPO{
+			ProductID: productID,
+			Version:   maxVersion + 1,
+			SpecID:    specPO.ID,
+		}
+		if err := tx.Create(versionPO).Error; err != nil {
+			r.log.Errorf("create product spec version failed: %v", err)
+			return err
+		}
+
+		// 5. 商品指向新规格
+		if err := tx.Model(&productPO{}).
+			Where("product_id = ?", productID).
+			Update("spec_id", specPO.ID).Error; err != nil {
+			r.log.Errorf("repoint product spec failed: %v", err)
+			return err
+		}
+
+		spec.ID = specPO.ID
+		spec.CreatedAt = specPO.CreatedAt
+		version = &biz.ProductSpecVersion{
+			ProductID:     productID,
+			Version:       versionPO.Version,
+			Spec:          spec,
+			EffectiveFrom: versionPO.CreatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// ListSpecHistory returns all spec versions of a product, newest first.
+func (r *productRepo) ListSpecHistory(ctx context.Context, productID int64) ([]*biz.ProductSpecVersion, error) {
+	var rows []productSpecVersionRow
+	err := r.data.DB(ctx).
+		Model(&productSpecVersionPO{}).
+		Joins("JOIN product_specs ON product_specs.spec_id = product_spec_versions.spec_id").
+		Where("product_spec_versions.product_id = ?", productID).
+		Order("product_spec_versions.version DESC").
+		Select("product_spec_versions.version, product_spec_versions.created_at AS effective_from, " +
+			"product_specs.spec_id, product_specs.cpu, product_specs.memory, product_specs.gpu, " +
+			"product_specs.image, product_specs.config_json, product_specs.created_at AS spec_created_at").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 未记录版本的旧商品：当前规格视为版本 1
+	if len(rows) == 0 {
+		product, err := r.GetByID(ctx, productID)
+		if err != nil {
+			return nil, err
+		}
+		return []*biz.ProductSpecVersion{{
+			ProductID:     productID,
+			Version:       1,
+			Spec:          product.Spec,
+			EffectiveFrom: product.CreatedAt,
+		}}, nil
+	}
+
+	versions := make([]*biz.ProductSpecVersion, 0, len(rows))
+	var effectiveTo *time.Time
+	for _, row := range rows {
+		versions = append(versions, &biz.ProductSpecVersion{
+			ProductID: productID,
+			Version:   row.Version,
+			Spec: &biz.ProductSpec{
+				ID:         row.SpecID,
+				CPU:        row.CPU,
+				Memory:     row.Memory,
+				GPU:        row.GPU,
+				Image:      row.Image,
+				ConfigJSON: row.ConfigJSON,
+				CreatedAt:  row.SpecCreatedAt,
+			},
+			EffectiveFrom: row.EffectiveFrom,
+			EffectiveTo:   effectiveTo,
+		})
+		// 按版本倒序，上一个（更新的）版本的生效时间即为本版本的失效时间
+		from := row.EffectiveFrom
+		effectiveTo = &from
+	}
+	return versions, nil
+}
+
+type productSpecVersionRow struct {
+	Version       int32     `gorm:"column:version"`
+	EffectiveFrom time.Time `gorm:"column:effective_from"`
+	SpecID        int64     `gorm:"column:spec_id"`
+	CPU           int32     `gorm:"column:cpu"`
+	Memory        int32     `gorm:"column:memory"`
+	GPU           int32     `gorm:"column:gpu"`
+	Image         string    `gorm:"column:image"`
+	ConfigJSON    []byte    `gorm:"column:config_json"`
+	SpecCreatedAt time.Time `gorm:"column:spec_created_at"`
+}
+
 type productListRow struct {
 	ID             int64     `gorm:"column:id"`
 	Name           string    `gorm:"column:name"`
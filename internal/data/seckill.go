@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"product/internal/biz"
 
@@ -12,16 +14,59 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// 活动参数 Hash（seckill:meta:{productID}）的字段，BFF 层按同样的字段读取限购数量
 const (
-	// Redis Keys - 与 BFF 层保持一致
-	keyStock         = "seckill:stock"      // 库存
-	keyReqSeq        = "req:seq"            // 请求序列号（注意：不是 seckill:req_seq）
-	keyUID2ReqHash   = "uid2req"            // 用户ID -> 请求号映射（注意：无前缀）
-	keyStreamOrders  = "stream:orders"      // 订单流（注意：不是 seckill:stream_orders）
-	keyCurrentProdID = "seckill:product_id" // 当前秒杀商品ID
+	seckillMetaCampaignID   = "campaign_id"
+	seckillMetaPrice        = "price"
+	seckillMetaPerUserLimit = "per_user_limit"
+	seckillMetaInitialStock = "initial_stock"
+	seckillMetaEndAt        = "end_at"    // Unix 秒，0 表示不自动结束
+	seckillMetaClosed       = "closed"    // 1 表示已结束售卖（补偿时不再归还库存）
+	seckillMetaRemaining    = "remaining" // 结束售卖时的剩余库存，重复结束时返回该值
 )
 
+// seckillPurchaseScript 秒杀抢购脚本，与 BFF 层的 goods.lua 逻辑一致
+// KEYS[1]=seckill:stock  KEYS[2]=req:seq  KEYS[3]=uid2req  KEYS[4]=stream:orders  KEYS[5]=seckill:meta  KEYS[6]=uidcnt
+// ARGV[1]=uid  ARGV[2]=now_ms
+// 返回值：
+//
+//	{-1}            活动不存在（库存 Key 不存在）
+//	{0}             库存不足
+//	{2, old_req}    已达到每人限购数量
+//	{1, req, sid}   抢购成功
+var seckillPurchaseScript = redis.NewScript(`
+local stock = tonumber(redis.call('GET', KEYS[1]))
+if not stock then
+  return {-1}
+end
+
+local limit = tonumber(redis.call('HGET', KEYS[5], 'per_user_limit')) or 1
+if limit < 1 then
+  limit = 1
+end
+-- BFF 层写入的数据没有 uidcnt，按 uid2req 是否存在计数
+local bought = tonumber(redis.call('HGET', KEYS[6], ARGV[1]))
+if not bought then
+  bought = redis.call('HEXISTS', KEYS[3], ARGV[1])
+end
+if bought >= limit then
+  return {2, tonumber(redis.call('HGET', KEYS[3], ARGV[1])) or 0}
+end
+
+if stock <= 0 then
+  return {0}
+end
+
+redis.call('DECRBY', KEYS[1], 1)
+local req = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[3], ARGV[1], req)
+redis.call('HSET', KEYS[6], ARGV[1], bought + 1)
+local sid = redis.call('XADD', KEYS[4], '*', 'uid', ARGV[1], 'req', req, 'ts', ARGV[2])
+return {1, req, sid}
+`)
+
 // seckillProductRepo 秒杀商品仓储实现
+// 每个商品的秒杀数据使用独立的 Key（见 biz.SeckillKeysFor），活跃商品记录在 seckill:campaigns 集合中
 type seckillProductRepo struct {
 	data *Data
 	log  *log.Helper
@@ -35,120 +80,546 @@ func NewSeckillProductRepo(data *Data, logger log.Logger) biz.SeckillProductRepo
 	}
 }
 
-// InitSeckill 初始化秒杀（清空上次数据并设置新的 productID 和库存）
-func (r *seckillProductRepo) InitSeckill(ctx context.Context, productID int64, stock int32) error {
+// seckillInitScript 按活动ID幂等地开放秒杀库存：活动参数中已是同一活动时不做修改（返回 0），
+// 否则清空该商品上次的数据（不删除订单流，消费者组依赖它）并写入库存和活动参数（返回 1）
+// KEYS[1]=seckill:stock  KEYS[2]=req:seq  KEYS[3]=uid2req  KEYS[4]=uidcnt  KEYS[5]=seckill:meta
+// ARGV[1]=campaign_id  ARGV[2]=stock  ARGV[3]=price  ARGV[4]=per_user_limit  ARGV[5]=end_at
+var seckillInitScript = redis.NewScript(`
+if redis.call('HGET', KEYS[5], 'campaign_id') == ARGV[1] then
+  return 0
+end
+redis.call('DEL', KEYS[1], KEYS[2], KEYS[3], KEYS[4], KEYS[5])
+redis.call('SET', KEYS[1], ARGV[2])
+redis.call('SET', KEYS[2], 0)
+redis.call('HSET', KEYS[5], 'campaign_id', ARGV[1], 'price', ARGV[3], 'per_user_limit', ARGV[4],
+  'initial_stock', ARGV[2], 'end_at', ARGV[5])
+return 1
+`)
+
+// InitSeckill 开放商品的秒杀库存（清空该商品上次数据并写入库存和活动参数）
+// 按活动ID幂等，重试时不会重置售卖中的库存和抢购记录；活动集合和通知在之后单独写入（可重复执行）
+func (r *seckillProductRepo) InitSeckill(ctx context.Context, campaign *biz.SeckillCampaign) error {
 	if r.data.redis == nil {
 		return fmt.Errorf("redis client is not initialized")
 	}
 
-	// 使用 Pipeline 批量执行命令
-	pipe := r.data.redis.Pipeline()
-
-	// 1. 删除旧数据
-	pipe.Del(ctx, keyStock)
-	pipe.Del(ctx, keyReqSeq)
-	pipe.Del(ctx, keyUID2ReqHash)
-	pipe.Del(ctx, keyCurrentProdID)
-
-	// 注意：不删除 Stream，因为消费者组依赖它
-	// 如果需要清空 Stream，应该先删除消费者组
+	productID := campaign.ProductID
+	keys := biz.SeckillKeysFor(productID)
 
-	// 2. 设置新数据
-	pipe.Set(ctx, keyCurrentProdID, productID, 0) // 不过期
-	pipe.Set(ctx, keyStock, stock, 0)
-	pipe.Set(ctx, keyReqSeq, 0, 0) // 初始化请求序列号为 0
+	var endAt int64
+	if campaign.EndAt != nil {
+		endAt = campaign.EndAt.Unix()
+	}
 
-	// 执行 Pipeline
-	_, err := pipe.Exec(ctx)
+	initialized, err := seckillInitScript.Run(ctx, r.data.redis,
+		[]string{keys.Stock, keys.ReqSeq, keys.UID2Req, keys.UIDCnt, keys.Meta},
+		campaign.ID, campaign.InitialStock, campaign.Price, campaign.PerUserLimit, endAt,
+	).Int()
 	if err != nil {
-		r.log.Errorf("failed to init seckill: %v", err)
+		r.log.Errorf("failed to init seckill: productID=%d err=%v", productID, err)
 		return err
 	}
 
-	// 3. 初始化 Stream（如果不存在）
-	// 使用 XADD 添加一个占位消息，然后立即删除
-	// 这样可以确保 Stream 存在，方便后续创建消费者组
-	streamExists, err := r.data.redis.Exists(ctx, keyStreamOrders).Result()
-	if err != nil {
-		r.log.Errorf("failed to check stream existence: %v", err)
+	// 登记活跃商品并通知各实例的消费者
+	pipe := r.data.redis.Pipeline()
+	pipe.SAdd(ctx, biz.SeckillCampaignsKey, productID)
+	pipe.Publish(ctx, biz.SeckillCampaignsChannel, productID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.log.Errorf("failed to register seckill campaign: productID=%d err=%v", productID, err)
 		return err
 	}
 
-	if streamExists == 0 {
-		// Stream 不存在，创建一个空的 Stream
-		// 使用 XGROUP CREATE MKSTREAM 会自动创建 Stream
-		r.log.Infof("stream does not exist, will be created by consumer group")
+	if initialized == 0 {
+		r.log.Infof("seckill already initialized for campaign, skip: productID=%d campaignID=%d", productID, campaign.ID)
+		return nil
 	}
-
-	r.log.Infof("seckill initialized: productID=%d, stock=%d", productID, stock)
+	r.log.Infof("seckill initialized: productID=%d, stock=%d", productID, campaign.InitialStock)
 	return nil
 }
 
-// GetCurrentProductID 获取当前秒杀商品ID
-func (r *seckillProductRepo) GetCurrentProductID(ctx context.Context) (int64, error) {
+// seckillCompensateScript 补偿被拒绝的秒杀订单（每条订单流消息最多执行一次）
+// KEYS[1]=seckill:stock  KEYS[2]=uid2req  KEYS[3]=uidcnt  KEYS[4]=seckill:meta  KEYS[5]=seckill:compensated
+// ARGV[1]=stream_id  ARGV[2]=uid
+// 返回值：{applied, stock_returned, slot_released}，applied=0 表示该消息已补偿过，其余两项为上次的结果
+var seckillCompensateScript = redis.NewScript(`
+local done = redis.call('HGET', KEYS[5], ARGV[1])
+if done then
+  return {0, tonumber(string.sub(done, 1, 1)), tonumber(string.sub(done, 3, 3))}
+end
+
+-- 活动已结束售卖（库存已置 0 并结算）或已清空时不归还库存
+local returned = 0
+if redis.call('EXISTS', KEYS[1]) == 1 and redis.call('HGET', KEYS[4], 'closed') ~= '1' then
+  redis.call('INCR', KEYS[1])
+  returned = 1
+end
+
+-- 限购数量大于 1 时只减少已抢购数量，否则删除用户的抢购记录
+local released = 0
+local cnt = tonumber(redis.call('HGET', KEYS[3], ARGV[2]))
+if cnt and cnt > 1 then
+  redis.call('HINCRBY', KEYS[3], ARGV[2], -1)
+  released = 1
+else
+  if redis.call('HDEL', KEYS[2], ARGV[2]) + redis.call('HDEL', KEYS[3], ARGV[2]) > 0 then
+    released = 1
+  end
+end
+
+redis.call('HSET', KEYS[5], ARGV[1], returned .. ':' .. released)
+return {1, returned, released}
+`)
+
+// ListProductIDs 列出所有活跃秒杀活动的商品ID
+func (r *seckillProductRepo) ListProductIDs(ctx context.Context) ([]int64, error) {
 	if r.data.redis == nil {
-		return 0, fmt.Errorf("redis client is not initialized")
+		return nil, fmt.Errorf("redis client is not initialized")
 	}
 
-	val, err := r.data.redis.Get(ctx, keyCurrentProdID).Result()
+	members, err := r.data.redis.SMembers(ctx, biz.SeckillCampaignsKey).Result()
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return 0, biz.ErrNoActiveSeckill
+		return nil, err
+	}
+
+	productIDs := make([]int64, 0, len(members))
+	for _, m := range members {
+		productID, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			r.log.Warnf("invalid seckill campaign member: %q", m)
+			continue
 		}
-		return 0, err
+		productIDs = append(productIDs, productID)
+	}
+	return productIDs, nil
+}
+
+// GetSeckill 获取商品当前库存和活动参数
+// 直接通过 Redis 命令初始化、没有活动参数的秒杀按原价、每人限购 1 件处理
+func (r *seckillProductRepo) GetSeckill(ctx context.Context, productID int64) (*biz.SeckillCampaign, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
 	}
 
-	productID, err := strconv.ParseInt(val, 10, 64)
+	keys := biz.SeckillKeysFor(productID)
+	pipe := r.data.redis.Pipeline()
+	stockCmd := pipe.Get(ctx, keys.Stock)
+	metaCmd := pipe.HGetAll(ctx, keys.Meta)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	val, err := stockCmd.Result()
 	if err != nil {
-		return 0, fmt.Errorf("invalid product_id format: %v", err)
+		if errors.Is(err, redis.Nil) {
+			return nil, biz.ErrNoActiveSeckill
+		}
+		return nil, err
 	}
+	stock, err := strconv.ParseInt(val, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stock format: %v", err)
+	}
+
+	meta := metaCmd.Val()
+	campaign := &biz.SeckillCampaign{
+		ID:           parseMetaInt(meta, seckillMetaCampaignID),
+		ProductID:    productID,
+		Stock:        int32(stock),
+		InitialStock: int32(parseMetaInt(meta, seckillMetaInitialStock)),
+		Price:        parseMetaInt(meta, seckillMetaPrice),
+		PerUserLimit: int32(parseMetaInt(meta, seckillMetaPerUserLimit)),
+		Status:       biz.SeckillStatusActive,
+	}
+	if campaign.PerUserLimit == 0 {
+		campaign.PerUserLimit = 1
+	}
+	if endAt := parseMetaInt(meta, seckillMetaEndAt); endAt > 0 {
+		t := time.Unix(endAt, 0)
+		campaign.EndAt = &t
+	}
+	return campaign, nil
+}
 
-	return productID, nil
+// parseMetaInt 读取活动参数中的整数字段，缺失或格式错误时返回 0
+func parseMetaInt(meta map[string]string, field string) int64 {
+	v, err := strconv.ParseInt(meta[field], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
 }
 
-// GetStock 获取当前库存
-func (r *seckillProductRepo) GetStock(ctx context.Context) (int32, error) {
+// seckillCloseScript 停止售卖：库存置 0 并把关闭前的库存记入活动参数；已关闭时直接返回记录的库存
+// 库存为负（异常数据）时按 0 结算
+// KEYS[1]=stock  KEYS[2]=meta
+var seckillCloseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'closed') == '1' then
+  return tonumber(redis.call('HGET', KEYS[2], 'remaining') or '0')
+end
+local remaining = tonumber(redis.call('GET', KEYS[1]) or '0')
+if remaining < 0 then
+  remaining = 0
+end
+redis.call('SET', KEYS[1], 0)
+redis.call('HSET', KEYS[2], 'closed', 1, 'remaining', remaining)
+return remaining
+`)
+
+// CloseSeckill 停止售卖：原子地把库存置 0、标记活动已结束售卖，并返回关闭前的库存
+// 可重复执行：已关闭时返回第一次关闭时记录的库存，结束活动的事务提交失败后重试仍能算出正确的已售数量。
+// 商品仍保留在 seckill:campaigns 中，消费者继续处理订单流中已抢到的订单
+func (r *seckillProductRepo) CloseSeckill(ctx context.Context, productID int64) (int32, error) {
 	if r.data.redis == nil {
 		return 0, fmt.Errorf("redis client is not initialized")
 	}
 
-	val, err := r.data.redis.Get(ctx, keyStock).Result()
+	keys := biz.SeckillKeysFor(productID)
+	remaining, err := seckillCloseScript.Run(ctx, r.data.redis, []string{keys.Stock, keys.Meta}).Int64()
 	if err != nil {
-		if err == redis.Nil {
-			return 0, nil
-		}
+		r.log.Errorf("failed to close seckill: productID=%d err=%v", productID, err)
 		return 0, err
 	}
 
-	stock, err := strconv.ParseInt(val, 10, 32)
+	r.log.Infof("seckill closed: productID=%d remaining=%d", productID, remaining)
+	return int32(remaining), nil
+}
+
+// StreamDrained 订单流是否已全部投递并确认
+// 消费者组不存在时以 Stream 是否为空判断
+func (r *seckillProductRepo) StreamDrained(ctx context.Context, productID int64) (bool, error) {
+	if r.data.redis == nil {
+		return false, fmt.Errorf("redis client is not initialized")
+	}
+
+	stream := biz.SeckillKeysFor(productID).Stream
+	groups, err := r.data.redis.XInfoGroups(ctx, stream).Result()
 	if err != nil {
-		return 0, fmt.Errorf("invalid stock format: %v", err)
+		if strings.Contains(err.Error(), "no such key") {
+			return true, nil
+		}
+		return false, err
 	}
 
-	return int32(stock), nil
+	for _, g := range groups {
+		if g.Name != biz.SeckillConsumerGroup {
+			continue
+		}
+		if g.Pending > 0 {
+			return false, nil
+		}
+		if g.Lag >= 0 {
+			return g.Lag == 0, nil
+		}
+		// lag 无法确定时，比较最后投递的消息与最后写入的消息
+		info, err := r.data.redis.XInfoStream(ctx, stream).Result()
+		if err != nil {
+			return false, err
+		}
+		return info.Length == 0 || info.LastGeneratedID == g.LastDeliveredID, nil
+	}
+
+	n, err := r.data.redis.XLen(ctx, stream).Result()
+	if err != nil {
+		return false, err
+	}
+	return n == 0, nil
 }
 
-// ClearSeckill 清空秒杀数据
-func (r *seckillProductRepo) ClearSeckill(ctx context.Context) error {
+// ClearSeckill 清空商品的秒杀数据
+func (r *seckillProductRepo) ClearSeckill(ctx context.Context, productID int64) error {
 	if r.data.redis == nil {
 		return fmt.Errorf("redis client is not initialized")
 	}
 
-	// 删除所有秒杀相关的 Key
-	keys := []string{
-		keyStock,
-		keyReqSeq,
-		keyUID2ReqHash,
-		keyStreamOrders,
-		keyCurrentProdID,
+	keys := biz.SeckillKeysFor(productID)
+
+	pipe := r.data.redis.TxPipeline()
+	pipe.Del(ctx, keys.Stock, keys.ReqSeq, keys.UID2Req, keys.UIDCnt, keys.Meta, keys.Stream, keys.Compensated)
+	pipe.SRem(ctx, biz.SeckillCampaignsKey, productID)
+	pipe.Publish(ctx, biz.SeckillCampaignsChannel, productID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.log.Errorf("failed to clear seckill: productID=%d err=%v", productID, err)
+		return err
+	}
+
+	r.log.Infof("seckill data cleared: productID=%d", productID)
+	return nil
+}
+
+// Purchase 执行秒杀抢购脚本（EVALSHA，脚本未缓存时自动回退为 EVAL）
+func (r *seckillProductRepo) Purchase(ctx context.Context, productID int64, userID string) (*biz.SeckillPurchaseResult, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
 	}
 
-	err := r.data.redis.Del(ctx, keys...).Err()
+	keys := biz.SeckillKeysFor(productID)
+	vals, err := seckillPurchaseScript.Run(ctx, r.data.redis,
+		[]string{keys.Stock, keys.ReqSeq, keys.UID2Req, keys.Stream, keys.Meta, keys.UIDCnt},
+		userID, time.Now().UnixMilli(),
+	).Slice()
 	if err != nil {
-		r.log.Errorf("failed to clear seckill: %v", err)
+		r.log.Errorf("failed to run seckill purchase script: productID=%d userID=%s err=%v", productID, userID, err)
+		return nil, err
+	}
+
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("empty seckill purchase result")
+	}
+	code, _ := vals[0].(int64)
+	switch code {
+	case -1:
+		return nil, biz.ErrNoActiveSeckill
+	case 0:
+		return &biz.SeckillPurchaseResult{Result: biz.SeckillPurchaseSoldOut}, nil
+	case 2:
+		res := &biz.SeckillPurchaseResult{Result: biz.SeckillPurchaseAlreadyBought}
+		if len(vals) > 1 {
+			res.ReqID, _ = vals[1].(int64)
+		}
+		return res, nil
+	case 1:
+		if len(vals) < 3 {
+			break
+		}
+		reqID, _ := vals[1].(int64)
+		streamID, _ := vals[2].(string)
+		return &biz.SeckillPurchaseResult{
+			Result:   biz.SeckillPurchaseAccepted,
+			ReqID:    reqID,
+			StreamID: streamID,
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected seckill purchase result: %v", vals)
+}
+
+// seckillScanBatch 对账时每批读取的 Stream 消息 / Hash 字段数
+const seckillScanBatch = 1000
+
+// ScanStream 按顺序分批读取订单流中的全部消息
+func (r *seckillProductRepo) ScanStream(ctx context.Context, productID int64) ([]*biz.SeckillStreamEntry, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
+	}
+
+	stream := biz.SeckillKeysFor(productID).Stream
+	var entries []*biz.SeckillStreamEntry
+	start := "-"
+	for {
+		msgs, err := r.data.redis.XRangeN(ctx, stream, start, "+", seckillScanBatch).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			entry := &biz.SeckillStreamEntry{StreamID: msg.ID}
+			if uid, ok := msg.Values["uid"].(string); ok {
+				entry.UserID = uid
+			}
+			if req, ok := msg.Values["req"].(string); ok {
+				entry.Req, _ = strconv.ParseInt(req, 10, 64)
+			}
+			entries = append(entries, entry)
+		}
+		if len(msgs) < seckillScanBatch {
+			return entries, nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// ScanUID2Req 通过 HSCAN 读取全部用户ID -> 请求号映射
+func (r *seckillProductRepo) ScanUID2Req(ctx context.Context, productID int64) (map[string]int64, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
+	}
+
+	key := biz.SeckillKeysFor(productID).UID2Req
+	uid2req := make(map[string]int64)
+	var cursor uint64
+	for {
+		kvs, next, err := r.data.redis.HScan(ctx, key, cursor, "", seckillScanBatch).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(kvs); i += 2 {
+			req, _ := strconv.ParseInt(kvs[i+1], 10, 64)
+			uid2req[kvs[i]] = req
+		}
+		if next == 0 {
+			return uid2req, nil
+		}
+		cursor = next
+	}
+}
+
+// 死信消息（stream:orders:dlq:{productID}）的字段
+const (
+	seckillDLQStreamID   = "stream_id"
+	seckillDLQUID        = "uid"
+	seckillDLQReq        = "req"
+	seckillDLQPrice      = "price"
+	seckillDLQReason     = "reason"
+	seckillDLQDeliveries = "deliveries"
+	seckillDLQFailedAt   = "failed_at" // Unix 毫秒
+)
+
+// AddDeadLetter 写入死信并 ACK 原消息（MULTI/EXEC，死信与 ACK 同时生效）
+func (r *seckillProductRepo) AddDeadLetter(ctx context.Context, productID int64, letter *biz.SeckillDeadLetter) error {
+	if r.data.redis == nil {
+		return fmt.Errorf("redis client is not initialized")
+	}
+
+	keys := biz.SeckillKeysFor(productID)
+	pipe := r.data.redis.TxPipeline()
+	addCmd := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: keys.DLQ,
+		Values: []interface{}{
+			seckillDLQStreamID, letter.StreamID,
+			seckillDLQUID, letter.UserID,
+			seckillDLQReq, letter.Req,
+			seckillDLQPrice, letter.Price,
+			seckillDLQReason, letter.Reason,
+			seckillDLQDeliveries, letter.Deliveries,
+			seckillDLQFailedAt, letter.FailedAt.UnixMilli(),
+		},
+	})
+	pipe.XAck(ctx, keys.Stream, biz.SeckillConsumerGroup, letter.StreamID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.log.Errorf("failed to add dead letter: productID=%d streamID=%s err=%v", productID, letter.StreamID, err)
 		return err
 	}
+	letter.ID = addCmd.Val()
+	return nil
+}
+
+// ListDeadLetters 按顺序列出死信
+func (r *seckillProductRepo) ListDeadLetters(ctx context.Context, productID int64, after string, limit int64) ([]*biz.SeckillDeadLetter, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
+	}
+
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	msgs, err := r.data.redis.XRangeN(ctx, biz.SeckillKeysFor(productID).DLQ, start, "+", limit).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*biz.SeckillDeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		letters = append(letters, toSeckillDeadLetter(msg))
+	}
+	return letters, nil
+}
+
+// GetDeadLetter 获取死信
+func (r *seckillProductRepo) GetDeadLetter(ctx context.Context, productID int64, id string) (*biz.SeckillDeadLetter, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
+	}
 
-	r.log.Info("seckill data cleared")
+	msgs, err := r.data.redis.XRangeN(ctx, biz.SeckillKeysFor(productID).DLQ, id, id, 1).Result()
+	if err != nil {
+		// 非法的消息ID视为不存在
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			return nil, biz.ErrSeckillDeadLetterNotFound
+		}
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, biz.ErrSeckillDeadLetterNotFound
+	}
+	return toSeckillDeadLetter(msgs[0]), nil
+}
+
+// DeleteDeadLetter 删除死信
+func (r *seckillProductRepo) DeleteDeadLetter(ctx context.Context, productID int64, id string) error {
+	if r.data.redis == nil {
+		return fmt.Errorf("redis client is not initialized")
+	}
+
+	n, err := r.data.redis.XDel(ctx, biz.SeckillKeysFor(productID).DLQ, id).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			return biz.ErrSeckillDeadLetterNotFound
+		}
+		r.log.Errorf("failed to delete dead letter: productID=%d id=%s err=%v", productID, id, err)
+		return err
+	}
+	if n == 0 {
+		return biz.ErrSeckillDeadLetterNotFound
+	}
 	return nil
 }
+
+func toSeckillDeadLetter(msg redis.XMessage) *biz.SeckillDeadLetter {
+	field := func(name string) string {
+		v, _ := msg.Values[name].(string)
+		return v
+	}
+	intField := func(name string) int64 {
+		v, _ := strconv.ParseInt(field(name), 10, 64)
+		return v
+	}
+	return &biz.SeckillDeadLetter{
+		ID:         msg.ID,
+		StreamID:   field(seckillDLQStreamID),
+		UserID:     field(seckillDLQUID),
+		Req:        intField(seckillDLQReq),
+		Price:      intField(seckillDLQPrice),
+		Reason:     field(seckillDLQReason),
+		Deliveries: intField(seckillDLQDeliveries),
+		FailedAt:   time.UnixMilli(intField(seckillDLQFailedAt)),
+	}
+}
+
+// Compensate 执行补偿脚本：归还库存、释放用户名额并记录补偿结果
+func (r *seckillProductRepo) Compensate(ctx context.Context, compensation *biz.SeckillCompensation) (bool, error) {
+	if r.data.redis == nil {
+		return false, fmt.Errorf("redis client is not initialized")
+	}
+
+	keys := biz.SeckillKeysFor(compensation.ProductID)
+	vals, err := seckillCompensateScript.Run(ctx, r.data.redis,
+		[]string{keys.Stock, keys.UID2Req, keys.UIDCnt, keys.Meta, keys.Compensated},
+		compensation.StreamID, compensation.UserID,
+	).Int64Slice()
+	if err != nil {
+		r.log.Errorf("failed to run seckill compensate script: productID=%d streamID=%s err=%v",
+			compensation.ProductID, compensation.StreamID, err)
+		return false, err
+	}
+	if len(vals) != 3 {
+		return false, fmt.Errorf("unexpected seckill compensate result: %v", vals)
+	}
+
+	compensation.StockReturned = vals[1] == 1
+	compensation.SlotReleased = vals[2] == 1
+	return vals[0] == 1, nil
+}
+
+// ScanCompensated 读取已补偿的订单流消息ID -> 是否归还了库存
+func (r *seckillProductRepo) ScanCompensated(ctx context.Context, productID int64) (map[string]bool, error) {
+	if r.data.redis == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
+	}
+
+	key := biz.SeckillKeysFor(productID).Compensated
+	compensated := make(map[string]bool)
+	var cursor uint64
+	for {
+		kvs, next, err := r.data.redis.HScan(ctx, key, cursor, "", seckillScanBatch).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(kvs); i += 2 {
+			// 值为 "stock_returned:slot_released"
+			compensated[kvs[i]] = strings.HasPrefix(kvs[i+1], "1")
+		}
+		if next == 0 {
+			return compensated, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+// seckillCampaignUnfinished 未结束的活动状态（同一商品最多一个）
+var seckillCampaignUnfinished = []string{
+	biz.SeckillStatusScheduled,
+	biz.SeckillStatusActive,
+	biz.SeckillStatusEnded,
+}
+
+// seckillCampaignPO 秒杀活动持久化对象
+type seckillCampaignPO struct {
+	ID           int64        `gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID    int64        `gorm:"column:product_id;not null"`
+	InitialStock int32        `gorm:"column:initial_stock;not null"`
+	Sold         int32        `gorm:"column:sold;not null;default:0"`
+	Price        int64        `gorm:"column:price;not null;default:0"`
+	PerUserLimit int32        `gorm:"column:per_user_limit;not null;default:1"`
+	StartAt      time.Time    `gorm:"column:start_at;not null"`
+	EndAt        sql.NullTime `gorm:"column:end_at"`
+	Status       string       `gorm:"column:status;type:varchar(20);not null"`
+	CreatedAt    time.Time    `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time    `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (seckillCampaignPO) TableName() string {
+	return "seckill_campaigns"
+}
+
+func toSeckillCampaignPO(campaign *biz.SeckillCampaign) *seckillCampaignPO {
+	po := &seckillCampaignPO{
+		ID:           campaign.ID,
+		ProductID:    campaign.ProductID,
+		InitialStock: campaign.InitialStock,
+		Sold:         campaign.Sold,
+		Price:        campaign.Price,
+		PerUserLimit: campaign.PerUserLimit,
+		StartAt:      campaign.StartAt,
+		Status:       campaign.Status,
+		CreatedAt:    campaign.CreatedAt,
+		UpdatedAt:    campaign.UpdatedAt,
+	}
+	if campaign.EndAt != nil {
+		po.EndAt = sql.NullTime{Time: *campaign.EndAt, Valid: true}
+	}
+	return po
+}
+
+func toSeckillCampaign(po *seckillCampaignPO) *biz.SeckillCampaign {
+	campaign := &biz.SeckillCampaign{
+		ID:           po.ID,
+		ProductID:    po.ProductID,
+		InitialStock: po.InitialStock,
+		Sold:         po.Sold,
+		Price:        po.Price,
+		PerUserLimit: po.PerUserLimit,
+		StartAt:      po.StartAt,
+		Status:       po.Status,
+		CreatedAt:    po.CreatedAt,
+		UpdatedAt:    po.UpdatedAt,
+	}
+	if po.EndAt.Valid {
+		campaign.EndAt = &po.EndAt.Time
+	}
+	return campaign
+}
+
+type seckillCampaignRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewSeckillCampaignRepo 创建秒杀活动仓储
+func NewSeckillCampaignRepo(data *Data, logger log.Logger) biz.SeckillCampaignRepo {
+	return &seckillCampaignRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// Create 创建活动（跟随 ctx 中的事务）
+// 同一商品已有未结束的活动时，部分唯一索引 uk_seckill_campaigns_unfinished 拒绝写入
+func (r *seckillCampaignRepo) Create(ctx context.Context, campaign *biz.SeckillCampaign) error {
+	po := toSeckillCampaignPO(campaign)
+	if err := r.data.DB(ctx).Create(po).Error; err != nil {
+		if isUniqueViolation(err) {
+			return biz.ErrSeckillCampaignExists
+		}
+		r.log.Errorf("create seckill campaign failed: productID=%d err=%v", campaign.ProductID, err)
+		return err
+	}
+	campaign.ID = po.ID
+	return nil
+}
+
+// GetCurrent 获取商品未结束的活动
+func (r *seckillCampaignRepo) GetCurrent(ctx context.Context, productID int64) (*biz.SeckillCampaign, error) {
+	var po seckillCampaignPO
+	err := r.data.DB(ctx).
+		Where("product_id = ? AND status IN ?", productID, seckillCampaignUnfinished).
+		Order("id DESC").
+		First(&po).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrNoActiveSeckill
+		}
+		r.log.Errorf("get seckill campaign failed: productID=%d err=%v", productID, err)
+		return nil, err
+	}
+	return toSeckillCampaign(&po), nil
+}
+
+// List 列出所有未结束的活动
+func (r *seckillCampaignRepo) List(ctx context.Context) ([]*biz.SeckillCampaign, error) {
+	return r.find(ctx, r.data.DB(ctx).Where("status IN ?", seckillCampaignUnfinished).Order("start_at"))
+}
+
+// ListToStart 列出开始时间已到、仍未开启的活动
+func (r *seckillCampaignRepo) ListToStart(ctx context.Context, now time.Time) ([]*biz.SeckillCampaign, error) {
+	return r.find(ctx, r.data.DB(ctx).Where("status = ? AND start_at <= ?", biz.SeckillStatusScheduled, now).Order("start_at"))
+}
+
+// ListToEnd 列出结束时间已到、仍在进行的活动
+func (r *seckillCampaignRepo) ListToEnd(ctx context.Context, now time.Time) ([]*biz.SeckillCampaign, error) {
+	return r.find(ctx, r.data.DB(ctx).Where("status = ? AND end_at <= ?", biz.SeckillStatusActive, now).Order("end_at"))
+}
+
+// ListEnded 列出已结束、等待结算的活动
+func (r *seckillCampaignRepo) ListEnded(ctx context.Context) ([]*biz.SeckillCampaign, error) {
+	return r.find(ctx, r.data.DB(ctx).Where("status = ?", biz.SeckillStatusEnded).Order("end_at"))
+}
+
+func (r *seckillCampaignRepo) find(ctx context.Context, query *gorm.DB) ([]*biz.SeckillCampaign, error) {
+	var pos []seckillCampaignPO
+	if err := query.Find(&pos).Error; err != nil {
+		r.log.Errorf("list seckill campaigns failed: err=%v", err)
+		return nil, err
+	}
+
+	campaigns := make([]*biz.SeckillCampaign, 0, len(pos))
+	for i := range pos {
+		campaigns = append(campaigns, toSeckillCampaign(&pos[i]))
+	}
+	return campaigns, nil
+}
+
+// UpdateStatus 以当前状态作为乐观锁条件更新活动状态和已售数量
+func (r *seckillCampaignRepo) UpdateStatus(ctx context.Context, campaign *biz.SeckillCampaign, from string) error {
+	res := r.data.DB(ctx).Model(&seckillCampaignPO{}).
+		Where("id = ? AND status = ?", campaign.ID, from).
+		Updates(map[string]interface{}{
+			"status":     campaign.Status,
+			"sold":       campaign.Sold,
+			"updated_at": campaign.UpdatedAt,
+		})
+	if res.Error != nil {
+		r.log.Errorf("update seckill campaign status failed: id=%d %s -> %s err=%v", campaign.ID, from, campaign.Status, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrSeckillCampaignConflict
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm/clause"
+)
+
+// seckillCompensationPO 秒杀补偿审计记录持久化对象
+type seckillCompensationPO struct {
+	ID            int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID     int64     `gorm:"column:product_id;not null"`
+	StreamID      string    `gorm:"column:stream_id;type:varchar(64);not null"`
+	UserID        string    `gorm:"column:user_id;type:varchar(64);not null"` // 非法 UID 也要记录，不使用 uuid 类型
+	ReqID         int64     `gorm:"column:req_id;not null"`
+	Reason        string    `gorm:"column:reason;type:text;not null"`
+	StockReturned bool      `gorm:"column:stock_returned;not null"`
+	SlotReleased  bool      `gorm:"column:slot_released;not null"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (seckillCompensationPO) TableName() string {
+	return "seckill_compensations"
+}
+
+type seckillCompensationRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewSeckillCompensationRepo 创建秒杀补偿审计记录仓储
+func NewSeckillCompensationRepo(data *Data, logger log.Logger) biz.SeckillCompensationRepo {
+	return &seckillCompensationRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// Create 写入补偿记录，唯一索引 (product_id, stream_id) 冲突说明该消息已记录过
+func (r *seckillCompensationRepo) Create(ctx context.Context, compensation *biz.SeckillCompensation) error {
+	po := &seckillCompensationPO{
+		ProductID:     compensation.ProductID,
+		StreamID:      compensation.StreamID,
+		UserID:        compensation.UserID,
+		ReqID:         compensation.ReqID,
+		Reason:        compensation.Reason,
+		StockReturned: compensation.StockReturned,
+		SlotReleased:  compensation.SlotReleased,
+		CreatedAt:     compensation.CreatedAt,
+	}
+	res := r.data.DB(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(po)
+	if res.Error != nil {
+		r.log.Errorf("create seckill compensation failed: productID=%d streamID=%s err=%v",
+			compensation.ProductID, compensation.StreamID, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		compensation.ID = po.ID
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"product/internal/biz"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis 启动内存 Redis，返回只带 Redis 客户端的 Data
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *Data) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return mr, &Data{redis: rdb}
+}
+
+func TestSeckillProductRepo_CloseSeckill(t *testing.T) {
+	ctx := context.Background()
+	mr, d := newTestRedis(t)
+	repo := NewSeckillProductRepo(d, log.DefaultLogger)
+	keys := biz.SeckillKeysFor(1)
+
+	campaign := &biz.SeckillCampaign{ID: 7, ProductID: 1, InitialStock: 10, Price: 100, PerUserLimit: 1}
+	if err := repo.InitSeckill(ctx, campaign); err != nil {
+		t.Fatalf("InitSeckill() error = %v", err)
+	}
+	mr.Set(keys.Stock, "4")
+
+	if remaining, err := repo.CloseSeckill(ctx, 1); err != nil || remaining != 4 {
+		t.Fatalf("CloseSeckill() = %d, %v, want 4", remaining, err)
+	}
+	if stock, _ := mr.Get(keys.Stock); stock != "0" {
+		t.Errorf("stock = %q, want 0", stock)
+	}
+	// 结束活动的事务提交失败后重试：返回第一次关闭时的库存
+	if remaining, err := repo.CloseSeckill(ctx, 1); err != nil || remaining != 4 {
+		t.Errorf("CloseSeckill() retry = %d, %v, want 4", remaining, err)
+	}
+
+	// 新活动重新初始化后清除关闭记录
+	next := &biz.SeckillCampaign{ID: 8, ProductID: 1, InitialStock: 5, Price: 100, PerUserLimit: 1}
+	if err := repo.InitSeckill(ctx, next); err != nil {
+		t.Fatalf("InitSeckill(next) error = %v", err)
+	}
+	if remaining, err := repo.CloseSeckill(ctx, 1); err != nil || remaining != 5 {
+		t.Errorf("CloseSeckill(next) = %d, %v, want 5", remaining, err)
+	}
+
+	// 库存为负（异常数据）时按 0 结算
+	mr.Set(biz.SeckillKeysFor(2).Stock, "-3")
+	if remaining, err := repo.CloseSeckill(ctx, 2); err != nil || remaining != 0 {
+		t.Errorf("CloseSeckill(negative) = %d, %v, want 0", remaining, err)
+	}
+}
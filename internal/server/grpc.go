@@ -11,7 +11,7 @@ import (
 )
 
 // NewGRPCServer new a gRPC server.
-func NewGRPCServer(c *conf.Server, logger log.Logger, productSvc *service.ProductService, seckillSvc *service.SeckillService, orderSvc *service.OrderService) *grpc.Server {
+func NewGRPCServer(c *conf.Server, logger log.Logger, productSvc *service.ProductService, seckillSvc *service.SeckillService, orderSvc *service.OrderService, instanceSvc *service.InstanceService) *grpc.Server {
 	var opts = []grpc.ServerOption{
 		grpc.Middleware(
 			recovery.Recovery(),
@@ -30,5 +30,6 @@ func NewGRPCServer(c *conf.Server, logger log.Logger, productSvc *service.Produc
 	v1.RegisterProductServiceServer(srv, productSvc)
 	v1.RegisterSeckillServiceServer(srv, seckillSvc)
 	v1.RegisterOrderServiceServer(srv, orderSvc)
+	v1.RegisterInstanceServiceServer(srv, instanceSvc)
 	return srv
 }
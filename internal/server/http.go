@@ -1,6 +1,8 @@
 package server
 
 import (
+	"expvar"
+
 	"product/api/product/v1"
 	"product/internal/conf"
 	"product/internal/service"
@@ -11,7 +13,7 @@ import (
 )
 
 // NewHTTPServer new an HTTP server.
-func NewHTTPServer(c *conf.Server, logger log.Logger, productSvc *service.ProductService, orderSvc *service.OrderService) *http.Server {
+func NewHTTPServer(c *conf.Server, logger log.Logger, productSvc *service.ProductService, orderSvc *service.OrderService, instanceSvc *service.InstanceService) *http.Server {
 	var opts = []http.ServerOption{
 		http.Middleware(
 			recovery.Recovery(),
@@ -27,7 +29,9 @@ func NewHTTPServer(c *conf.Server, logger log.Logger, productSvc *service.Produc
 		opts = append(opts, http.Timeout(c.Http.Timeout.AsDuration()))
 	}
 	srv := http.NewServer(opts...)
+	srv.Handle("/debug/vars", expvar.Handler())
 	v1.RegisterProductServiceHTTPServer(srv, productSvc)
 	v1.RegisterOrderServiceHTTPServer(srv, orderSvc)
+	v1.RegisterInstanceServiceHTTPServer(srv, instanceSvc)
 	return srv
 }
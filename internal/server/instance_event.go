@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"product/api/mq"
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// instanceEventRoutingKeys 订阅的资源域事件
+var instanceEventRoutingKeys = []string{
+	"instance.status.changed",
+	"instance.k8s.sync",
+	"instance.started",
+	"instance.stopped",
+	"instance.deleted",
+}
+
+const (
+	instanceEventPrefetch  = 32
+	instanceEventReconnect = 5 * time.Second
+
+	// 可重试的失败转入延迟队列 {queue}.retry.{退避毫秒数}，TTL 到期后由死信路由回主队列；
+	// 超过最大次数或永久失败的消息转入死信队列 {queue}.dlq
+	instanceEventRetryBase      = time.Second
+	instanceEventRetryMax       = 30 * time.Second
+	instanceEventMaxRetries     = 10
+	instanceEventConfirmTimeout = 5 * time.Second
+
+	// instanceEventRetryHeader 消息已重试次数
+	instanceEventRetryHeader = "x-retry-count"
+	// instanceEventErrorHeader 转入死信队列的原因
+	instanceEventErrorHeader = "x-last-error"
+)
+
+// InstanceEventServer 资源域实例事件消费服务器
+// 订阅 resource.events 上的实例状态事件，驱动实例状态记录和订单状态
+type InstanceEventServer struct {
+	url      string
+	exchange string
+	queue    string
+	uc       *biz.InstanceEventUsecase
+	log      *log.Helper
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ transport.Server = (*InstanceEventServer)(nil)
+
+// NewInstanceEventServer 创建实例事件消费服务器
+func NewInstanceEventServer(c *conf.Data, uc *biz.InstanceEventUsecase, logger log.Logger) *InstanceEventServer {
+	rc := c.GetRabbitmq()
+	s := &InstanceEventServer{
+		url:      rc.GetUrl(),
+		exchange: rc.GetExchange(),
+		queue:    rc.GetEventQueue(),
+		uc:       uc,
+		log:      log.NewHelper(log.With(logger, "module", "server/instance_event")),
+	}
+	if s.queue == "" {
+		s.queue = "product.instance.events"
+	}
+	return s
+}
+
+func (s *InstanceEventServer) Start(ctx context.Context) error {
+	if s.url == "" {
+		s.log.Warn("rabbitmq config not found, instance event consumer disabled")
+		return nil
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(runCtx)
+	}()
+
+	s.log.Infof("instance event server started: exchange=%s queue=%s", s.exchange, s.queue)
+	return nil
+}
+
+func (s *InstanceEventServer) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		s.log.Info("instance event server stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 消费循环，连接断开后自动重连
+func (s *InstanceEventServer) run(ctx context.Context) {
+	for {
+		if err := s.consume(ctx); err != nil {
+			s.log.Errorf("consume instance events failed: %v, reconnect in %s", err, instanceEventReconnect)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(instanceEventReconnect):
+		}
+	}
+}
+
+// consume 建立连接并消费，直到 ctx 取消或连接断开
+func (s *InstanceEventServer) consume(ctx context.Context) error {
+	conn, err := amqp.Dial(s.url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.ExchangeDeclare(s.exchange, "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare(s.queue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	for _, key := range instanceEventRoutingKeys {
+		if err := ch.QueueBind(s.queue, key, s.exchange, false, nil); err != nil {
+			return err
+		}
+	}
+	if err := s.declareRetryQueues(ch); err != nil {
+		return err
+	}
+	if err := ch.Qos(instanceEventPrefetch, 0, false); err != nil {
+		return err
+	}
+	// 转入延迟队列或死信队列的消息确认写入后才 ACK 原消息
+	if err := ch.Confirm(false); err != nil {
+		return err
+	}
+
+	// 手动 ACK：处理成功后才确认，失败的消息转入延迟队列或死信队列后确认
+	deliveries, err := ch.Consume(s.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			s.handle(ctx, ch, d)
+		}
+	}
+}
+
+// declareRetryQueues 声明死信队列和每个退避时间对应的延迟队列
+// 延迟队列没有消费者，消息 TTL 到期后经默认交换机路由回主队列
+func (s *InstanceEventServer) declareRetryQueues(ch *amqp.Channel) error {
+	if _, err := ch.QueueDeclare(s.deadLetterQueue(), true, false, false, false, nil); err != nil {
+		return err
+	}
+	for attempt := int32(1); attempt <= instanceEventMaxRetries; attempt++ {
+		delay := biz.OutboxBackoff(attempt, instanceEventRetryBase, instanceEventRetryMax)
+		_, err := ch.QueueDeclare(s.retryQueue(delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": s.queue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *InstanceEventServer) retryQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%d", s.queue, delay.Milliseconds())
+}
+
+func (s *InstanceEventServer) deadLetterQueue() string {
+	return s.queue + ".dlq"
+}
+
+// handle 处理单条消息
+func (s *InstanceEventServer) handle(ctx context.Context, ch *amqp.Channel, d amqp.Delivery) {
+	// 启动/停止/删除事件同时是商品域下发给资源域的命令，跳过自己发布的消息
+	if d.AppId == biz.EventSourceProduct {
+		_ = d.Ack(false)
+		return
+	}
+
+	var event mq.Event
+	if err := proto.Unmarshal(d.Body, &event); err != nil {
+		// 无法解析的消息重试也不会成功，直接丢弃
+		s.log.Errorf("decode instance event failed, drop: routingKey=%s err=%v", d.RoutingKey, err)
+		_ = d.Reject(false)
+		return
+	}
+
+	ev := toInstanceEvent(&event, d)
+	err := s.uc.HandleEvent(ctx, ev)
+	if err == nil {
+		_ = d.Ack(false)
+		return
+	}
+	if biz.IsPermanentError(err) {
+		s.log.Errorf("handle instance event failed permanently, dead-letter: eventID=%s type=%s err=%v", ev.EventID, ev.EventType, err)
+		s.republish(ctx, ch, d, s.deadLetterQueue(), amqp.Table{instanceEventErrorHeader: err.Error()})
+		return
+	}
+
+	attempts := retryCount(d) + 1
+	if attempts > instanceEventMaxRetries {
+		s.log.Errorf("handle instance event failed after %d retries, dead-letter: eventID=%s type=%s err=%v",
+			instanceEventMaxRetries, ev.EventID, ev.EventType, err)
+		s.republish(ctx, ch, d, s.deadLetterQueue(), amqp.Table{instanceEventErrorHeader: err.Error()})
+		return
+	}
+	// 转入延迟队列而不是在消费协程中等待，单个事件的失败不阻塞其他事件
+	delay := biz.OutboxBackoff(attempts, instanceEventRetryBase, instanceEventRetryMax)
+	s.log.Errorf("handle instance event failed, retry in %s (attempt %d/%d): eventID=%s type=%s err=%v",
+		delay, attempts, instanceEventMaxRetries, ev.EventID, ev.EventType, err)
+	s.republish(ctx, ch, d, s.retryQueue(delay), amqp.Table{instanceEventRetryHeader: attempts})
+}
+
+// republish 把消息经默认交换机转入 queue（延迟队列或死信队列），确认写入后 ACK 原消息；
+// 转入失败时原消息重新入队，不会丢失
+func (s *InstanceEventServer) republish(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, queue string, headers amqp.Table) {
+	merged := amqp.Table{}
+	for k, v := range d.Headers {
+		if k != "x-death" {
+			merged[k] = v
+		}
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, instanceEventConfirmTimeout)
+	defer cancel()
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(pubCtx, "", queue, false, false, amqp.Publishing{
+		Headers:      merged,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Type:         d.Type,
+		AppId:        d.AppId,
+		Body:         d.Body,
+	})
+	if err == nil {
+		var acked bool
+		if acked, err = confirm.WaitContext(pubCtx); err == nil && !acked {
+			err = errors.New("nacked by broker")
+		}
+	}
+	if err != nil {
+		s.log.Errorf("move instance event to %s failed, requeue: messageID=%s err=%v", queue, d.MessageId, err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+// retryCount 读取消息已重试次数
+func retryCount(d amqp.Delivery) int32 {
+	switch v := d.Headers[instanceEventRetryHeader].(type) {
+	case int32:
+		return v
+	case int64:
+		return int32(v)
+	case int:
+		return int32(v)
+	}
+	return 0
+}
+
+// toInstanceEvent 转换为业务事件，事件未携带 ID 时依次使用 AMQP MessageId、类型+实例+时间 作为幂等键
+func toInstanceEvent(event *mq.Event, d amqp.Delivery) *biz.InstanceEvent {
+	ev := &biz.InstanceEvent{
+		EventID:    event.GetEventId(),
+		EventType:  event.GetEventType(),
+		InstanceID: event.GetInstanceId(),
+		UserID:     event.GetUserId(),
+		Status:     event.GetStatus(),
+		Reason:     event.GetReason(),
+		OccurredAt: d.Timestamp,
+	}
+	if event.GetTimestamp() != nil {
+		ev.OccurredAt = event.GetTimestamp().AsTime()
+	}
+	if ev.EventID == "" {
+		ev.EventID = d.MessageId
+	}
+	if ev.EventID == "" {
+		ev.EventID = fmt.Sprintf("%s-%d-%d", ev.EventType, ev.InstanceID, ev.OccurredAt.UnixNano())
+	}
+	return ev
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// 待支付订单过期指标（通过 HTTP /debug/vars 暴露）
+var orderExpiryMetrics = expvar.NewMap("order_expiry")
+
+// OrderExpiryServer 待支付订单过期服务器
+// 定期取消超过有效期仍未支付的订单并归还预占的库存，多个副本可同时运行
+type OrderExpiryServer struct {
+	uc       *biz.OrderUsecase
+	interval time.Duration
+	opts     biz.OrderExpiryOptions
+	log      *log.Helper
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ transport.Server = (*OrderExpiryServer)(nil)
+
+// NewOrderExpiryServer 创建待支付订单过期服务器
+func NewOrderExpiryServer(c *conf.Server, uc *biz.OrderUsecase, logger log.Logger) *OrderExpiryServer {
+	s := &OrderExpiryServer{
+		uc:       uc,
+		interval: 10 * time.Second,
+		opts: biz.OrderExpiryOptions{
+			TTL:       15 * time.Minute,
+			BatchSize: 100,
+			Lease:     time.Minute,
+		},
+		log: log.NewHelper(log.With(logger, "module", "server/order_expiry")),
+	}
+
+	// 时长配置为 0 或负数时使用默认值（interval <= 0 会使 time.NewTicker panic）
+	ec := c.GetOrderExpiry()
+	if d := ec.GetInterval().AsDuration(); d > 0 {
+		s.interval = d
+	}
+	if d := ec.GetTtl().AsDuration(); d > 0 {
+		s.opts.TTL = d
+	}
+	if ec.GetBatchSize() > 0 {
+		s.opts.BatchSize = int(ec.GetBatchSize())
+	}
+	if d := ec.GetLease().AsDuration(); d > 0 {
+		s.opts.Lease = d
+	}
+	return s
+}
+
+func (s *OrderExpiryServer) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.expireLoop(runCtx)
+	}()
+
+	s.log.Infof("order expiry server started: interval=%s ttl=%s batch=%d lease=%s", s.interval, s.opts.TTL, s.opts.BatchSize, s.opts.Lease)
+	return nil
+}
+
+func (s *OrderExpiryServer) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		s.log.Info("order expiry server stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// expireLoop 过期循环：一批满载时立即继续，否则等待下一个周期
+func (s *OrderExpiryServer) expireLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			res, err := s.uc.ExpirePendingOrders(ctx, s.opts)
+			if err != nil {
+				orderExpiryMetrics.Add("errors", 1)
+				break
+			}
+			orderExpiryMetrics.Add("expired", int64(res.Expired))
+			orderExpiryMetrics.Add("paid", int64(res.Paid))
+			orderExpiryMetrics.Add("failed", int64(res.Failed))
+
+			// 处理失败的订单保持待支付，留到下一个周期重试
+			if res.Expired+res.Paid < s.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
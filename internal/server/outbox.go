@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// 发件箱中继指标（通过 HTTP /debug/vars 暴露）
+var (
+	outboxMetrics = expvar.NewMap("outbox_relay")
+	outboxPending = new(expvar.Int)
+)
+
+func init() {
+	outboxMetrics.Set("pending", outboxPending)
+}
+
+// OutboxRelayServer 发件箱中继服务器
+// 定期认领 order_outbox 中待投递的消息并发布到 resource.events Exchange
+type OutboxRelayServer struct {
+	uc       *biz.OutboxUsecase
+	interval time.Duration
+	opts     biz.OutboxOptions
+	log      *log.Helper
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ transport.Server = (*OutboxRelayServer)(nil)
+
+// NewOutboxRelayServer 创建发件箱中继服务器
+func NewOutboxRelayServer(c *conf.Server, uc *biz.OutboxUsecase, logger log.Logger) *OutboxRelayServer {
+	s := &OutboxRelayServer{
+		uc:       uc,
+		interval: time.Second,
+		opts: biz.OutboxOptions{
+			BatchSize:   100,
+			MaxAttempts: 20,
+			BaseBackoff: time.Second,
+			MaxBackoff:  5 * time.Minute,
+			Lease:       30 * time.Second,
+		},
+		log: log.NewHelper(log.With(logger, "module", "server/outbox")),
+	}
+
+	// 时长配置为 0 或负数时使用默认值（interval <= 0 会使 time.NewTicker panic）
+	oc := c.GetOutbox()
+	if d := oc.GetInterval().AsDuration(); d > 0 {
+		s.interval = d
+	}
+	if oc.GetBatchSize() > 0 {
+		s.opts.BatchSize = int(oc.GetBatchSize())
+	}
+	if oc.GetMaxAttempts() > 0 {
+		s.opts.MaxAttempts = oc.GetMaxAttempts()
+	}
+	if d := oc.GetBaseBackoff().AsDuration(); d > 0 {
+		s.opts.BaseBackoff = d
+	}
+	if d := oc.GetMaxBackoff().AsDuration(); d > 0 {
+		s.opts.MaxBackoff = d
+	}
+	if s.opts.MaxBackoff < s.opts.BaseBackoff {
+		s.opts.MaxBackoff = s.opts.BaseBackoff
+	}
+	if d := oc.GetLease().AsDuration(); d > 0 {
+		s.opts.Lease = d
+	}
+	return s
+}
+
+func (s *OutboxRelayServer) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.relayLoop(runCtx)
+	}()
+
+	s.log.Infof("outbox relay server started: interval=%s batch=%d maxAttempts=%d",
+		s.interval, s.opts.BatchSize, s.opts.MaxAttempts)
+	return nil
+}
+
+func (s *OutboxRelayServer) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		s.log.Info("outbox relay server stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// relayLoop 中继循环：一批满载时立即继续，否则等待下一个周期
+func (s *OutboxRelayServer) relayLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			res, err := s.uc.Relay(ctx, s.opts)
+			if err != nil {
+				outboxMetrics.Add("errors", 1)
+				break
+			}
+			outboxMetrics.Add("sent", int64(res.Sent))
+			outboxMetrics.Add("retried", int64(res.Retried))
+			outboxMetrics.Add("dead", int64(res.Dead))
+
+			if res.Sent+res.Retried+res.Dead < s.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if pending, err := s.uc.CountPending(ctx); err == nil {
+			outboxPending.Set(pending)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"product/internal/biz"
 	"product/internal/conf"
+	"strconv"
 	"sync"
 	"time"
 
@@ -101,47 +104,144 @@ type SeckillStreamHandler interface {
 	HandleSeckillOrder(ctx context.Context, streamID string, uid string) error
 }
 
+// SeckillStreamBatchHandler 可选的批量处理接口，处理器实现后同一批消息一次处理
+type SeckillStreamBatchHandler interface {
+	// HandleSeckillOrderBatch 批量处理秒杀订单，返回值与 entries 一一对应，nil 表示可以 ACK
+	HandleSeckillOrderBatch(ctx context.Context, entries []*biz.SeckillStreamEntry) []error
+}
+
+// SeckillDeadLetterSink 死信投递（由 biz.SeckillUsecase 实现）
+type SeckillDeadLetterSink interface {
+	// DeadLetter 把消息转入死信队列并 ACK 原消息
+	DeadLetter(ctx context.Context, productID int64, letter *biz.SeckillDeadLetter) error
+}
+
+const (
+	// defaultSeckillMaxDeliveries 订单流消息默认最大投递次数
+	defaultSeckillMaxDeliveries = 5
+	// defaultSeckillWorkers 默认并发处理消息的 worker 数
+	defaultSeckillWorkers = 4
+	// defaultSeckillConsumers 默认每个实例注册的消费者数
+	defaultSeckillConsumers = 1
+	// defaultSeckillBatchSize 默认每批交给 worker 的消息数
+	defaultSeckillBatchSize = 32
+	// defaultSeckillClaimIdle 默认的重新认领空闲时长，远大于消息排队等待 worker 加处理一批的耗时
+	defaultSeckillClaimIdle = time.Minute
+	// seckillReclaimInterval 重新认领的轮询间隔
+	seckillReclaimInterval = 2 * time.Second
+)
+
+// seckillOwnScript worker 开始处理前确认消息仍由 consumer 持有，并以 XCLAIM JUSTID 重置空闲时间（不增加投递次数）
+// 返回仍持有的消息ID；空闲超时已被其他消费者认领的消息不返回
+// KEYS[1]=stream  ARGV[1]=group  ARGV[2]=consumer  ARGV[3...]=消息ID
+var seckillOwnScript = redis.NewScript(`
+local owned = {}
+for i = 3, #ARGV do
+  local pending = redis.call('XPENDING', KEYS[1], ARGV[1], ARGV[i], ARGV[i], 1, ARGV[2])
+  if #pending > 0 then
+    redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[2], 0, ARGV[i], 'JUSTID')
+    owned[#owned + 1] = ARGV[i]
+  end
+end
+return owned
+`)
+
+// seckillJob 待 worker 处理的一批消息，deliveries 为 0 表示需要从 XPENDING 查询投递次数
+type seckillJob struct {
+	consumer   string // 读取（或认领）这批消息的消费者名
+	msgs       []redis.XMessage
+	deliveries int64
+}
+
 // SeckillStreamServer 秒杀 Stream 消费服务器
+// 每个消费者名各自 XREADGROUP 拉取消息，交给固定数量的 worker 并发处理，每条消息处理成功后单独 ACK
 type SeckillStreamServer struct {
-	rdb       *redis.Client
-	productID int64
-	stream    string
-	group     string
-	consumer  string
-	block     time.Duration
-	count     int64
-	claimIdle time.Duration
-	handler   SeckillStreamHandler
-	log       *log.Helper
-	cancel    context.CancelFunc
-	wg        sync.WaitGroup
+	rdb           *redis.Client
+	productID     int64
+	price         int64 // 活动秒杀价，写入死信以便补单
+	stream        string
+	group         string
+	consumers     []string // 本实例在消费者组中的消费者名（含主机名和进程号，多副本共享消费者组）
+	block         time.Duration
+	count         int64
+	claimIdle     time.Duration
+	maxDeliveries int64 // 处理失败且投递次数达到该值时转入死信队列
+	workers       int
+	batchSize     int // 每批交给 worker 的消息数，处理器实现 SeckillStreamBatchHandler 时整批写入订单
+	handler       SeckillStreamHandler
+	deadLetters   SeckillDeadLetterSink
+	log           *log.Helper
+
+	jobs     chan seckillJob
+	cancel   context.CancelFunc // 停止拉取消息
+	abort    context.CancelFunc // 停止超时时中断正在处理的消息
+	readers  sync.WaitGroup
+	workerWg sync.WaitGroup
 }
 
 var _ transport.Server = (*SeckillStreamServer)(nil)
 
 // NewSeckillStreamServer 创建秒杀 Stream 服务器
+// c 中未配置（为 0）的最大投递次数、worker 数、消费者数、批大小和重新认领空闲时长使用默认值
 func NewSeckillStreamServer(
 	rdb *redis.Client,
 	logger log.Logger,
 	handler SeckillStreamHandler,
+	deadLetters SeckillDeadLetterSink,
 	productID int64,
+	price int64,
+	c *conf.Server_Seckill,
 ) transport.Server {
-	// 使用固定的 stream key，与 BFF 层保持一致
-	stream := "stream:orders"
-	group := "g1"
-	consumer := fmt.Sprintf("seckill-consumer-%d", productID)
+	// 每个商品独立的 stream key，与 BFF 层保持一致
+	stream := biz.SeckillKeysFor(productID).Stream
+	group := biz.SeckillConsumerGroup
+	maxDeliveries := int64(c.GetMaxDeliveries())
+	if maxDeliveries <= 0 {
+		maxDeliveries = defaultSeckillMaxDeliveries
+	}
+	workers := int(c.GetWorkers())
+	if workers <= 0 {
+		workers = defaultSeckillWorkers
+	}
+	batchSize := int(c.GetBatchSize())
+	if batchSize <= 0 {
+		batchSize = defaultSeckillBatchSize
+	}
+	consumerCount := int(c.GetConsumers())
+	if consumerCount <= 0 {
+		consumerCount = defaultSeckillConsumers
+	}
+	claimIdle := c.GetClaimIdle().AsDuration()
+	if claimIdle <= 0 {
+		claimIdle = defaultSeckillClaimIdle
+	}
+
+	// 消费者名带上实例标识，水平扩容的多个副本在同一消费者组中分摊消息
+	instance, err := os.Hostname()
+	if err != nil || instance == "" {
+		instance = "unknown"
+	}
+	consumers := make([]string, 0, consumerCount)
+	for i := 0; i < consumerCount; i++ {
+		consumers = append(consumers, fmt.Sprintf("seckill-consumer-%d-%s-%d-%d", productID, instance, os.Getpid(), i))
+	}
 
 	return &SeckillStreamServer{
-		rdb:       rdb,
-		productID: productID,
-		stream:    stream,
-		group:     group,
-		consumer:  consumer,
-		block:     2 * time.Second,
-		count:     128,
-		claimIdle: 10 * time.Second, // 10秒后重新认领
-		handler:   handler,
-		log:       log.NewHelper(logger),
+		rdb:           rdb,
+		productID:     productID,
+		price:         price,
+		stream:        stream,
+		group:         group,
+		consumers:     consumers,
+		block:         2 * time.Second,
+		count:         128,
+		claimIdle:     claimIdle,
+		maxDeliveries: maxDeliveries,
+		workers:       workers,
+		batchSize:     batchSize,
+		handler:       handler,
+		deadLetters:   deadLetters,
+		log:           log.NewHelper(logger),
 	}
 }
 
@@ -155,30 +255,48 @@ func (s *SeckillStreamServer) Start(ctx context.Context) error {
 		return err
 	}
 
-	runCtx, cancel := context.WithCancel(context.Background())
+	readCtx, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
+	// 处理消息使用独立的上下文，停止时已拉取的消息继续处理完
+	handleCtx, abort := context.WithCancel(context.Background())
+	s.abort = abort
+
+	// 无缓冲：worker 全忙时拉取循环阻塞，已拉取未处理的消息不超过 worker 数 × 批大小
+	s.jobs = make(chan seckillJob)
+	for i := 0; i < s.workers; i++ {
+		s.workerWg.Add(1)
+		go func() {
+			defer s.workerWg.Done()
+			s.worker(handleCtx)
+		}()
+	}
 
-	// 启动消费循环
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		s.consumeLoop(runCtx)
-	}()
+	// 启动消费循环（每个消费者名一个）
+	for _, consumer := range s.consumers {
+		consumer := consumer
+		s.readers.Add(1)
+		go func() {
+			defer s.readers.Done()
+			s.consumeLoop(readCtx, consumer)
+		}()
+	}
 
 	// 启动重新认领循环
 	if s.claimIdle > 0 {
-		s.wg.Add(1)
+		s.readers.Add(1)
 		go func() {
-			defer s.wg.Done()
-			s.reclaimLoop(runCtx)
+			defer s.readers.Done()
+			s.reclaimLoop(readCtx, s.consumers[0])
 		}()
 	}
 
-	s.log.Infof("seckill stream server started: productID=%d stream=%s group=%s consumer=%s",
-		s.productID, s.stream, s.group, s.consumer)
+	s.log.Infof("seckill stream server started: productID=%d stream=%s group=%s consumers=%v workers=%d batch=%d",
+		s.productID, s.stream, s.group, s.consumers, s.workers, s.batchSize)
 	return nil
 }
 
+// Stop 停止拉取新消息，等待 worker 处理完已拉取的消息（优雅排空）
+// ctx 到期时中断正在处理的消息，未 ACK 的消息保留 pending，由其他副本或重启后重新认领
 func (s *SeckillStreamServer) Stop(ctx context.Context) error {
 	if s.cancel != nil {
 		s.cancel()
@@ -187,18 +305,47 @@ func (s *SeckillStreamServer) Stop(ctx context.Context) error {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		s.wg.Wait()
+		s.readers.Wait()
+		if s.jobs != nil {
+			close(s.jobs)
+		}
+		s.workerWg.Wait()
 	}()
 
 	select {
 	case <-done:
+		s.removeIdleConsumers(ctx)
 		s.log.Infof("seckill stream server stopped: productID=%d", s.productID)
 		return nil
 	case <-ctx.Done():
+		if s.abort != nil {
+			s.abort()
+		}
 		return ctx.Err()
 	}
 }
 
+// removeIdleConsumers 从消费者组中删除本实例没有 pending 消息的消费者，避免副本重建后遗留大量消费者名
+func (s *SeckillStreamServer) removeIdleConsumers(ctx context.Context) {
+	infos, err := s.rdb.XInfoConsumers(ctx, s.stream, s.group).Result()
+	if err != nil {
+		s.log.Warnf("XInfoConsumers failed: stream=%s err=%v", s.stream, err)
+		return
+	}
+	pending := make(map[string]int64, len(infos))
+	for _, info := range infos {
+		pending[info.Name] = info.Pending
+	}
+	for _, consumer := range s.consumers {
+		if n, ok := pending[consumer]; !ok || n > 0 {
+			continue
+		}
+		if err := s.rdb.XGroupDelConsumer(ctx, s.stream, s.group, consumer).Err(); err != nil {
+			s.log.Warnf("XGroupDelConsumer failed: consumer=%s err=%v", consumer, err)
+		}
+	}
+}
+
 // ensureGroup 确保消费者组存在
 func (s *SeckillStreamServer) ensureGroup(ctx context.Context) error {
 	// 使用 XGroupCreateMkStream 会自动创建 Stream（如果不存在）
@@ -216,8 +363,63 @@ func (s *SeckillStreamServer) ensureGroup(ctx context.Context) error {
 	return nil
 }
 
-// consumeLoop 消费循环
-func (s *SeckillStreamServer) consumeLoop(ctx context.Context) {
+// worker 处理消息直到 jobs 关闭，只处理仍由本实例持有的消息
+func (s *SeckillStreamServer) worker(ctx context.Context) {
+	for job := range s.jobs {
+		if msgs := s.own(ctx, job.consumer, job.msgs); len(msgs) > 0 {
+			s.handleBatch(ctx, msgs, job.deliveries)
+		}
+	}
+}
+
+// own 确认一批消息仍由 consumer 持有并重置空闲时间，返回仍持有的消息
+// 消息在排队等待 worker 期间空闲超时、已被其他消费者认领时跳过，避免重复处理；确认失败时按原样处理
+func (s *SeckillStreamServer) own(ctx context.Context, consumer string, msgs []redis.XMessage) []redis.XMessage {
+	args := make([]interface{}, 0, len(msgs)+2)
+	args = append(args, s.group, consumer)
+	for _, msg := range msgs {
+		args = append(args, msg.ID)
+	}
+	ids, err := seckillOwnScript.Run(ctx, s.rdb, []string{s.stream}, args...).StringSlice()
+	if err != nil {
+		s.log.Errorf("confirm stream message ownership failed: consumer=%s count=%d err=%v", consumer, len(msgs), err)
+		return msgs
+	}
+	if len(ids) == len(msgs) {
+		return msgs
+	}
+
+	owned := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		owned[id] = true
+	}
+	kept := make([]redis.XMessage, 0, len(ids))
+	for _, msg := range msgs {
+		if owned[msg.ID] {
+			kept = append(kept, msg)
+			continue
+		}
+		s.log.Warnf("stream message claimed by another consumer, skip: consumer=%s msgID=%s", consumer, msg.ID)
+	}
+	return kept
+}
+
+// dispatch 把消息按批大小分批交给 worker，停止拉取时返回 false（未分发的消息保留 pending）
+func (s *SeckillStreamServer) dispatch(ctx context.Context, consumer string, msgs []redis.XMessage, deliveries int64) bool {
+	for len(msgs) > 0 {
+		n := min(len(msgs), s.batchSize)
+		select {
+		case s.jobs <- seckillJob{consumer: consumer, msgs: msgs[:n], deliveries: deliveries}:
+		case <-ctx.Done():
+			return false
+		}
+		msgs = msgs[n:]
+	}
+	return true
+}
+
+// consumeLoop 以 consumer 的名义拉取新消息
+func (s *SeckillStreamServer) consumeLoop(ctx context.Context, consumer string) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -227,7 +429,7 @@ func (s *SeckillStreamServer) consumeLoop(ctx context.Context) {
 
 		res, err := s.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
 			Group:    s.group,
-			Consumer: s.consumer,
+			Consumer: consumer,
 			Streams:  []string{s.stream, ">"},
 			Count:    s.count,
 			Block:    s.block,
@@ -237,37 +439,23 @@ func (s *SeckillStreamServer) consumeLoop(ctx context.Context) {
 			if errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
 				continue
 			}
-			s.log.Errorf("XReadGroup error: %v", err)
+			s.log.Errorf("XReadGroup error: consumer=%s err=%v", consumer, err)
 			time.Sleep(200 * time.Millisecond)
 			continue
 		}
 
 		for _, strm := range res {
-			for _, msg := range strm.Messages {
-				uid := fmt.Sprint(msg.Values["uid"])
-				if uid == "" || uid == "<nil>" {
-					s.log.Warnf("missing uid field, msgID=%s values=%v", msg.ID, msg.Values)
-					continue
-				}
-
-				// 业务交付处理
-				if err := s.handler.HandleSeckillOrder(ctx, msg.ID, uid); err != nil {
-					s.log.Errorf("handle failed, keep pending: streamID=%s uid=%s err=%v", msg.ID, uid, err)
-					continue
-				}
-
-				// 确认消息
-				if _, err := s.rdb.XAck(ctx, s.stream, s.group, msg.ID).Result(); err != nil {
-					s.log.Errorf("XAck failed: msgID=%s err=%v", msg.ID, err)
-				}
+			// 新读取的消息投递次数为 1
+			if !s.dispatch(ctx, consumer, strm.Messages, 1) {
+				return
 			}
 		}
 	}
 }
 
-// reclaimLoop 重新认领超时消息
-func (s *SeckillStreamServer) reclaimLoop(ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Second)
+// reclaimLoop 重新认领空闲超过 claimIdle 的消息（持有它的消费者已崩溃或长时间未处理）
+func (s *SeckillStreamServer) reclaimLoop(ctx context.Context, consumer string) {
+	ticker := time.NewTicker(seckillReclaimInterval)
 	defer ticker.Stop()
 
 	start := "0-0"
@@ -281,7 +469,7 @@ func (s *SeckillStreamServer) reclaimLoop(ctx context.Context) {
 		msgs, next, err := s.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
 			Stream:   s.stream,
 			Group:    s.group,
-			Consumer: s.consumer,
+			Consumer: consumer,
 			MinIdle:  s.claimIdle,
 			Start:    start,
 			Count:    s.count,
@@ -298,18 +486,129 @@ func (s *SeckillStreamServer) reclaimLoop(ctx context.Context) {
 			continue
 		}
 
+		// 投递次数在处理失败时从 XPENDING 查询（XAUTOCLAIM 已计入本次认领）
+		if !s.dispatch(ctx, consumer, msgs, 0) {
+			return
+		}
+	}
+}
+
+// handleBatch 处理一批消息：处理器支持批量时整批创建订单，只 ACK 处理成功的消息，其余按 fail 处理
+func (s *SeckillStreamServer) handleBatch(ctx context.Context, msgs []redis.XMessage, deliveries int64) {
+	batch, ok := s.handler.(SeckillStreamBatchHandler)
+	if !ok || len(msgs) == 1 {
 		for _, msg := range msgs {
-			uid := fmt.Sprint(msg.Values["uid"])
-			if uid == "" || uid == "<nil>" {
-				continue
-			}
+			s.handle(ctx, msg, deliveries)
+		}
+		return
+	}
 
-			if err := s.handler.HandleSeckillOrder(ctx, msg.ID, uid); err != nil {
-				s.log.Errorf("reclaim handle failed: streamID=%s uid=%s err=%v", msg.ID, uid, err)
-				continue
-			}
+	valid := make([]redis.XMessage, 0, len(msgs))
+	entries := make([]*biz.SeckillStreamEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		uid, _ := msg.Values["uid"].(string)
+		if uid == "" {
+			s.missingUID(ctx, msg, deliveries)
+			continue
+		}
+		valid = append(valid, msg)
+		entries = append(entries, &biz.SeckillStreamEntry{StreamID: msg.ID, UserID: uid})
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	errs := batch.HandleSeckillOrderBatch(ctx, entries)
+	acked := make([]string, 0, len(valid))
+	for i, msg := range valid {
+		if errs[i] == nil {
+			acked = append(acked, msg.ID)
+			continue
+		}
+		s.fail(ctx, msg, entries[i].UserID, errs[i], deliveries)
+	}
+	if len(acked) > 0 {
+		if err := s.rdb.XAck(ctx, s.stream, s.group, acked...).Err(); err != nil {
+			s.log.Errorf("XAck failed: count=%d err=%v", len(acked), err)
+		}
+	}
+}
+
+// handle 处理单条消息：成功时 ACK，失败时按 fail 处理
+// 缺少 uid 的消息无法处理，直接转入死信队列；deliveries 为 0 表示需要从 XPENDING 查询
+func (s *SeckillStreamServer) handle(ctx context.Context, msg redis.XMessage, deliveries int64) {
+	uid, _ := msg.Values["uid"].(string)
+	if uid == "" {
+		s.missingUID(ctx, msg, deliveries)
+		return
+	}
+
+	// 业务交付处理
+	err := s.handler.HandleSeckillOrder(ctx, msg.ID, uid)
+	if err == nil {
+		// 确认消息
+		if _, err := s.rdb.XAck(ctx, s.stream, s.group, msg.ID).Result(); err != nil {
+			s.log.Errorf("XAck failed: msgID=%s err=%v", msg.ID, err)
+		}
+		return
+	}
+	s.fail(ctx, msg, uid, err, deliveries)
+}
+
+// missingUID 缺少 uid 的消息直接转入死信队列
+func (s *SeckillStreamServer) missingUID(ctx context.Context, msg redis.XMessage, deliveries int64) {
+	if deliveries == 0 {
+		deliveries = s.deliveryCount(ctx, msg.ID)
+	}
+	s.log.Warnf("missing uid field, dead-lettering: msgID=%s values=%v", msg.ID, msg.Values)
+	s.deadLetter(ctx, msg, "", biz.SeckillDeadLetterMissingUID, deliveries)
+}
+
+// fail 处理失败的消息保留 pending 等待重新认领，投递次数达到上限后转入死信队列
+func (s *SeckillStreamServer) fail(ctx context.Context, msg redis.XMessage, uid string, err error, deliveries int64) {
+	if deliveries == 0 {
+		deliveries = s.deliveryCount(ctx, msg.ID)
+	}
+	if deliveries < s.maxDeliveries {
+		s.log.Errorf("handle failed, keep pending: streamID=%s uid=%s deliveries=%d err=%v", msg.ID, uid, deliveries, err)
+		return
+	}
+	s.log.Errorf("handle failed %d times, dead-lettering: streamID=%s uid=%s err=%v", deliveries, msg.ID, uid, err)
+	s.deadLetter(ctx, msg, uid, err.Error(), deliveries)
+}
 
-			_, _ = s.rdb.XAck(ctx, s.stream, s.group, msg.ID).Result()
+// deliveryCount 通过 XPENDING 查询消息的投递次数，查询失败时返回 0（本轮不转入死信，等待下次认领）
+func (s *SeckillStreamServer) deliveryCount(ctx context.Context, msgID string) int64 {
+	pending, err := s.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Start:  msgID,
+		End:    msgID,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		if err != nil {
+			s.log.Errorf("XPending failed: msgID=%s err=%v", msgID, err)
 		}
+		return 0
+	}
+	return pending[0].RetryCount
+}
+
+// deadLetter 转入死信队列（同时 ACK 原消息），失败时消息保留 pending，下次认领时重试
+func (s *SeckillStreamServer) deadLetter(ctx context.Context, msg redis.XMessage, uid, reason string, deliveries int64) {
+	if s.deadLetters == nil {
+		return
+	}
+	letter := &biz.SeckillDeadLetter{
+		StreamID:   msg.ID,
+		UserID:     uid,
+		Price:      s.price,
+		Reason:     reason,
+		Deliveries: deliveries,
+	}
+	if req, ok := msg.Values["req"].(string); ok {
+		letter.Req, _ = strconv.ParseInt(req, 10, 64)
 	}
+	_ = s.deadLetters.DeadLetter(ctx, s.productID, letter)
 }
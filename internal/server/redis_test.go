@@ -0,0 +1,394 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const testSeckillProductID = 1
+
+// fakeSeckillHandler 记录处理过的 uid，failures 中的 uid 处理失败；release 非 nil 时处理前等待其关闭或 ctx 取消
+type fakeSeckillHandler struct {
+	mu       sync.Mutex
+	handled  []string
+	failures map[string]error
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (h *fakeSeckillHandler) HandleSeckillOrder(ctx context.Context, streamID string, uid string) error {
+	if h.started != nil {
+		select {
+		case h.started <- struct{}{}:
+		default:
+		}
+	}
+	if h.release != nil {
+		select {
+		case <-h.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handled = append(h.handled, uid)
+	return h.failures[uid]
+}
+
+func (h *fakeSeckillHandler) handledCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.handled)
+}
+
+// fakeSeckillBatchHandler 批量处理，failures 中的 uid 处理失败
+type fakeSeckillBatchHandler struct {
+	fakeSeckillHandler
+	batches [][]string
+}
+
+func (h *fakeSeckillBatchHandler) HandleSeckillOrderBatch(ctx context.Context, entries []*biz.SeckillStreamEntry) []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	uids := make([]string, 0, len(entries))
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		uids = append(uids, entry.UserID)
+		errs[i] = h.failures[entry.UserID]
+	}
+	h.batches = append(h.batches, uids)
+	return errs
+}
+
+// fakeDeadLetterSink 记录死信并与 biz.SeckillUsecase.DeadLetter 一样 ACK 原消息
+type fakeDeadLetterSink struct {
+	mu      sync.Mutex
+	rdb     *redis.Client
+	letters []*biz.SeckillDeadLetter
+}
+
+func (s *fakeDeadLetterSink) DeadLetter(ctx context.Context, productID int64, letter *biz.SeckillDeadLetter) error {
+	keys := biz.SeckillKeysFor(productID)
+	if err := s.rdb.XAck(ctx, keys.Stream, biz.SeckillConsumerGroup, letter.StreamID).Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+func (s *fakeDeadLetterSink) snapshot() []*biz.SeckillDeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*biz.SeckillDeadLetter(nil), s.letters...)
+}
+
+type streamFixture struct {
+	mr     *miniredis.Miniredis
+	rdb    *redis.Client
+	server *SeckillStreamServer
+	sink   *fakeDeadLetterSink
+	stream string
+}
+
+func newStreamFixture(t *testing.T, handler SeckillStreamHandler, c *conf.Server_Seckill) *streamFixture {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	sink := &fakeDeadLetterSink{rdb: rdb}
+	s := NewSeckillStreamServer(rdb, log.DefaultLogger, handler, sink, testSeckillProductID, 100, c).(*SeckillStreamServer)
+	s.block = 50 * time.Millisecond
+	if err := s.ensureGroup(context.Background()); err != nil {
+		t.Fatalf("ensureGroup() error = %v", err)
+	}
+	return &streamFixture{mr: mr, rdb: rdb, server: s, sink: sink, stream: s.stream}
+}
+
+// add 向订单流写入消息，uid 为空时不带 uid 字段
+func (f *streamFixture) add(t *testing.T, uid string) string {
+	t.Helper()
+	values := map[string]interface{}{"req": "1"}
+	if uid != "" {
+		values["uid"] = uid
+	}
+	id, err := f.rdb.XAdd(context.Background(), &redis.XAddArgs{Stream: f.stream, Values: values}).Result()
+	if err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+	return id
+}
+
+// read 以 consumer 的名义拉取新消息
+func (f *streamFixture) read(t *testing.T, consumer string) []redis.XMessage {
+	t.Helper()
+	res, err := f.rdb.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    biz.SeckillConsumerGroup,
+		Consumer: consumer,
+		Streams:  []string{f.stream, ">"},
+		Count:    100,
+		Block:    -1,
+	}).Result()
+	if err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+	return res[0].Messages
+}
+
+// pending 返回消息ID到持有者的映射
+func (f *streamFixture) pending(t *testing.T) map[string]string {
+	t.Helper()
+	entries, err := f.rdb.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: f.stream,
+		Group:  biz.SeckillConsumerGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err != nil {
+		t.Fatalf("XPendingExt() error = %v", err)
+	}
+	owners := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		owners[entry.ID] = entry.Consumer
+	}
+	return owners
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSeckillStreamServer_AckAndStop(t *testing.T) {
+	handler := &fakeSeckillHandler{}
+	f := newStreamFixture(t, handler, &conf.Server_Seckill{Workers: 2, BatchSize: 1, Consumers: 2})
+	for _, uid := range []string{"u1", "u2", "u3"} {
+		f.add(t, uid)
+	}
+	missing := f.add(t, "")
+
+	if err := f.server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitFor(t, 3*time.Second, "messages handled", func() bool {
+		return handler.handledCount() == 3 && len(f.sink.snapshot()) == 1
+	})
+	if err := f.server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if owners := f.pending(t); len(owners) != 0 {
+		t.Errorf("pending = %v, want all acked", owners)
+	}
+	if letters := f.sink.snapshot(); letters[0].StreamID != missing || letters[0].Reason != biz.SeckillDeadLetterMissingUID {
+		t.Errorf("dead letter = %+v, want missing uid for %s", letters[0], missing)
+	}
+	// 没有 pending 消息的消费者在停止时从消费者组中删除
+	consumers, err := f.rdb.XInfoConsumers(context.Background(), f.stream, biz.SeckillConsumerGroup).Result()
+	if err != nil || len(consumers) != 0 {
+		t.Errorf("consumers after Stop = %v, %v, want none", consumers, err)
+	}
+}
+
+func TestSeckillStreamServer_StopDrainsDispatched(t *testing.T) {
+	handler := &fakeSeckillHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+	f := newStreamFixture(t, handler, &conf.Server_Seckill{Workers: 1, BatchSize: 8})
+	for _, uid := range []string{"u1", "u2", "u3"} {
+		f.add(t, uid)
+	}
+	if err := f.server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-handler.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- f.server.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop() = %v before in-flight messages were handled", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 已交给 worker 的消息处理完后才停止
+	close(handler.release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if n := handler.handledCount(); n != 3 {
+		t.Errorf("handled = %d, want 3", n)
+	}
+	if owners := f.pending(t); len(owners) != 0 {
+		t.Errorf("pending = %v, want all acked", owners)
+	}
+}
+
+func TestSeckillStreamServer_StopTimeoutKeepsPending(t *testing.T) {
+	handler := &fakeSeckillHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+	f := newStreamFixture(t, handler, &conf.Server_Seckill{Workers: 1})
+	id := f.add(t, "u1")
+	if err := f.server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-handler.started
+
+	// 停止超时时中断正在处理的消息，消息保留 pending 等待重新认领
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := f.server.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	f.server.workerWg.Wait()
+	if owners := f.pending(t); owners[id] != f.server.consumers[0] {
+		t.Errorf("pending = %v, want %s held by %s", owners, id, f.server.consumers[0])
+	}
+}
+
+func TestSeckillStreamServer_Own(t *testing.T) {
+	f := newStreamFixture(t, &fakeSeckillHandler{}, &conf.Server_Seckill{})
+	consumer := f.server.consumers[0]
+	f.add(t, "u1")
+	f.add(t, "u2")
+	msgs := f.read(t, consumer)
+
+	// 排队期间空闲超时被其他消费者认领的消息跳过
+	if err := f.rdb.XClaim(context.Background(), &redis.XClaimArgs{
+		Stream:   f.stream,
+		Group:    biz.SeckillConsumerGroup,
+		Consumer: "other",
+		Messages: []string{msgs[0].ID},
+	}).Err(); err != nil {
+		t.Fatalf("XClaim() error = %v", err)
+	}
+	owned := f.server.own(context.Background(), consumer, msgs)
+	if len(owned) != 1 || owned[0].ID != msgs[1].ID {
+		t.Fatalf("own() = %v, want only %s", owned, msgs[1].ID)
+	}
+	if owners := f.pending(t); owners[msgs[0].ID] != "other" || owners[msgs[1].ID] != consumer {
+		t.Errorf("pending = %v, want %s held by other and %s by %s", owners, msgs[0].ID, msgs[1].ID, consumer)
+	}
+}
+
+func TestSeckillStreamServer_Fail(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxDeliveries  int32
+		deliveries     int64
+		wantDeadLetter bool
+	}{
+		{name: "below limit keeps pending", maxDeliveries: 3, deliveries: 2},
+		{name: "at limit dead-letters", maxDeliveries: 3, deliveries: 3, wantDeadLetter: true},
+		{name: "reclaimed below limit", maxDeliveries: 3, deliveries: 0},
+		{name: "reclaimed at limit", maxDeliveries: 1, deliveries: 0, wantDeadLetter: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newStreamFixture(t, &fakeSeckillHandler{}, &conf.Server_Seckill{MaxDeliveries: tt.maxDeliveries})
+			consumer := f.server.consumers[0]
+			f.add(t, "u1")
+			msg := f.read(t, consumer)[0]
+
+			// deliveries 为 0 时从 XPENDING 查询（读取一次，投递次数为 1）
+			f.server.fail(context.Background(), msg, "u1", errors.New("db down"), tt.deliveries)
+
+			letters := f.sink.snapshot()
+			if !tt.wantDeadLetter {
+				if len(letters) != 0 || f.pending(t)[msg.ID] != consumer {
+					t.Errorf("letters = %d pending = %v, want message kept pending", len(letters), f.pending(t))
+				}
+				return
+			}
+			if len(letters) != 1 {
+				t.Fatalf("letters = %d, want 1", len(letters))
+			}
+			want := tt.deliveries
+			if want == 0 {
+				want = 1
+			}
+			letter := letters[0]
+			if letter.StreamID != msg.ID || letter.UserID != "u1" || letter.Reason != "db down" ||
+				letter.Deliveries != want || letter.Price != 100 || letter.Req != 1 {
+				t.Errorf("letter = %+v, want stream %s uid u1 reason db down deliveries %d price 100 req 1", letter, msg.ID, want)
+			}
+			if owners := f.pending(t); len(owners) != 0 {
+				t.Errorf("pending = %v, want acked", owners)
+			}
+		})
+	}
+}
+
+func TestSeckillStreamServer_HandleBatchPartialFailure(t *testing.T) {
+	handler := &fakeSeckillBatchHandler{fakeSeckillHandler: fakeSeckillHandler{
+		failures: map[string]error{"u2": errors.New("db down")},
+	}}
+	f := newStreamFixture(t, handler, &conf.Server_Seckill{MaxDeliveries: 3})
+	consumer := f.server.consumers[0]
+	f.add(t, "u1")
+	failed := f.add(t, "u2")
+	missing := f.add(t, "")
+	f.add(t, "u3")
+	msgs := f.read(t, consumer)
+
+	f.server.handleBatch(context.Background(), msgs, 1)
+
+	if len(handler.batches) != 1 || len(handler.batches[0]) != 3 {
+		t.Fatalf("batches = %v, want one batch of the 3 messages with uid", handler.batches)
+	}
+	// 成功的消息 ACK，失败的保留 pending，缺少 uid 的转入死信
+	if owners := f.pending(t); len(owners) != 1 || owners[failed] != consumer {
+		t.Errorf("pending = %v, want only %s", owners, failed)
+	}
+	if letters := f.sink.snapshot(); len(letters) != 1 || letters[0].StreamID != missing {
+		t.Errorf("letters = %v, want only missing uid %s", letters, missing)
+	}
+}
+
+func TestSeckillStreamServer_ReclaimDeadLetters(t *testing.T) {
+	handler := &fakeSeckillHandler{failures: map[string]error{"u1": errors.New("db down")}}
+	f := newStreamFixture(t, handler, &conf.Server_Seckill{
+		MaxDeliveries: 2,
+		ClaimIdle:     durationpb.New(10 * time.Millisecond),
+	})
+	// 已崩溃的副本读取后未 ACK 的消息
+	id := f.add(t, "u1")
+	f.read(t, "crashed")
+	time.Sleep(20 * time.Millisecond)
+
+	if err := f.server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = f.server.Stop(context.Background()) })
+
+	// 重新认领后投递次数达到上限，处理失败转入死信并 ACK
+	waitFor(t, 3*seckillReclaimInterval, "dead letter", func() bool { return len(f.sink.snapshot()) == 1 })
+	letter := f.sink.snapshot()[0]
+	if letter.StreamID != id || letter.UserID != "u1" || letter.Deliveries < 2 {
+		t.Errorf("letter = %+v, want %s for u1 with at least 2 deliveries", letter, id)
+	}
+	if handler.handledCount() != 1 {
+		t.Errorf("handled = %d, want 1", handler.handledCount())
+	}
+	if owners := f.pending(t); len(owners) != 0 {
+		t.Errorf("pending = %v, want acked", owners)
+	}
+}
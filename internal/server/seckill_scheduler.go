@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// SeckillSchedulerServer 秒杀活动调度服务器
+// 定期开启到达开始时间的活动、结束到期的活动，并在订单流消费完后结算
+type SeckillSchedulerServer struct {
+	uc       *biz.SeckillUsecase
+	interval time.Duration
+	log      *log.Helper
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ transport.Server = (*SeckillSchedulerServer)(nil)
+
+// NewSeckillSchedulerServer 创建秒杀活动调度服务器
+func NewSeckillSchedulerServer(c *conf.Server, uc *biz.SeckillUsecase, logger log.Logger) *SeckillSchedulerServer {
+	s := &SeckillSchedulerServer{
+		uc:       uc,
+		interval: time.Second,
+		log:      log.NewHelper(log.With(logger, "module", "server/seckill_scheduler")),
+	}
+	// 配置为 0 或负数时使用默认值（interval <= 0 会使 time.NewTicker panic）
+	if d := c.GetSeckill().GetScheduleInterval().AsDuration(); d > 0 {
+		s.interval = d
+	}
+	return s
+}
+
+func (s *SeckillSchedulerServer) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.scheduleLoop(runCtx)
+	}()
+
+	s.log.Infof("seckill scheduler started: interval=%s", s.interval)
+	return nil
+}
+
+func (s *SeckillSchedulerServer) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		s.log.Info("seckill scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scheduleLoop 调度循环
+func (s *SeckillSchedulerServer) scheduleLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		res, err := s.uc.RunSchedule(ctx, time.Now())
+		if err != nil {
+			s.log.Errorf("run seckill schedule failed: %v", err)
+			continue
+		}
+		if res.Started+res.Ended+res.Settled > 0 {
+			s.log.Infof("seckill schedule: started=%d ended=%d settled=%d", res.Started, res.Ended, res.Settled)
+		}
+	}
+}
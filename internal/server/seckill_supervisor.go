@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"product/internal/biz"
+	"product/internal/conf"
+	"product/internal/service"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// seckillResyncInterval 全量对账间隔（兜底 Pub/Sub 丢失的通知）
+	seckillResyncInterval = 30 * time.Second
+	// seckillConsumerStopTimeout 单个消费者停止的超时时间
+	seckillConsumerStopTimeout = 10 * time.Second
+)
+
+// SeckillSupervisor 秒杀 Stream 消费者监管服务器
+// 订阅 seckill:campaigns:changed，活动创建时启动对应的 SeckillStreamServer，活动清空时停止，无需重启应用
+type SeckillSupervisor struct {
+	rdb       *redis.Client
+	seckillUc *biz.SeckillUsecase
+	orderUc   *biz.OrderUsecase
+	staticIDs []int64              // 配置文件中固定开启的商品
+	conf      *conf.Server_Seckill // 消费者的投递次数、worker 数和消费者数配置
+	logger    log.Logger
+	log       *log.Helper
+
+	mu        sync.Mutex
+	consumers map[int64]transport.Server
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+var _ transport.Server = (*SeckillSupervisor)(nil)
+
+// NewSeckillSupervisor 创建秒杀消费者监管服务器
+func NewSeckillSupervisor(
+	c *conf.Server,
+	rs *RedisServer,
+	seckillUc *biz.SeckillUsecase,
+	orderUc *biz.OrderUsecase,
+	logger log.Logger,
+) *SeckillSupervisor {
+	return &SeckillSupervisor{
+		rdb:       rs.Client(),
+		seckillUc: seckillUc,
+		orderUc:   orderUc,
+		staticIDs: c.GetSeckill().GetProductIds(),
+		conf:      c.GetSeckill(),
+		logger:    logger,
+		log:       log.NewHelper(log.With(logger, "module", "server/seckill_supervisor")),
+		consumers: make(map[int64]transport.Server),
+	}
+}
+
+func (s *SeckillSupervisor) Start(ctx context.Context) error {
+	if s.rdb == nil {
+		s.log.Warn("redis not available, skip seckill supervisor")
+		return nil
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	// 先订阅再对账，避免对账期间的变更通知丢失
+	pubsub := s.rdb.Subscribe(runCtx, biz.SeckillCampaignsChannel)
+	s.reconcile(runCtx, 0)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer pubsub.Close()
+		s.watchLoop(runCtx, pubsub)
+	}()
+
+	s.log.Infof("seckill supervisor started: channel=%s", biz.SeckillCampaignsChannel)
+	return nil
+}
+
+func (s *SeckillSupervisor) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for productID, consumer := range s.consumers {
+		if err := consumer.Stop(ctx); err != nil {
+			s.log.Errorf("stop seckill consumer failed: productID=%d err=%v", productID, err)
+		}
+		delete(s.consumers, productID)
+	}
+	s.log.Info("seckill supervisor stopped")
+	return nil
+}
+
+// watchLoop 收到变更通知或定时器到期时对账
+func (s *SeckillSupervisor) watchLoop(ctx context.Context, pubsub *redis.PubSub) {
+	ticker := time.NewTicker(seckillResyncInterval)
+	defer ticker.Stop()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			productID, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				s.log.Warnf("invalid seckill campaign notification: %q", msg.Payload)
+				productID = 0
+			}
+			s.log.Infof("seckill campaign changed: productID=%d", productID)
+			s.reconcile(ctx, productID)
+		case <-ticker.C:
+			s.reconcile(ctx, 0)
+		}
+	}
+}
+
+// reconcile 使运行中的消费者与活跃活动一致：新活动启动消费者，已清空的活动停止消费者
+// changed 为收到通知的商品，若仍然活跃则重启其消费者（ClearSeckill 会删除 Stream 及消费者组，新活动的秒杀价也可能变化）
+func (s *SeckillSupervisor) reconcile(ctx context.Context, changed int64) {
+	campaigns, err := s.seckillUc.ListLiveSeckills(ctx)
+	if err != nil {
+		s.log.Errorf("list seckill campaigns failed: %v", err)
+		return
+	}
+
+	// 商品ID -> 秒杀价（配置文件中的商品没有活动时按原价）
+	desired := make(map[int64]int64)
+	for _, productID := range s.staticIDs {
+		if productID > 0 {
+			desired[productID] = 0
+		}
+	}
+	for _, campaign := range campaigns {
+		desired[campaign.ProductID] = campaign.Price
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for productID, consumer := range s.consumers {
+		if _, ok := desired[productID]; ok && productID != changed {
+			continue
+		}
+		s.stopConsumer(productID, consumer)
+	}
+
+	for productID, price := range desired {
+		if _, ok := s.consumers[productID]; ok {
+			continue
+		}
+		handler := service.NewSeckillOrderService(s.orderUc, s.seckillUc, productID, price, s.logger)
+		consumer := NewSeckillStreamServer(s.rdb, s.logger, handler, s.seckillUc, productID, price, s.conf)
+		if err := consumer.Start(ctx); err != nil {
+			s.log.Errorf("start seckill consumer failed: productID=%d err=%v", productID, err)
+			continue
+		}
+		s.consumers[productID] = consumer
+		s.log.Infof("seckill consumer started: productID=%d", productID)
+	}
+}
+
+// stopConsumer 停止并移除商品的消费者，调用方需持有 s.mu
+func (s *SeckillSupervisor) stopConsumer(productID int64, consumer transport.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), seckillConsumerStopTimeout)
+	defer cancel()
+	if err := consumer.Stop(ctx); err != nil {
+		s.log.Errorf("stop seckill consumer failed: productID=%d err=%v", productID, err)
+	}
+	delete(s.consumers, productID)
+	s.log.Infof("seckill consumer stopped: productID=%d", productID)
+}
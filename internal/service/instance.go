@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"product/api/product/v1"
+	"product/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// InstanceService implements instance lifecycle APIs.
+type InstanceService struct {
+	v1.UnimplementedInstanceServiceServer
+	instanceUC *biz.InstanceUsecase
+	log        *log.Helper
+}
+
+// NewInstanceService creates an InstanceService.
+func NewInstanceService(instanceUC *biz.InstanceUsecase, logger log.Logger) *InstanceService {
+	return &InstanceService{
+		instanceUC: instanceUC,
+		log:        log.NewHelper(logger),
+	}
+}
+
+// GetInstance 获取实例（客户端轮询待处理操作）
+func (s *InstanceService) GetInstance(ctx context.Context, req *v1.GetInstanceReq) (*v1.InstanceReply, error) {
+	instance, err := s.instanceUC.GetInstance(ctx, req.GetUserId(), req.GetInstanceId())
+	if err != nil {
+		s.log.Errorf("get instance failed: instanceID=%d err=%v", req.GetInstanceId(), err)
+		return nil, err
+	}
+	return &v1.InstanceReply{Instance: toInstanceProto(instance)}, nil
+}
+
+// StartInstance 启动实例
+func (s *InstanceService) StartInstance(ctx context.Context, req *v1.StartInstanceReq) (*v1.InstanceReply, error) {
+	instance, err := s.instanceUC.StartInstance(ctx, req.GetUserId(), req.GetInstanceId())
+	if err != nil {
+		s.log.Errorf("start instance failed: instanceID=%d err=%v", req.GetInstanceId(), err)
+		return nil, err
+	}
+	return &v1.InstanceReply{Instance: toInstanceProto(instance)}, nil
+}
+
+// StopInstance 停止实例
+func (s *InstanceService) StopInstance(ctx context.Context, req *v1.StopInstanceReq) (*v1.InstanceReply, error) {
+	instance, err := s.instanceUC.StopInstance(ctx, req.GetUserId(), req.GetInstanceId())
+	if err != nil {
+		s.log.Errorf("stop instance failed: instanceID=%d err=%v", req.GetInstanceId(), err)
+		return nil, err
+	}
+	return &v1.InstanceReply{Instance: toInstanceProto(instance)}, nil
+}
+
+// DeleteInstance 删除实例
+func (s *InstanceService) DeleteInstance(ctx context.Context, req *v1.DeleteInstanceReq) (*v1.InstanceReply, error) {
+	instance, err := s.instanceUC.DeleteInstance(ctx, req.GetUserId(), req.GetInstanceId())
+	if err != nil {
+		s.log.Errorf("delete instance failed: instanceID=%d err=%v", req.GetInstanceId(), err)
+		return nil, err
+	}
+	return &v1.InstanceReply{Instance: toInstanceProto(instance)}, nil
+}
+
+// ResizeInstance 变更实例规格
+func (s *InstanceService) ResizeInstance(ctx context.Context, req *v1.ResizeInstanceReq) (*v1.InstanceReply, error) {
+	instance, err := s.instanceUC.ResizeInstance(ctx, req.GetUserId(), req.GetInstanceId(), req.GetProductId())
+	if err != nil {
+		s.log.Errorf("resize instance failed: instanceID=%d productID=%d err=%v", req.GetInstanceId(), req.GetProductId(), err)
+		return nil, err
+	}
+	return &v1.InstanceReply{Instance: toInstanceProto(instance)}, nil
+}
+
+// toInstanceProto 转换为 proto 实例对象
+func toInstanceProto(info *biz.InstanceInfo) *v1.Instance {
+	instance := &v1.Instance{
+		InstanceId:    info.InstanceID,
+		OrderId:       info.OrderID,
+		UserId:        info.UserID,
+		ProductId:     info.ProductID,
+		ProductName:   info.ProductName,
+		Spec:          toSpecProto(info.Spec),
+		Status:        info.Status,
+		PendingAction: info.PendingAction,
+		ResourceState: info.ResourceState,
+		Reason:        info.Reason,
+		CreatedAt:     info.CreatedAt.Unix(),
+		UpdatedAt:     info.UpdatedAt.Unix(),
+	}
+	if info.PendingAt != nil {
+		instance.PendingAt = info.PendingAt.Unix()
+	}
+	if info.PendingSpec != nil {
+		instance.PendingProductId = info.PendingProductID
+		instance.PendingSpec = toSpecProto(info.PendingSpec)
+	}
+	return instance
+}
@@ -2,21 +2,25 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"product/api/product/v1"
 	"product/internal/biz"
 
+	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // ProductService implements product APIs.
 type ProductService struct {
 	v1.UnimplementedProductServiceServer
-	productUC *biz.ProductUsecase
-	orderUC   *biz.OrderUsecase
-	log       *log.Helper
+	productUC   *biz.ProductUsecase
+	orderUC     *biz.OrderUsecase
+	inventoryUC *biz.InventoryUsecase
+	log         *log.Helper
 }
 
 // OrderService implements order APIs.
@@ -27,11 +31,12 @@ type OrderService struct {
 }
 
 // NewProductService creates a ProductService.
-func NewProductService(productUC *biz.ProductUsecase, orderUC *biz.OrderUsecase, logger log.Logger) *ProductService {
+func NewProductService(productUC *biz.ProductUsecase, orderUC *biz.OrderUsecase, inventoryUC *biz.InventoryUsecase, logger log.Logger) *ProductService {
 	return &ProductService{
-		productUC: productUC,
-		orderUC:   orderUC,
-		log:       log.NewHelper(logger),
+		productUC:   productUC,
+		orderUC:     orderUC,
+		inventoryUC: inventoryUC,
+		log:         log.NewHelper(logger),
 	}
 }
 
@@ -72,7 +77,7 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *v1.CreateProduc
 		Name:        req.GetName(),
 		Description: req.GetDescription(),
 		Price:       req.GetPrice(),
-		Status:      "ENABLED", // 默认启用
+		Status:      biz.ProductStatusEnabled, // 默认启用
 		Spec: &biz.ProductSpec{
 			CPU:        req.GetSpec().GetCpu(),
 			Memory:     req.GetSpec().GetMemory(),
@@ -94,7 +99,12 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *v1.CreateProduc
 
 // PurchaseProduct handles normal product purchase (not seckill).
 func (s *ProductService) PurchaseProduct(ctx context.Context, req *v1.PurchaseProductReq) (*v1.PurchaseProductReply, error) {
-	order, resourceID, err := s.orderUC.PurchaseProduct(ctx, req.GetUserId(), req.GetProductId())
+	key := req.GetIdempotencyKey()
+	if key == "" {
+		key = idempotencyKeyFromHeader(ctx)
+	}
+
+	order, resourceID, replayed, err := s.orderUC.PurchaseProduct(ctx, req.GetUserId(), req.GetProductId(), key)
 	if err != nil {
 		s.log.Errorf("purchase product failed: user_id=%s, product_id=%d, err=%v",
 			req.GetUserId(), req.GetProductId(), err)
@@ -105,9 +115,186 @@ func (s *ProductService) PurchaseProduct(ctx context.Context, req *v1.PurchasePr
 		OrderId:    order.ID,
 		ResourceId: resourceID,
 		Status:     order.Status,
+		Replayed:   replayed,
+		PaymentId:  order.PaymentID,
+	}, nil
+}
+
+// idempotencyKeyFromHeader 读取 Idempotency-Key 请求头（HTTP 头或 gRPC metadata）
+func idempotencyKeyFromHeader(ctx context.Context) string {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return ""
+	}
+	return tr.RequestHeader().Get("Idempotency-Key")
+}
+
+// GetProduct returns a product by ID.
+func (s *ProductService) GetProduct(ctx context.Context, req *v1.GetProductReq) (*v1.GetProductReply, error) {
+	product, err := s.productUC.GetProduct(ctx, req.GetId())
+	if err != nil {
+		s.log.Errorf("get product failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	return &v1.GetProductReply{
+		Product: toProductProto(product),
+	}, nil
+}
+
+// UpdateProduct updates the fields listed in update_mask.
+func (s *ProductService) UpdateProduct(ctx context.Context, req *v1.UpdateProductReq) (*v1.UpdateProductReply, error) {
+	product := &biz.Product{
+		ID:          req.GetId(),
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Price:       req.GetPrice(),
+	}
+
+	paths := make([]string, 0, len(req.GetUpdateMask().GetPaths()))
+	for path := range normalizeMaskPaths(req.GetUpdateMask().GetPaths()) {
+		paths = append(paths, path)
+	}
+
+	updated, err := s.productUC.UpdateProduct(ctx, product, paths)
+	if err != nil {
+		s.log.Errorf("update product failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	return &v1.UpdateProductReply{
+		Product: toProductProto(updated),
+	}, nil
+}
+
+// SetProductStatus enables or disables a product.
+func (s *ProductService) SetProductStatus(ctx context.Context, req *v1.SetProductStatusReq) (*v1.SetProductStatusReply, error) {
+	status, err := _productStatusFromProto(req.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+	product, err := s.productUC.SetProductStatus(ctx, req.GetId(), status)
+	if err != nil {
+		s.log.Errorf("set product status failed: id=%d status=%s err=%v", req.GetId(), req.GetStatus(), err)
+		return nil, err
+	}
+
+	return &v1.SetProductStatusReply{
+		Product: toProductProto(product),
+	}, nil
+}
+
+// DeleteProduct soft deletes a product.
+func (s *ProductService) DeleteProduct(ctx context.Context, req *v1.DeleteProductReq) (*v1.DeleteProductReply, error) {
+	if err := s.productUC.DeleteProduct(ctx, req.GetId()); err != nil {
+		s.log.Errorf("delete product failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	return &v1.DeleteProductReply{}, nil
+}
+
+// ChangeProductSpec creates a new spec version for a product.
+func (s *ProductService) ChangeProductSpec(ctx context.Context, req *v1.ChangeProductSpecReq) (*v1.ChangeProductSpecReply, error) {
+	spec := &biz.ProductSpec{
+		CPU:        req.GetSpec().GetCpu(),
+		Memory:     req.GetSpec().GetMemory(),
+		GPU:        req.GetSpec().GetGpu(),
+		Image:      req.GetSpec().GetImage(),
+		ConfigJSON: []byte(req.GetSpec().GetConfigJson()),
+	}
+
+	product, version, err := s.productUC.ChangeProductSpec(ctx, req.GetId(), spec)
+	if err != nil {
+		s.log.Errorf("change product spec failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	return &v1.ChangeProductSpecReply{
+		Product: toProductProto(product),
+		Version: toSpecVersionProto(version),
+	}, nil
+}
+
+// ListProductSpecHistory lists the spec versions of a product.
+func (s *ProductService) ListProductSpecHistory(ctx context.Context, req *v1.ListProductSpecHistoryReq) (*v1.ListProductSpecHistoryReply, error) {
+	versions, err := s.productUC.ListProductSpecHistory(ctx, req.GetId())
+	if err != nil {
+		s.log.Errorf("list product spec history failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	result := make([]*v1.ProductSpecVersion, 0, len(versions))
+	for _, version := range versions {
+		result = append(result, toSpecVersionProto(version))
+	}
+
+	return &v1.ListProductSpecHistoryReply{
+		Versions: result,
+	}, nil
+}
+
+// GetInventory returns the inventory of a product and its recent ledger entries.
+func (s *ProductService) GetInventory(ctx context.Context, req *v1.GetInventoryReq) (*v1.GetInventoryReply, error) {
+	inventory, ledger, err := s.inventoryUC.GetInventory(ctx, req.GetId(), int(req.GetLimit()))
+	if err != nil {
+		s.log.Errorf("get inventory failed: id=%d err=%v", req.GetId(), err)
+		return nil, err
+	}
+
+	movements := make([]*v1.InventoryMovement, 0, len(ledger))
+	for _, movement := range ledger {
+		movements = append(movements, &v1.InventoryMovement{
+			Id:         movement.ID,
+			Delta:      movement.Delta,
+			StockAfter: movement.StockAfter,
+			Reason:     movement.Reason,
+			OrderId:    movement.OrderID,
+			Note:       movement.Note,
+			CreatedAt:  movement.CreatedAt.Unix(),
+		})
+	}
+
+	return &v1.GetInventoryReply{
+		Inventory: toInventoryProto(inventory),
+		Ledger:    movements,
+	}, nil
+}
+
+// RestockProduct adds stock to a product.
+func (s *ProductService) RestockProduct(ctx context.Context, req *v1.RestockProductReq) (*v1.RestockProductReply, error) {
+	inventory, err := s.inventoryUC.Restock(ctx, req.GetId(), req.GetQuantity(), req.GetNote())
+	if err != nil {
+		s.log.Errorf("restock product failed: id=%d quantity=%d err=%v", req.GetId(), req.GetQuantity(), err)
+		return nil, err
+	}
+
+	return &v1.RestockProductReply{
+		Inventory: toInventoryProto(inventory),
+	}, nil
+}
+
+// AdjustInventory sets the stock of a product to an absolute value.
+func (s *ProductService) AdjustInventory(ctx context.Context, req *v1.AdjustInventoryReq) (*v1.AdjustInventoryReply, error) {
+	inventory, err := s.inventoryUC.Adjust(ctx, req.GetId(), req.GetStock(), req.GetNote())
+	if err != nil {
+		s.log.Errorf("adjust inventory failed: id=%d stock=%d err=%v", req.GetId(), req.GetStock(), err)
+		return nil, err
+	}
+
+	return &v1.AdjustInventoryReply{
+		Inventory: toInventoryProto(inventory),
 	}, nil
 }
 
+func toInventoryProto(inventory *biz.Inventory) *v1.Inventory {
+	return &v1.Inventory{
+		ProductId: inventory.ProductID,
+		Stock:     inventory.Stock,
+		UpdatedAt: inventory.UpdatedAt.Unix(),
+	}
+}
+
 func (s *ProductService) buildFilter(req *v1.ListProductReq) biz.ProductFilter {
 	filter := biz.ProductFilter{
 		SortBy:    mapSortBy(req.GetSortBy()),
@@ -171,36 +358,60 @@ func toProductProto(product *biz.Product) *v1.Product {
 		Status:      statusToInt32(product.Status),
 		Price:       product.Price,
 	}
-	if product.Spec != nil {
-		protoProduct.Spec = &v1.ProductSpec{
-			Cpu:        product.Spec.CPU,
-			Memory:     product.Spec.Memory,
-			Gpu:        product.Spec.GPU,
-			Image:      product.Spec.Image,
-			ConfigJson: string(product.Spec.ConfigJSON),
-		}
-	}
+	protoProduct.Spec = toSpecProto(product.Spec)
 	return protoProduct
 }
 
+func toSpecProto(spec *biz.ProductSpec) *v1.ProductSpec {
+	if spec == nil {
+		return nil
+	}
+	return &v1.ProductSpec{
+		Cpu:        spec.CPU,
+		Memory:     spec.Memory,
+		Gpu:        spec.GPU,
+		Image:      spec.Image,
+		ConfigJson: string(spec.ConfigJSON),
+	}
+}
+
+func toSpecVersionProto(version *biz.ProductSpecVersion) *v1.ProductSpecVersion {
+	protoVersion := &v1.ProductSpecVersion{
+		Version:       version.Version,
+		Spec:          toSpecProto(version.Spec),
+		EffectiveFrom: version.EffectiveFrom.Unix(),
+	}
+	if version.Spec != nil {
+		protoVersion.SpecId = version.Spec.ID
+	}
+	if version.EffectiveTo != nil {
+		protoVersion.EffectiveTo = version.EffectiveTo.Unix()
+	}
+	return protoVersion
+}
+
 // statusToInt32 converts string status to int32 for proto
 func statusToInt32(status string) int32 {
 	switch status {
-	case "ENABLED":
+	case biz.ProductStatusEnabled:
 		return 1
-	case "DISABLED":
+	case biz.ProductStatusDisabled:
 		return 0
 	default:
 		return 0
 	}
 }
 
-// _int32ToStatus converts int32 status to string for database
-func _int32ToStatus(status int32) string {
-	if status == 1 {
-		return "ENABLED"
+// _productStatusFromProto converts the requested status enum to string for database;
+// PRODUCT_STATUS_UNSPECIFIED (field omitted) and unknown values are rejected as InvalidArgument
+func _productStatusFromProto(status v1.ProductStatus) (string, error) {
+	switch status {
+	case v1.ProductStatus_PRODUCT_STATUS_ENABLED:
+		return biz.ProductStatusEnabled, nil
+	case v1.ProductStatus_PRODUCT_STATUS_DISABLED:
+		return biz.ProductStatusDisabled, nil
 	}
-	return "DISABLED"
+	return "", errors.BadRequest("INVALID_STATUS", fmt.Sprintf("invalid product status %s: must be PRODUCT_STATUS_ENABLED or PRODUCT_STATUS_DISABLED", status))
 }
 
 func applyProductMask(product *v1.Product, mask *fieldmaskpb.FieldMask) *v1.Product {
@@ -305,6 +516,96 @@ func (s *OrderService) GetOrder(ctx context.Context, req *v1.GetOrderReq) (*v1.G
 	}, nil
 }
 
+// ConfirmPayment 同步确认订单支付
+func (s *OrderService) ConfirmPayment(ctx context.Context, req *v1.ConfirmPaymentReq) (*v1.ConfirmPaymentReply, error) {
+	order, err := s.orderUC.ConfirmPayment(ctx, req.GetOrderId())
+	if err != nil {
+		s.log.Errorf("confirm payment failed: orderID=%d err=%v", req.GetOrderId(), err)
+		return nil, err
+	}
+
+	return &v1.ConfirmPaymentReply{
+		Order: toOrderProto(order),
+	}, nil
+}
+
+// PaymentWebhook 支付网关异步通知
+func (s *OrderService) PaymentWebhook(ctx context.Context, req *v1.PaymentWebhookReq) (*v1.PaymentWebhookReply, error) {
+	s.log.Infof("payment webhook received: paymentID=%s event=%s", req.GetPaymentId(), req.GetEvent())
+	order, err := s.orderUC.HandlePaymentWebhook(ctx, req.GetPaymentId())
+	if err != nil {
+		s.log.Errorf("handle payment webhook failed: paymentID=%s err=%v", req.GetPaymentId(), err)
+		return nil, err
+	}
+
+	return &v1.PaymentWebhookReply{
+		OrderId: order.ID,
+		Status:  order.Status,
+	}, nil
+}
+
+// CancelOrder 取消订单
+func (s *OrderService) CancelOrder(ctx context.Context, req *v1.CancelOrderReq) (*v1.CancelOrderReply, error) {
+	order, err := s.orderUC.CancelOrder(ctx, req.GetUserId(), req.GetOrderId())
+	if err != nil {
+		s.log.Errorf("cancel order failed: orderID=%d err=%v", req.GetOrderId(), err)
+		return nil, err
+	}
+
+	return &v1.CancelOrderReply{
+		Order: toOrderProto(order),
+	}, nil
+}
+
+// RefundOrder 退款并取消已支付或已完成的订单
+func (s *OrderService) RefundOrder(ctx context.Context, req *v1.RefundOrderReq) (*v1.RefundOrderReply, error) {
+	order, err := s.orderUC.RefundOrder(ctx, req.GetUserId(), req.GetOrderId(), req.GetAmount(), req.GetReason())
+	if err != nil {
+		s.log.Errorf("refund order failed: orderID=%d err=%v", req.GetOrderId(), err)
+		return nil, err
+	}
+
+	return &v1.RefundOrderReply{
+		Order: toOrderProto(order),
+	}, nil
+}
+
+// CompleteOrder 完成订单
+func (s *OrderService) CompleteOrder(ctx context.Context, req *v1.CompleteOrderReq) (*v1.CompleteOrderReply, error) {
+	order, err := s.orderUC.CompleteOrder(ctx, req.GetOrderId())
+	if err != nil {
+		s.log.Errorf("complete order failed: orderID=%d err=%v", req.GetOrderId(), err)
+		return nil, err
+	}
+
+	return &v1.CompleteOrderReply{
+		Order: toOrderProto(order),
+	}, nil
+}
+
+// DecodeID 调试接口：按订单 ID 和实例 ID 两种结构解析 ID（ID 本身不携带类型，由调用方按来源取用）
+func (s *OrderService) DecodeID(ctx context.Context, req *v1.DecodeIDReq) (*v1.DecodeIDReply, error) {
+	if req.GetId() <= 0 {
+		return nil, biz.ErrInvalidID
+	}
+
+	orderTime, workerID, orderSeq := biz.OrderID(req.GetId()).Decode()
+	instanceTime, userHash, instanceSeq := biz.InstanceID(req.GetId()).Decode()
+	return &v1.DecodeIDReply{
+		Id: req.GetId(),
+		AsOrder: &v1.DecodeIDReply_OrderIDParts{
+			TimestampMs: orderTime.UnixMilli(),
+			WorkerId:    workerID,
+			Sequence:    orderSeq,
+		},
+		AsInstance: &v1.DecodeIDReply_InstanceIDParts{
+			TimestampMs: instanceTime.UnixMilli(),
+			UserHash:    uint32(userHash),
+			Sequence:    instanceSeq,
+		},
+	}, nil
+}
+
 // GetOrderResource 获取订单关联的资源信息
 func (s *OrderService) GetOrderResource(ctx context.Context, req *v1.GetOrderResourceReq) (*v1.GetOrderResourceReply, error) {
 	resource, err := s.orderUC.GetInstanceByOrder(ctx, req.GetOrderId())
@@ -357,6 +658,7 @@ func toOrderProto(order *biz.Order) *v1.Order {
 		ResourceId: order.InstanceID,
 		Status:     order.Status,
 		CreatedAt:  order.CreatedAt.Unix(),
+		PaymentId:  order.PaymentID,
 	}
 
 	if order.PaidAt != nil {
@@ -365,6 +667,24 @@ func toOrderProto(order *biz.Order) *v1.Order {
 	if order.CompletedAt != nil {
 		protoOrder.CompletedAt = order.CompletedAt.Unix()
 	}
+	if order.Refund != nil {
+		protoOrder.RefundAmount = order.Refund.Amount
+		protoOrder.RefundReason = order.Refund.Reason
+		protoOrder.RefundId = order.Refund.RefundID
+		protoOrder.RefundedAt = order.Refund.RefundedAt.Unix()
+		protoOrder.RefundManual = order.Refund.Manual
+	}
+	if order.ProductSnapshot != nil {
+		protoOrder.ProductSnapshot = &v1.ProductSnapshot{
+			ProductId: order.ProductSnapshot.ProductID,
+			Name:      order.ProductSnapshot.Name,
+			Price:     order.ProductSnapshot.Price,
+			Spec:      toSpecProto(order.ProductSnapshot.Spec),
+		}
+		if order.ProductSnapshot.Spec != nil {
+			protoOrder.ProductSnapshot.SpecId = order.ProductSnapshot.Spec.ID
+		}
+	}
 
 	return protoOrder
 }
@@ -381,15 +701,7 @@ func toOrderResourceProto(info *biz.InstanceInfo) *v1.OrderResource {
 		CreatedAt:   info.CreatedAt.Unix(),
 	}
 
-	if info.Spec != nil {
-		protoResource.Spec = &v1.ProductSpec{
-			Cpu:        info.Spec.CPU,
-			Memory:     info.Spec.Memory,
-			Gpu:        info.Spec.GPU,
-			Image:      info.Spec.Image,
-			ConfigJson: string(info.Spec.ConfigJSON),
-		}
-	}
+	protoResource.Spec = toSpecProto(info.Spec)
 
 	return protoResource
 }
@@ -2,8 +2,7 @@ package service
 
 import (
 	"context"
-	"strings"
-	
+
 	"product/internal/biz"
 
 	"github.com/go-kratos/kratos/v2/log"
@@ -12,61 +11,82 @@ import (
 // SeckillOrderService 秒杀订单服务
 type SeckillOrderService struct {
 	orderUC   *biz.OrderUsecase
-	productID int64 // 当前服务处理的商品 ID
+	seckillUC *biz.SeckillUsecase // 订单被拒绝时补偿库存和用户名额
+	productID int64               // 当前服务处理的商品 ID
+	price     int64               // 活动秒杀价（分），0 表示按商品原价
 	log       *log.Helper
 }
 
 // NewSeckillOrderService 创建秒杀订单服务
-func NewSeckillOrderService(orderUC *biz.OrderUsecase, productID int64, logger log.Logger) *SeckillOrderService {
+func NewSeckillOrderService(orderUC *biz.OrderUsecase, seckillUC *biz.SeckillUsecase, productID int64, price int64, logger log.Logger) *SeckillOrderService {
 	return &SeckillOrderService{
 		orderUC:   orderUC,
+		seckillUC: seckillUC,
 		productID: productID,
+		price:     price,
 		log:       log.NewHelper(logger),
 	}
 }
 
 // HandleSeckillOrder 处理秒杀订单（从 Stream 消费）
-// streamID: Redis Stream 消息 ID（转换为 reqID 使用）
+// streamID: Redis Stream 消息 ID（无损打包为 reqID 使用）
 // uid: 用户 ID（UUID 字符串）
+// 同一用户的订单已存在（消息重放）时返回 nil 以便 ACK；req_id 被其他用户占用时返回错误，消息最终转入死信队列
 func (s *SeckillOrderService) HandleSeckillOrder(ctx context.Context, streamID string, uid string) error {
 	s.log.Infof("handling seckill order: streamID=%s uid=%s", streamID, uid)
 
-	// 将 streamID 转换为 int64 作为 reqID
-	reqID := hashStreamID(streamID)
-	_, _, err := s.orderUC.CreateOrderFromSeckill(ctx, s.productID, uid, reqID)
+	// 将 streamID 打包为 int64 作为 reqID（与对账使用同一规则）
+	reqID, err := biz.SeckillOrderReqID(streamID)
 	if err != nil {
-		// 检查是否是唯一约束冲突（订单已存在）
-		if isUniqueViolationError(err) {
-			s.log.Warnf("order already exists (idempotent): streamID=%s uid=%s reqID=%d", streamID, uid, reqID)
-			// 订单已存在，视为成功，返回 nil 以便 ACK 消息
-			return nil
-		}
-		s.log.Errorf("create order failed: %v", err)
+		s.log.Errorf("derive req_id failed: %v", err)
 		return err
 	}
-	return nil
+	_, _, err = s.orderUC.CreateOrderFromSeckill(ctx, s.productID, uid, reqID, s.price)
+	return s.settle(ctx, streamID, uid, reqID, err)
 }
 
-// hashStreamID 将 Redis Stream ID 转换为 int64
-// Stream ID 格式：timestamp-sequence (如 "1609459200000-0")
-func hashStreamID(streamID string) int64 {
-	var hash int64
-	for i := 0; i < len(streamID); i++ {
-		hash = hash*31 + int64(streamID[i])
+// HandleSeckillOrderBatch 批量处理同一次拉取的秒杀订单，返回值与 entries 一一对应（nil 表示可以 ACK）
+func (s *SeckillOrderService) HandleSeckillOrderBatch(ctx context.Context, entries []*biz.SeckillStreamEntry) []error {
+	s.log.Infof("handling seckill order batch: productID=%d size=%d", s.productID, len(entries))
+
+	errs := make([]error, len(entries))
+	reqs := make([]*biz.SeckillOrderRequest, 0, len(entries))
+	idx := make([]int, 0, len(entries))
+	for i, entry := range entries {
+		reqID, err := biz.SeckillOrderReqID(entry.StreamID)
+		if err != nil {
+			s.log.Errorf("derive req_id failed: %v", err)
+			errs[i] = err
+			continue
+		}
+		reqs = append(reqs, &biz.SeckillOrderRequest{UserID: entry.UserID, ReqID: reqID})
+		idx = append(idx, i)
 	}
-	if hash < 0 {
-		hash = -hash
+
+	outcomes := s.orderUC.CreateOrderBatchFromSeckill(ctx, s.productID, s.price, reqs)
+	for j, outcome := range outcomes {
+		entry := entries[idx[j]]
+		errs[idx[j]] = s.settle(ctx, entry.StreamID, entry.UserID, reqs[j].ReqID, outcome.Err)
 	}
-	return hash
+	return errs
 }
 
-// isUniqueViolationError 检查是否是唯一约束冲突错误
-func isUniqueViolationError(err error) bool {
+// settle 根据创建订单的结果决定消息能否 ACK
+// 订单被确定拒绝时重试也不会成功：归还库存并释放用户名额后 ACK 消息
+func (s *SeckillOrderService) settle(ctx context.Context, streamID, uid string, reqID int64, err error) error {
 	if err == nil {
-		return false
+		return nil
+	}
+	if biz.IsSeckillOrderRejected(err) {
+		s.log.Warnf("seckill order rejected, compensating: streamID=%s uid=%s err=%v", streamID, uid, err)
+		return s.seckillUC.Compensate(ctx, &biz.SeckillCompensation{
+			ProductID: s.productID,
+			StreamID:  streamID,
+			UserID:    uid,
+			ReqID:     reqID,
+			Reason:    err.Error(),
+		})
 	}
-	errMsg := err.Error()
-	return strings.Contains(errMsg, "23505") || 
-	       strings.Contains(errMsg, "duplicate key") || 
-	       strings.Contains(errMsg, "uq_orders_product_req")
+	s.log.Errorf("create order failed: streamID=%s uid=%s err=%v", streamID, uid, err)
+	return err
 }
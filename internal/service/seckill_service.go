@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	pb "product/api/product/v1"
 	"product/internal/biz"
@@ -13,23 +14,42 @@ import (
 type SeckillService struct {
 	pb.UnimplementedSeckillServiceServer
 
-	uc  *biz.SeckillUsecase
-	log *log.Helper
+	uc      *biz.SeckillUsecase
+	orderUC *biz.OrderUsecase // 对账补单
+	logger  log.Logger
+	log     *log.Helper
 }
 
 // NewSeckillService 创建秒杀管理服务
-func NewSeckillService(uc *biz.SeckillUsecase, logger log.Logger) *SeckillService {
+func NewSeckillService(uc *biz.SeckillUsecase, orderUC *biz.OrderUsecase, logger log.Logger) *SeckillService {
 	return &SeckillService{
-		uc:  uc,
-		log: log.NewHelper(logger),
+		uc:      uc,
+		orderUC: orderUC,
+		logger:  logger,
+		log:     log.NewHelper(logger),
 	}
 }
 
-// InitSeckill 初始化秒杀活动
+// InitSeckill 创建秒杀活动
 func (s *SeckillService) InitSeckill(ctx context.Context, req *pb.InitSeckillReq) (*pb.InitSeckillReply, error) {
-	s.log.Infof("init seckill: product_id=%d stock=%d", req.ProductId, req.Stock)
+	s.log.Infof("init seckill: product_id=%d stock=%d start=%d end=%d", req.ProductId, req.Stock, req.StartTime, req.EndTime)
 
-	if err := s.uc.InitSeckill(ctx, req.ProductId, req.Stock); err != nil {
+	campaign := &biz.SeckillCampaign{
+		ProductID:    req.ProductId,
+		InitialStock: req.Stock,
+		Price:        req.Price,
+		PerUserLimit: req.PerUserLimit,
+	}
+	if req.StartTime > 0 {
+		campaign.StartAt = time.Unix(req.StartTime, 0)
+	}
+	if req.EndTime > 0 {
+		endAt := time.Unix(req.EndTime, 0)
+		campaign.EndAt = &endAt
+	}
+
+	campaign, err := s.uc.InitSeckill(ctx, campaign)
+	if err != nil {
 		s.log.Errorf("init seckill failed: %v", err)
 		return &pb.InitSeckillReply{
 			Success: false,
@@ -37,38 +57,66 @@ func (s *SeckillService) InitSeckill(ctx context.Context, req *pb.InitSeckillReq
 		}, nil
 	}
 
+	message := "秒杀活动初始化成功"
+	if campaign.Status == biz.SeckillStatusScheduled {
+		message = "秒杀活动已创建，将在开始时间自动开启"
+	}
 	return &pb.InitSeckillReply{
-		Success: true,
-		Message: "秒杀活动初始化成功",
+		Success:  true,
+		Message:  message,
+		Campaign: toSeckillCampaignProto(campaign),
 	}, nil
 }
 
-// GetCurrentSeckill 获取当前秒杀信息
+// GetCurrentSeckill 获取商品的秒杀信息
 func (s *SeckillService) GetCurrentSeckill(ctx context.Context, req *pb.GetCurrentSeckillReq) (*pb.GetCurrentSeckillReply, error) {
-	productID, stock, err := s.uc.GetCurrentSeckill(ctx)
+	reply := &pb.GetCurrentSeckillReply{ProductId: req.ProductId}
+
+	// 如果该商品没有进行中的秒杀，返回 active=false
+	live, err := s.uc.GetSeckill(ctx, req.ProductId)
+	switch {
+	case err == nil:
+		reply.Stock = live.Stock
+		reply.Active = true
+	case err != biz.ErrNoActiveSeckill:
+		s.log.Errorf("get seckill failed: product_id=%d err=%v", req.ProductId, err)
+		return nil, err
+	}
+
+	// 尚未开始或等待结算的活动也一并返回
+	campaign, err := s.uc.GetCampaign(ctx, req.ProductId)
+	switch {
+	case err == nil:
+		reply.Campaign = toSeckillCampaignProto(campaign)
+	case err != biz.ErrNoActiveSeckill:
+		s.log.Errorf("get seckill campaign failed: product_id=%d err=%v", req.ProductId, err)
+		return nil, err
+	}
+	return reply, nil
+}
+
+// ListSeckills 列出所有未结束的秒杀活动
+func (s *SeckillService) ListSeckills(ctx context.Context, req *pb.ListSeckillsReq) (*pb.ListSeckillsReply, error) {
+	campaigns, err := s.uc.ListSeckills(ctx)
 	if err != nil {
-		// 如果没有活跃的秒杀，返回 active=false
-		if err == biz.ErrNoActiveSeckill {
-			return &pb.GetCurrentSeckillReply{
-				Active: false,
-			}, nil
-		}
-		s.log.Errorf("get current seckill failed: %v", err)
+		s.log.Errorf("list seckills failed: %v", err)
 		return nil, err
 	}
 
-	return &pb.GetCurrentSeckillReply{
-		ProductId: productID,
-		Stock:     stock,
-		Active:    true,
-	}, nil
+	reply := &pb.ListSeckillsReply{
+		Campaigns: make([]*pb.SeckillCampaign, 0, len(campaigns)),
+	}
+	for _, campaign := range campaigns {
+		reply.Campaigns = append(reply.Campaigns, toSeckillCampaignProto(campaign))
+	}
+	return reply, nil
 }
 
-// ClearSeckill 清空秒杀数据
+// ClearSeckill 取消商品当前的秒杀活动并清空秒杀数据
 func (s *SeckillService) ClearSeckill(ctx context.Context, req *pb.ClearSeckillReq) (*pb.ClearSeckillReply, error) {
-	s.log.Info("clearing seckill data")
+	s.log.Infof("clearing seckill data: product_id=%d", req.ProductId)
 
-	if err := s.uc.ClearSeckill(ctx); err != nil {
+	if err := s.uc.ClearSeckill(ctx, req.ProductId); err != nil {
 		s.log.Errorf("clear seckill failed: %v", err)
 		return &pb.ClearSeckillReply{
 			Success: false,
@@ -81,3 +129,184 @@ func (s *SeckillService) ClearSeckill(ctx context.Context, req *pb.ClearSeckillR
 		Message: "秒杀数据已清空",
 	}, nil
 }
+
+// Purchase 秒杀抢购，活动不存在或未开始时返回 ErrNoActiveSeckill
+func (s *SeckillService) Purchase(ctx context.Context, req *pb.SeckillPurchaseReq) (*pb.SeckillPurchaseReply, error) {
+	res, err := s.uc.Purchase(ctx, req.ProductId, req.UserId)
+	if err != nil {
+		s.log.Errorf("seckill purchase failed: product_id=%d user_id=%s err=%v", req.ProductId, req.UserId, err)
+		return nil, err
+	}
+
+	reply := &pb.SeckillPurchaseReply{
+		Result:   res.Result,
+		ReqId:    res.ReqID,
+		StreamId: res.StreamID,
+	}
+	switch res.Result {
+	case biz.SeckillPurchaseAccepted:
+		reply.Message = "抢购成功，订单处理中"
+	case biz.SeckillPurchaseSoldOut:
+		reply.Message = "已售罄"
+	case biz.SeckillPurchaseAlreadyBought:
+		reply.Message = "已达到每人限购数量"
+	}
+	return reply, nil
+}
+
+// ReconcileSeckill 对账商品当前活动，replay=true 时对缺失订单的消息补单
+// 补单与 Stream 消费者走同一个 HandleSeckillOrder，订单已存在时按幂等处理
+func (s *SeckillService) ReconcileSeckill(ctx context.Context, req *pb.ReconcileSeckillReq) (*pb.ReconcileSeckillReply, error) {
+	report, err := s.uc.ReconcileSeckill(ctx, req.ProductId)
+	if err != nil {
+		s.log.Errorf("reconcile seckill failed: product_id=%d err=%v", req.ProductId, err)
+		return nil, err
+	}
+
+	reply := &pb.ReconcileSeckillReply{
+		ProductId:         report.ProductID,
+		CampaignId:        report.CampaignID,
+		InitialStock:      report.InitialStock,
+		Sold:              report.Sold,
+		StreamEntries:     int32(report.StreamEntries),
+		Uid2ReqEntries:    int32(report.UID2ReqEntries),
+		OrdersCreated:     int32(report.OrdersCreated),
+		Missing:           make([]*pb.SeckillStreamEntry, 0, len(report.Missing)),
+		Duplicated:        make([]*pb.Order, 0, len(report.Duplicated)),
+		Orphaned:          make([]*pb.Order, 0, len(report.Orphaned)),
+		UsersWithoutEntry: report.UsersWithoutEntry,
+		Consistent:        report.Consistent(),
+	}
+	for _, entry := range report.Missing {
+		reply.Missing = append(reply.Missing, &pb.SeckillStreamEntry{
+			StreamId: entry.StreamID,
+			UserId:   entry.UserID,
+			Req:      entry.Req,
+		})
+	}
+	for _, order := range report.Duplicated {
+		reply.Duplicated = append(reply.Duplicated, toOrderProto(order))
+	}
+	for _, order := range report.Orphaned {
+		reply.Orphaned = append(reply.Orphaned, toOrderProto(order))
+	}
+
+	if !req.Replay || len(report.Missing) == 0 {
+		return reply, nil
+	}
+
+	handler := NewSeckillOrderService(s.orderUC, s.uc, report.ProductID, report.Price, s.logger)
+	for _, entry := range report.Missing {
+		if entry.UserID == "" {
+			s.log.Warnf("skip replay of seckill message without uid: product_id=%d stream_id=%s", req.ProductId, entry.StreamID)
+			reply.ReplayFailed++
+			continue
+		}
+		if err := handler.HandleSeckillOrder(ctx, entry.StreamID, entry.UserID); err != nil {
+			s.log.Errorf("replay seckill order failed: product_id=%d stream_id=%s uid=%s err=%v",
+				req.ProductId, entry.StreamID, entry.UserID, err)
+			reply.ReplayFailed++
+			continue
+		}
+		reply.Replayed++
+	}
+	s.log.Infof("seckill orders replayed: product_id=%d replayed=%d failed=%d", req.ProductId, reply.Replayed, reply.ReplayFailed)
+	return reply, nil
+}
+
+// ListSeckillDeadLetters 列出商品订单流的死信
+func (s *SeckillService) ListSeckillDeadLetters(ctx context.Context, req *pb.ListSeckillDeadLettersReq) (*pb.ListSeckillDeadLettersReply, error) {
+	letters, err := s.uc.ListDeadLetters(ctx, req.ProductId, req.After, req.Limit)
+	if err != nil {
+		s.log.Errorf("list seckill dead letters failed: product_id=%d err=%v", req.ProductId, err)
+		return nil, err
+	}
+
+	reply := &pb.ListSeckillDeadLettersReply{
+		Letters: make([]*pb.SeckillDeadLetter, 0, len(letters)),
+	}
+	for _, letter := range letters {
+		reply.Letters = append(reply.Letters, &pb.SeckillDeadLetter{
+			Id:         letter.ID,
+			StreamId:   letter.StreamID,
+			UserId:     letter.UserID,
+			Req:        letter.Req,
+			Price:      letter.Price,
+			Reason:     letter.Reason,
+			Deliveries: letter.Deliveries,
+			FailedAt:   letter.FailedAt.Unix(),
+		})
+	}
+	return reply, nil
+}
+
+// ReplaySeckillDeadLetter 经 HandleSeckillOrder 重新处理死信（订单已存在时按幂等处理），成功后删除死信
+func (s *SeckillService) ReplaySeckillDeadLetter(ctx context.Context, req *pb.ReplaySeckillDeadLetterReq) (*pb.ReplaySeckillDeadLetterReply, error) {
+	s.log.Infof("replaying seckill dead letter: product_id=%d id=%s", req.ProductId, req.Id)
+
+	err := s.replayDeadLetter(ctx, req.ProductId, req.Id)
+	if err != nil {
+		s.log.Errorf("replay seckill dead letter failed: product_id=%d id=%s err=%v", req.ProductId, req.Id, err)
+		return &pb.ReplaySeckillDeadLetterReply{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.ReplaySeckillDeadLetterReply{
+		Success: true,
+		Message: "死信已重新处理",
+	}, nil
+}
+
+func (s *SeckillService) replayDeadLetter(ctx context.Context, productID int64, id string) error {
+	letter, err := s.uc.GetDeadLetter(ctx, productID, id)
+	if err != nil {
+		return err
+	}
+	if letter.UserID == "" {
+		return biz.ErrSeckillDeadLetterNotReplayable
+	}
+
+	handler := NewSeckillOrderService(s.orderUC, s.uc, productID, letter.Price, s.logger)
+	if err := handler.HandleSeckillOrder(ctx, letter.StreamID, letter.UserID); err != nil {
+		return err
+	}
+	return s.uc.DiscardDeadLetter(ctx, productID, id)
+}
+
+// DiscardSeckillDeadLetter 丢弃死信
+func (s *SeckillService) DiscardSeckillDeadLetter(ctx context.Context, req *pb.DiscardSeckillDeadLetterReq) (*pb.DiscardSeckillDeadLetterReply, error) {
+	s.log.Infof("discarding seckill dead letter: product_id=%d id=%s", req.ProductId, req.Id)
+
+	if err := s.uc.DiscardDeadLetter(ctx, req.ProductId, req.Id); err != nil {
+		s.log.Errorf("discard seckill dead letter failed: product_id=%d id=%s err=%v", req.ProductId, req.Id, err)
+		return &pb.DiscardSeckillDeadLetterReply{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.DiscardSeckillDeadLetterReply{
+		Success: true,
+		Message: "死信已丢弃",
+	}, nil
+}
+
+func toSeckillCampaignProto(campaign *biz.SeckillCampaign) *pb.SeckillCampaign {
+	out := &pb.SeckillCampaign{
+		ProductId:    campaign.ProductID,
+		Stock:        campaign.Stock,
+		CampaignId:   campaign.ID,
+		InitialStock: campaign.InitialStock,
+		Sold:         campaign.Sold,
+		Price:        campaign.Price,
+		PerUserLimit: campaign.PerUserLimit,
+		StartTime:    campaign.StartAt.Unix(),
+		Status:       campaign.Status,
+	}
+	if campaign.EndAt != nil {
+		out.EndTime = campaign.EndAt.Unix()
+	}
+	return out
+}
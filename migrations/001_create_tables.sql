@@ -0,0 +1,122 @@
+-- 基础表：商品、规格、规格版本、订单、订单快照、发件箱、事件消费幂等表
+-- 列名与 internal/data 中的持久化对象一致；已有数据库重复执行时只补齐缺失的表、列和索引
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS product_specs (
+    spec_id     BIGSERIAL PRIMARY KEY,
+    cpu         INT          NOT NULL,
+    memory      INT          NOT NULL,
+    gpu         INT          DEFAULT 0,
+    image       VARCHAR(255) NOT NULL,
+    config_json JSONB,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    product_id  BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(128) NOT NULL,
+    description TEXT,
+    status      VARCHAR(20)  NOT NULL DEFAULT 'ENABLED',
+    price       BIGINT       NOT NULL,
+    spec_id     BIGINT       NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 软删除：删除时同时置为 DISABLED，历史订单仍可关联
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_products_spec_id ON products(spec_id);
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at);
+
+-- product_spec_versions：商品在每个时间段使用的规格
+CREATE TABLE IF NOT EXISTS product_spec_versions (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT      NOT NULL,
+    version    INT         NOT NULL,
+    spec_id    BIGINT      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_product_spec_version ON product_spec_versions(product_id, version);
+
+-- 回填：已有商品的当前规格记为版本 1
+INSERT INTO product_spec_versions (product_id, version, spec_id, created_at)
+SELECT product_id, 1, spec_id, created_at FROM products
+ON CONFLICT (product_id, version) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS orders (
+    order_id     BIGINT PRIMARY KEY,
+    user_id      UUID,
+    product_id   BIGINT      NOT NULL,
+    req_id       BIGINT      NOT NULL DEFAULT 0,
+    amount       BIGINT      NOT NULL,
+    instance_id  BIGINT,
+    status       VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    paid_at      TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_orders_product_req ON orders(product_id, req_id);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_product_id ON orders(product_id);
+CREATE INDEX IF NOT EXISTS idx_orders_instance_id ON orders(instance_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+
+-- order_snapshots：下单时固化的商品名称、价格与规格
+CREATE TABLE IF NOT EXISTS order_snapshots (
+    order_id    BIGINT       PRIMARY KEY,
+    product_id  BIGINT       NOT NULL,
+    name        VARCHAR(128) NOT NULL,
+    price       BIGINT       NOT NULL,
+    spec_id     BIGINT       NOT NULL,
+    cpu         INT          NOT NULL,
+    memory      INT          NOT NULL,
+    gpu         INT          DEFAULT 0,
+    image       VARCHAR(255) NOT NULL,
+    config_json JSONB,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 回填：历史订单按下单时生效的规格版本补录快照（名称取当前值，价格取订单金额）
+INSERT INTO order_snapshots (order_id, product_id, name, price, spec_id, cpu, memory, gpu, image, config_json, created_at)
+SELECT o.order_id, o.product_id, p.name, o.amount, s.spec_id, s.cpu, s.memory, s.gpu, s.image, s.config_json, o.created_at
+FROM orders o
+JOIN products p ON p.product_id = o.product_id
+JOIN LATERAL (
+    SELECT v.spec_id FROM product_spec_versions v
+    WHERE v.product_id = o.product_id AND v.created_at <= o.created_at
+    ORDER BY v.version DESC LIMIT 1
+) v ON TRUE
+JOIN product_specs s ON s.spec_id = v.spec_id
+ON CONFLICT (order_id) DO NOTHING;
+
+-- order_outbox：与订单同事务写入的实例事件，由发件箱中继投递
+CREATE TABLE IF NOT EXISTS order_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    event_type      VARCHAR(64)  NOT NULL,
+    routing_key     VARCHAR(128) NOT NULL,
+    aggregate_id    BIGINT       NOT NULL,
+    payload         BYTEA        NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'PENDING',
+    attempts        INT          NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_outbox_pending
+    ON order_outbox(next_attempt_at, id) WHERE status = 'PENDING';
+
+-- processed_events：资源域事件消费幂等表
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id     VARCHAR(128) PRIMARY KEY,
+    event_type   VARCHAR(64)  NOT NULL,
+    processed_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
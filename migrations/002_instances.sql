@@ -0,0 +1,72 @@
+-- instances 表：实例归属、规格快照与生命周期状态
+-- 从历史订单回填，替代 instance_status 表
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS instances (
+    instance_id    BIGINT PRIMARY KEY,
+    order_id       BIGINT       NOT NULL UNIQUE,
+    user_id        UUID         NOT NULL,
+    product_id     BIGINT       NOT NULL,
+    name           VARCHAR(128) NOT NULL,
+    spec_id        BIGINT       NOT NULL,
+    cpu            INT          NOT NULL,
+    memory         INT          NOT NULL,
+    gpu            INT          DEFAULT 0,
+    image          VARCHAR(255) NOT NULL,
+    config_json    JSONB,
+    status         VARCHAR(20)  NOT NULL,
+    resource_state VARCHAR(32),
+    reason         TEXT,
+    last_event_at  TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_instances_user_id ON instances(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_instances_status ON instances(status);
+
+-- instance_status 不存在时（未部署过事件消费）建空表，保证回填语句可执行
+CREATE TABLE IF NOT EXISTS instance_status (
+    instance_id BIGINT PRIMARY KEY,
+    order_id    BIGINT      NOT NULL,
+    status      VARCHAR(20) NOT NULL,
+    reason      TEXT,
+    event_at    TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO instances (
+    instance_id, order_id, user_id, product_id, name, spec_id,
+    cpu, memory, gpu, image, config_json,
+    status, resource_state, reason, last_event_at, created_at, updated_at
+)
+SELECT
+    o.instance_id, o.order_id, o.user_id, o.product_id,
+    COALESCE(s.name, p.name),
+    COALESCE(s.spec_id, p.spec_id),
+    COALESCE(s.cpu, ps.cpu),
+    COALESCE(s.memory, ps.memory),
+    COALESCE(s.gpu, ps.gpu),
+    COALESCE(s.image, ps.image),
+    COALESCE(s.config_json, ps.config_json),
+    COALESCE(st.status, CASE o.status
+        WHEN 'COMPLETED' THEN 'RUNNING'
+        WHEN 'CANCELLED' THEN 'DELETED'
+        ELSE 'CREATING' END),
+    st.status,
+    st.reason,
+    st.event_at,
+    o.created_at,
+    COALESCE(st.updated_at, o.created_at)
+FROM orders o
+LEFT JOIN order_snapshots s ON s.order_id = o.order_id
+LEFT JOIN products p ON p.product_id = o.product_id
+LEFT JOIN product_specs ps ON ps.spec_id = p.spec_id
+LEFT JOIN instance_status st ON st.instance_id = o.instance_id
+WHERE o.instance_id IS NOT NULL
+ON CONFLICT (instance_id) DO NOTHING;
+
+DROP TABLE IF EXISTS instance_status;
+
+COMMIT;
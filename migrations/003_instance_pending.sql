@@ -0,0 +1,4 @@
+-- instances 表：记录已下发、等待资源域确认的生命周期操作
+
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_action VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_at TIMESTAMPTZ;
@@ -0,0 +1,20 @@
+-- seckill_campaigns 表：带开始/结束时间的秒杀活动，由调度器到点开启、到期结束并结算
+-- 同一商品最多一个未结束的活动（SCHEDULED/ACTIVE/ENDED），与 Redis 中按商品划分的 Key 对应
+
+CREATE TABLE IF NOT EXISTS seckill_campaigns (
+    id             BIGSERIAL PRIMARY KEY,
+    product_id     BIGINT      NOT NULL,
+    initial_stock  INT         NOT NULL,
+    sold           INT         NOT NULL DEFAULT 0,
+    price          BIGINT      NOT NULL DEFAULT 0,
+    per_user_limit INT         NOT NULL DEFAULT 1,
+    start_at       TIMESTAMPTZ NOT NULL,
+    end_at         TIMESTAMPTZ,
+    status         VARCHAR(20) NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_seckill_campaigns_unfinished
+    ON seckill_campaigns(product_id) WHERE status IN ('SCHEDULED', 'ACTIVE', 'ENDED');
+CREATE INDEX IF NOT EXISTS idx_seckill_campaigns_status ON seckill_campaigns(status, start_at);
@@ -0,0 +1,17 @@
+-- seckill_compensations 表：秒杀订单被确定拒绝时归还 Redis 库存、释放用户名额的审计记录
+-- 每条订单流消息最多补偿一次（Redis 中以消息ID去重，本表以唯一索引去重）
+
+CREATE TABLE IF NOT EXISTS seckill_compensations (
+    id             BIGSERIAL PRIMARY KEY,
+    product_id     BIGINT      NOT NULL,
+    stream_id      VARCHAR(64) NOT NULL,
+    user_id        VARCHAR(64) NOT NULL,
+    req_id         BIGINT      NOT NULL,
+    reason         TEXT        NOT NULL,
+    stock_returned BOOLEAN     NOT NULL,
+    slot_released  BOOLEAN     NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_seckill_compensations_stream
+    ON seckill_compensations(product_id, stream_id);
@@ -0,0 +1,23 @@
+-- product_inventory 表：正常购买的商品库存（可选），没有记录的商品不限量售卖
+-- 秒杀库存仍在 Redis 中，不使用本表
+-- inventory_ledger 表：库存流水，每次预占/归还/补货/调整写入一条，与库存变动同事务
+
+CREATE TABLE IF NOT EXISTS product_inventory (
+    product_id BIGINT      PRIMARY KEY,
+    stock      INTEGER     NOT NULL CHECK (stock >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS inventory_ledger (
+    id          BIGSERIAL PRIMARY KEY,
+    product_id  BIGINT      NOT NULL,
+    delta       INTEGER     NOT NULL,
+    stock_after INTEGER     NOT NULL,
+    reason      VARCHAR(32) NOT NULL,
+    order_id    BIGINT,
+    note        TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_ledger_product ON inventory_ledger(product_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_ledger_order ON inventory_ledger(order_id) WHERE order_id IS NOT NULL;
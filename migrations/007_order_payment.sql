@@ -0,0 +1,7 @@
+-- orders.payment_id：正常购买下单后创建的支付意图ID，支付网关回调按该ID定位订单
+-- 正常购买的订单以 PENDING 创建，支付确认后迁移为 PAID 并创建实例
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_id VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_orders_payment_id
+    ON orders(payment_id) WHERE payment_id IS NOT NULL;
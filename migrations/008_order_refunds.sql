@@ -0,0 +1,8 @@
+-- orders 退款信息：已支付或已完成的订单退款后迁移为 CANCELLED
+-- refund_amount 为实际退款金额（分），未指定金额时按支付后的使用时长比例折算；
+-- refund_id 为支付网关退款单号，没有支付意图的订单（秒杀）为空
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_amount BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_reason VARCHAR(256);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_id VARCHAR(64);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- 待支付订单过期任务按创建时间扫描 PENDING 订单，只为待支付订单建部分索引
+
+CREATE INDEX IF NOT EXISTS idx_orders_pending_created
+    ON orders(created_at) WHERE status = 'PENDING';
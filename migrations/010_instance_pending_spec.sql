@@ -0,0 +1,10 @@
+-- instances 表：变更规格（RESIZE）的目标商品与规格，资源域回传 RUNNING 后才写入规格列，失败或超时时丢弃
+
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_product_id BIGINT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_name VARCHAR(128);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_spec_id BIGINT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_cpu INT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_memory INT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_gpu INT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_image VARCHAR(255);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_config_json JSONB;
@@ -0,0 +1,7 @@
+-- 待支付订单过期任务以 FOR UPDATE SKIP LOCKED 认领订单并记录认领租期，多个副本不会处理同一个订单；
+-- 处理失败的订单租期过后按 COALESCE(expiry_attempt_at, created_at) 排在新过期的订单之后重试
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS expiry_attempt_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_pending_expiry_attempt
+    ON orders((COALESCE(expiry_attempt_at, created_at))) WHERE status = 'PENDING';
@@ -0,0 +1,9 @@
+-- orders 退款信息：没有支付意图的订单（秒杀）无法经支付网关退款，退款时记录金额并标记为需要人工退款
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_manual BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 回填：已退款但没有网关退款单号的秒杀订单
+UPDATE orders SET refund_manual = TRUE
+WHERE refunded_at IS NOT NULL AND refund_id IS NULL AND payment_id IS NULL AND refund_amount > 0;
+
+CREATE INDEX IF NOT EXISTS idx_orders_refund_manual ON orders(refunded_at) WHERE refund_manual;